@@ -17,7 +17,11 @@
 
 package common
 
-import "log"
+import (
+	"log"
+	"sync"
+	"time"
+)
 
 // Logger define the way to receive logs from Consensus instance.
 // NOTE: parameter in 'ctx' should be paired as key-value mapping. For example,
@@ -132,3 +136,91 @@ func (logger *CustomLogger) Warn(msg string, ctx ...interface{}) {
 func (logger *CustomLogger) Error(msg string, ctx ...interface{}) {
 	logger.logger.Println(composeVargs(msg, ctx)...)
 }
+
+type throttleWindow struct {
+	begin      time.Time
+	count      int
+	suppressed int
+}
+
+// ThrottledLogger passes at most limit logs with the same level and message
+// to the wrapped logger in each interval. When logs are suppressed in one
+// interval, their count is logged with the first log of that kind in later
+// intervals.
+type ThrottledLogger struct {
+	logger   Logger
+	limit    int
+	interval time.Duration
+	lock     sync.Mutex
+	windows  map[string]*throttleWindow
+	now      func() time.Time
+}
+
+// NewThrottledLogger creates a new throttled logger wrapping logger.
+func NewThrottledLogger(
+	logger Logger, limit int, interval time.Duration) *ThrottledLogger {
+	return &ThrottledLogger{
+		logger:   logger,
+		limit:    limit,
+		interval: interval,
+		windows:  make(map[string]*throttleWindow),
+		now:      time.Now,
+	}
+}
+
+// log passes the log to fn if it's not throttled.
+func (logger *ThrottledLogger) log(level string,
+	fn func(string, ...interface{}), msg string, ctx []interface{}) {
+	suppressed, pass := func() (int, bool) {
+		logger.lock.Lock()
+		defer logger.lock.Unlock()
+		key := level + ":" + msg
+		now := logger.now()
+		w, exist := logger.windows[key]
+		if !exist || now.Sub(w.begin) >= logger.interval {
+			suppressed := 0
+			if exist {
+				suppressed = w.suppressed
+			}
+			logger.windows[key] = &throttleWindow{begin: now, count: 1}
+			return suppressed, true
+		}
+		if w.count >= logger.limit {
+			w.suppressed++
+			return 0, false
+		}
+		w.count++
+		return 0, true
+	}()
+	if suppressed > 0 {
+		fn("Suppressed logs", "msg", msg, "count", suppressed)
+	}
+	if pass {
+		fn(msg, ctx...)
+	}
+}
+
+// Trace implements Logger interface.
+func (logger *ThrottledLogger) Trace(msg string, ctx ...interface{}) {
+	logger.log("trace", logger.logger.Trace, msg, ctx)
+}
+
+// Debug implements Logger interface.
+func (logger *ThrottledLogger) Debug(msg string, ctx ...interface{}) {
+	logger.log("debug", logger.logger.Debug, msg, ctx)
+}
+
+// Info implements Logger interface.
+func (logger *ThrottledLogger) Info(msg string, ctx ...interface{}) {
+	logger.log("info", logger.logger.Info, msg, ctx)
+}
+
+// Warn implements Logger interface.
+func (logger *ThrottledLogger) Warn(msg string, ctx ...interface{}) {
+	logger.log("warn", logger.logger.Warn, msg, ctx)
+}
+
+// Error implements Logger interface.
+func (logger *ThrottledLogger) Error(msg string, ctx ...interface{}) {
+	logger.log("error", logger.logger.Error, msg, ctx)
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LoggerTestSuite struct {
+	suite.Suite
+}
+
+func (s *LoggerTestSuite) TestThrottledLogger() {
+	buf := &bytes.Buffer{}
+	now := time.Now()
+	logger := NewThrottledLogger(
+		NewCustomLogger(log.New(buf, "", 0)), 3, time.Second)
+	logger.now = func() time.Time { return now }
+	countLines := func(prefix string) (count int) {
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, prefix) {
+				count++
+			}
+		}
+		return
+	}
+	// A burst of rejected messages.
+	for i := 0; i < 100; i++ {
+		logger.Error("Failed to process private share", "index", i)
+	}
+	s.Require().Equal(3, countLines("Failed to process private share"))
+	// Logs of other kinds are not throttled together.
+	logger.Warn("Failed to process private share")
+	logger.Error("Failed to process partial signature")
+	s.Require().Equal(4, countLines("Failed to process private share"))
+	s.Require().Equal(1, countLines("Failed to process partial signature"))
+	s.Require().Equal(0, countLines("Suppressed logs"))
+	// The suppressed count is logged in the next interval.
+	now = now.Add(time.Second)
+	logger.Error("Failed to process private share", "index", 100)
+	s.Require().Equal(5, countLines("Failed to process private share"))
+	s.Require().Equal(1,
+		countLines("Suppressed logs msg Failed to process private share count 97"))
+	// Nothing is suppressed in the last interval.
+	now = now.Add(time.Second)
+	logger.Error("Failed to process private share", "index", 101)
+	s.Require().Equal(6, countLines("Failed to process private share"))
+	s.Require().Equal(1, countLines("Suppressed logs"))
+}
+
+func TestLogger(t *testing.T) {
+	suite.Run(t, new(LoggerTestSuite))
+}
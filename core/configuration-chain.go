@@ -18,13 +18,17 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"runtime"
 	"sync"
 	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/db"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
 	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
@@ -43,6 +47,28 @@ var (
 		"skip but no error")
 	ErrDKGAborted = fmt.Errorf(
 		"DKG is aborted")
+	ErrPartialSignatureRateLimited = fmt.Errorf(
+		"partial signature rate limited")
+	ErrGroupPublicKeyMismatch = fmt.Errorf(
+		"group public key mismatch")
+	ErrInsufficientParticipation = fmt.Errorf(
+		"insufficient participation")
+	ErrThresholdMismatch = fmt.Errorf(
+		"threshold mismatch")
+	ErrPausedPrivateShareFull = fmt.Errorf(
+		"too many private shares buffered while paused")
+	ErrShareBufferFull = fmt.Errorf(
+		"too many private shares buffered for the round")
+	ErrDKGRoundTooFarAhead = fmt.Errorf(
+		"DKG round too far ahead")
+	ErrIncorrectThresholdSignature = fmt.Errorf(
+		"incorrect threshold signature")
+	ErrIncorrectPresetMasterPublicKey = fmt.Errorf(
+		"incorrect preset master public key")
+	ErrDKGProtocolNotRecovered = fmt.Errorf(
+		"DKG protocol not recovered")
+	ErrDuplicateNodeID = fmt.Errorf(
+		"duplicate node ID in node set")
 )
 
 // ErrMismatchDKG represent an attempt to run DKG protocol is failed because
@@ -58,8 +84,182 @@ func (e ErrMismatchDKG) Error() string {
 		e.expectRound, e.expectReset, e.actualRound, e.actualReset)
 }
 
+// TSigPartialResult is the best-effort result of a tsig which timed out
+// before collecting enough partial signatures. It's returned as the error of
+// runTSig instead of ErrNotEnoughtPartialSignatures when tsigPartialResult of
+// configurationChain is enabled.
+type TSigPartialResult struct {
+	Hash              common.Hash
+	Threshold         int
+	Count             int
+	PartialSignatures map[dkg.ID]dkg.PartialSignature
+}
+
+func (r *TSigPartialResult) Error() string {
+	return fmt.Sprintf("%s, hash:%s count:%d threshold:%d",
+		ErrNotEnoughtPartialSignatures, r.Hash.String()[:6], r.Count,
+		r.Threshold)
+}
+
+// Equivocation is the evidence that a DKG proposer proposed two conflicting
+// signed messages in one round. Either MasterPublicKeys or PrivateShares is
+// set.
+type Equivocation struct {
+	ProposerID       types.NodeID
+	Round            uint64
+	Reset            uint64
+	MasterPublicKeys [2]*typesDKG.MasterPublicKey
+	PrivateShares    [2]*typesDKG.PrivateShare
+}
+
+// DKGPhase is the phase of a running DKG protocol, each phase refers to one
+// step in runDKG.
+type DKGPhase int
+
+// DKG phases, in the order they are executed.
+const (
+	DKGPhaseWaitMPKReady DKGPhase = iota
+	DKGPhaseExchangePrivateShares
+	DKGPhaseProposeNackComplaints
+	DKGPhaseProposeAntiNackComplaints
+	DKGPhaseEnforceComplaints
+	DKGPhaseFinalize
+	DKGPhaseRecoverSigner
+)
+
 type dkgStepFn func(round uint64, reset uint64) error
 
+// dkgSchedule is used to estimate when each DKG phase would be executed.
+type dkgSchedule struct {
+	begin       time.Time
+	beginHeight uint64
+	phaseHeight uint64
+	interval    time.Duration
+	offsets     []uint64
+}
+
+// dkgPhaseOffsets returns the height offset of each DKG phase from the
+// beginning of DKG. Complaints are collected from the beginning of
+// DKGPhaseExchangePrivateShares until DKGPhaseProposeAntiNackComplaints, the
+// phases after that are shifted by the complaint window. The durations of
+// collecting MPKs, exchanging private shares and finalizing are configurable,
+// other phases last for one lambda.
+func dkgPhaseOffsets(cfg *types.Config, phases int) []uint64 {
+	toHeight := func(d time.Duration) uint64 {
+		return uint64(d.Nanoseconds() / cfg.MinBlockInterval.Nanoseconds())
+	}
+	phaseHeight := toHeight(cfg.LambdaDKG)
+	shareHeight := toHeight(cfg.SharePhase())
+	windowHeight := toHeight(cfg.ComplaintWindow())
+	if windowHeight <= shareHeight {
+		// Nack complaints should be proposed inside the window.
+		windowHeight = shareHeight + phaseHeight
+	}
+	// The height each phase lasts before the next one begins.
+	durations := map[DKGPhase]uint64{
+		DKGPhaseWaitMPKReady:          toHeight(cfg.MPKPhase()),
+		DKGPhaseExchangePrivateShares: shareHeight,
+		DKGPhaseProposeNackComplaints: windowHeight - shareHeight,
+		DKGPhaseFinalize:              toHeight(cfg.FinalizePhase()),
+	}
+	offsets := make([]uint64, phases)
+	for i := 1; i < phases; i++ {
+		duration, exist := durations[DKGPhase(i-1)]
+		if !exist {
+			duration = phaseHeight
+		}
+		offsets[i] = offsets[i-1] + duration
+	}
+	return offsets
+}
+
+// DKGLifetimeStats is the statistics of all DKGs run by a node, DKG resets
+// are counted as separate registrations.
+type DKGLifetimeStats struct {
+	// RoundsRegistered is the count of registered DKGs.
+	RoundsRegistered uint64
+	// RoundsQualified is the count of DKGs this node is qualified.
+	RoundsQualified uint64
+	// RoundsFailed is the count of DKGs failed or aborted.
+	RoundsFailed uint64
+	// Disqualifications is the total count of disqualified participants
+	// observed in finished DKGs.
+	Disqualifications uint64
+	// AverageFinalizeTime is the average time from runDKG to this node being
+	// qualified.
+	AverageFinalizeTime time.Duration
+}
+
+// defaultPendingPsigTTL is the default lifetime of buffered partial
+// signatures.
+const defaultPendingPsigTTL = 10 * time.Minute
+
+// defaultDKGStallTimeout is the default duration a running DKG could stay in
+// one phase before it's reported as stalled.
+const defaultDKGStallTimeout = 10 * time.Minute
+
+// defaultTSigLatencyLimit is the default count of latencies of completed
+// TSIGs retained.
+const defaultTSigLatencyLimit = 256
+
+// defaultPausedPrvShareLimit is the default count of private shares buffered
+// while share processing is paused.
+const defaultPausedPrvShareLimit = 1024
+
+// psigRateWindow counts the partial signatures buffered from one proposer
+// since begin.
+type psigRateWindow struct {
+	begin time.Time
+	count int
+}
+
+// psigSource identifies the partial signatures from one proposer in one
+// round, at most one of them is buffered for a hash.
+type psigSource struct {
+	round      uint64
+	proposerID types.NodeID
+}
+
+// psigBuffer keeps partial signatures of one hash in the order they are
+// received. Inserting and deduplicating are O(1), instead of scanning all
+// buffered ones.
+type psigBuffer struct {
+	psigs     []*typesDKG.PartialSignature
+	seen      map[psigSource]struct{}
+	rounds    map[uint64]int
+	firstTime time.Time
+}
+
+func newPsigBuffer() *psigBuffer {
+	return &psigBuffer{
+		seen:   make(map[psigSource]struct{}),
+		rounds: make(map[uint64]int),
+	}
+}
+
+// add buffers the partial signature, and returns false when there is already
+// one from the same proposer in the same round.
+func (buf *psigBuffer) add(psig *typesDKG.PartialSignature) bool {
+	src := psigSource{round: psig.Round, proposerID: psig.ProposerID}
+	if _, exist := buf.seen[src]; exist {
+		return false
+	}
+	if len(buf.psigs) == 0 {
+		buf.firstTime = time.Now()
+	}
+	buf.seen[src] = struct{}{}
+	buf.rounds[psig.Round]++
+	buf.psigs = append(buf.psigs, psig)
+	return true
+}
+
+// NodeSetProvider provides the node sets needed by configurationChain, it's
+// satisfied by utils.NodeSetCache.
+type NodeSetProvider interface {
+	// GetNotarySet returns the notary set of a round.
+	GetNotarySet(round uint64) (map[types.NodeID]struct{}, error)
+}
+
 type configurationChain struct {
 	ID              types.NodeID
 	recv            dkgReceiver
@@ -70,49 +270,169 @@ type configurationChain struct {
 	dkgLock         sync.RWMutex
 	dkgSigner       map[uint64]*dkgShareSecret
 	npks            map[uint64]*typesDKG.NodePublicKeys
+	groupPubKeys    map[uint64]*cachedGroupPublicKey
 	complaints      []*typesDKG.Complaint
 	dkgResult       sync.RWMutex
 	tsig            map[common.Hash]*tsigProtocol
 	tsigTouched     map[common.Hash]struct{}
 	tsigReady       *sync.Cond
-	cache           *utils.NodeSetCache
+	cache           NodeSetProvider
 	db              db.Database
 	notarySet       map[types.NodeID]struct{}
 	mpkReady        bool
 	pendingPrvShare map[types.NodeID]*typesDKG.PrivateShare
-	// TODO(jimmy-dexon): add timeout to pending psig.
-	pendingPsig  map[common.Hash][]*typesDKG.PartialSignature
-	prevHash     common.Hash
-	dkgCtx       context.Context
-	dkgCtxCancel context.CancelFunc
-	dkgRunning   bool
+	// Private shares received, indexed by [proposer][receiver].
+	receivedPrvShare map[types.NodeID]map[types.NodeID]*typesDKG.PrivateShare
+	pendingPsig      map[common.Hash]*psigBuffer
+	// Partial signatures buffered longer than pendingPsigTTL without any
+	// runTSig for its hash would be purged.
+	pendingPsigTTL   time.Duration
+	pendingPsigTimer map[common.Hash]*time.Timer
+	prevHash         common.Hash
+	dkgCtx           context.Context
+	dkgCtxCancel     context.CancelFunc
+	dkgRunning       bool
+	dkgDone          chan struct{}
+	dkgSchedule      dkgSchedule
+	// Entropy source to generate DKG polynomials, crypto/rand is used when
+	// it's nil. It's for reproducible DKG results in tests.
+	dkgRand io.Reader
+	// Evidence of conflicting DKG messages, indexed by round.
+	equivocationLock sync.RWMutex
+	equivocations    map[uint64][]Equivocation
+	// Lifetime statistics of DKG, guarded by dkgLock.
+	dkgStats             DKGLifetimeStats
+	dkgTotalFinalizeTime time.Duration
+	// Traces of DKG events for latency analysis, guarded by dkgLock.
+	dkgTrace dkgTraceRecorder
+	// Return the buffered partial signatures as TSigPartialResult when a
+	// runTSig times out.
+	tsigPartialResult bool
+	// Latencies from the first partial signature to the completion of
+	// recent TSIGs, at most tsigLatencyLimit of them are retained. They are
+	// guarded by tsigReady.L.
+	tsigLatencies    []time.Duration
+	tsigLatencyLimit int
+	// Threshold signatures recovered by runTSig are verified against the
+	// group public key before returned, unless it's TSigVerifyLazy.
+	tsigVerifyMode TSigVerifyMode
+	// At most psigRateLimit partial signatures from one proposer would be
+	// buffered in one second, 0 means unlimited.
+	psigRateLimit  int
+	psigRateWindow map[types.NodeID]*psigRateWindow
+	psigRateSwept  time.Time
+	// The stall observer is invoked when a running DKG doesn't advance its
+	// phase within dkgStallTimeout, 0 disables the watchdog.
+	dkgStallTimeout  time.Duration
+	dkgStallObserver func(round uint64, phase DKGPhase, stalledFor time.Duration)
+	// The phase observer is invoked when a running DKG enters a phase or
+	// fails.
+	dkgPhaseObserver func(round uint64, phase DKGPhase, err error)
+	// Signatures of runTSig are recovered by at most cap(tsigWorkers)
+	// goroutines at the same time.
+	tsigWorkers chan struct{}
+	// The policy to decide disqualified participants of DKG.
+	disqualifyPolicy DisqualificationPolicy
+	// The policy to retry governance reads failed during DKG, nil means no
+	// retry.
+	govRetryPolicy GovernanceRetryPolicy
+	// The logger for log sites which might flood during a byzantine-heavy
+	// round, it's the same as logger unless SetLogThrottle is called.
+	floodLogger common.Logger
+	// Partial signatures received for each hash are saved to DB when
+	// persistPsig is true, so a restarted node could resume runTSig with
+	// them. They are guarded by tsigReady.L.
+	persistPsig   bool
+	persistedPsig map[common.Hash]*psigBuffer
+	// Private shares received while share processing is paused are buffered
+	// in order, up to pausedPrvShareLimit in total and prvShareRoundLimit for
+	// each round.
+	pausedPrvShareLock  sync.Mutex
+	prvSharePaused      bool
+	pausedPrvShare      []*typesDKG.PrivateShare
+	pausedPrvShareLimit int
+	pausedPrvShareCount map[uint64]int
+	prvShareRoundLimit  int
+	// DKG could only be registered for rounds at most dkgLookahead ahead of
+	// the latest round notified via notifyRound. They are guarded by dkgLock.
+	dkgCurrentRound uint64
+	dkgLookahead    uint64
+	// The master public keys provided by registerDKGWithMPKs for the
+	// registered DKG, guarded by dkgLock.
+	presetMPKs []*typesDKG.MasterPublicKey
 }
 
 func newConfigurationChain(
 	ID types.NodeID,
 	recv dkgReceiver,
 	gov Governance,
-	cache *utils.NodeSetCache,
+	cache NodeSetProvider,
 	dbInst db.Database,
 	logger common.Logger) *configurationChain {
+	return newConfigurationChainWithRecovery(
+		ID, recv, gov, cache, dbInst, logger, false)
+}
+
+// newConfigurationChainWithRecovery constructs a configurationChain, and
+// rebuilds the DKG results of all finalized rounds with private keys stored in
+// DB when recoverFromDB is true, instead of recovering them lazily.
+func newConfigurationChainWithRecovery(
+	ID types.NodeID,
+	recv dkgReceiver,
+	gov Governance,
+	cache NodeSetProvider,
+	dbInst db.Database,
+	logger common.Logger,
+	recoverFromDB bool) *configurationChain {
 	configurationChain := &configurationChain{
-		ID:          ID,
-		recv:        recv,
-		gov:         gov,
-		logger:      logger,
-		dkgSigner:   make(map[uint64]*dkgShareSecret),
-		npks:        make(map[uint64]*typesDKG.NodePublicKeys),
-		tsig:        make(map[common.Hash]*tsigProtocol),
-		tsigTouched: make(map[common.Hash]struct{}),
-		tsigReady:   sync.NewCond(&sync.Mutex{}),
-		cache:       cache,
-		db:          dbInst,
-		pendingPsig: make(map[common.Hash][]*typesDKG.PartialSignature),
+		ID:               ID,
+		recv:             recv,
+		gov:              gov,
+		logger:           logger,
+		dkgSigner:        make(map[uint64]*dkgShareSecret),
+		npks:             make(map[uint64]*typesDKG.NodePublicKeys),
+		groupPubKeys:     make(map[uint64]*cachedGroupPublicKey),
+		tsig:             make(map[common.Hash]*tsigProtocol),
+		tsigTouched:      make(map[common.Hash]struct{}),
+		tsigReady:        sync.NewCond(&sync.Mutex{}),
+		cache:            cache,
+		db:               dbInst,
+		pendingPsig:      make(map[common.Hash]*psigBuffer),
+		pendingPsigTTL:   defaultPendingPsigTTL,
+		pendingPsigTimer: make(map[common.Hash]*time.Timer),
+		psigRateWindow:   make(map[types.NodeID]*psigRateWindow),
+		dkgStallTimeout:  defaultDKGStallTimeout,
+		tsigWorkers:      make(chan struct{}, runtime.GOMAXPROCS(0)),
+		tsigLatencyLimit: defaultTSigLatencyLimit,
+		disqualifyPolicy: DefaultDisqualificationPolicy{},
+		floodLogger:      logger,
+		equivocations:    make(map[uint64][]Equivocation),
+		persistedPsig:    make(map[common.Hash]*psigBuffer),
+
+		pausedPrvShareLimit: defaultPausedPrvShareLimit,
+		pausedPrvShareCount: make(map[uint64]int),
+		dkgLookahead:        ConfigRoundShift + 1,
 	}
 	configurationChain.initDKGPhasesFunc()
+	configurationChain.recoverPendingPsig()
+	if recoverFromDB {
+		configurationChain.recoverAllDKGInfo()
+	}
 	return configurationChain
 }
 
+// recoverAllDKGInfo rebuilds the node public keys and signers of each round
+// from DKGDelayRound, until the first round whose DKG is not final.
+func (cc *configurationChain) recoverAllDKGInfo() {
+	for round := DKGDelayRound; cc.gov.IsDKGFinal(round); round++ {
+		if err := cc.recoverDKGInfo(round, false); err != nil {
+			cc.logger.Warn("Failed to recover DKG info from DB",
+				"round", round,
+				"error", err)
+		}
+	}
+}
+
 func (cc *configurationChain) abortDKG(
 	parentCtx context.Context,
 	round, reset uint64) bool {
@@ -147,41 +467,179 @@ func (cc *configurationChain) abortDKGNoLock(
 	if cc.dkgCtxCancel != nil {
 		cc.dkgCtxCancel()
 	}
-	cc.dkgLock.Unlock()
 	// Wait for current running DKG protocol aborting.
-	for {
-		cc.dkgLock.Lock()
-		if cc.dkgRunning == false {
-			cc.dkg = nil
-			break
-		}
+	for cc.dkgRunning {
+		done := cc.dkgDone
+		cc.dkgLock.Unlock()
 		select {
 		case <-ctx.Done():
+			cc.dkgLock.Lock()
 			return false
-		case <-time.After(100 * time.Millisecond):
+		case <-done:
 		}
-		cc.dkgLock.Unlock()
+		cc.dkgLock.Lock()
 	}
+	cc.dkg = nil
 	cc.logger.Error("Previous DKG aborted",
 		"round", round,
 		"reset", reset)
 	return cc.dkg == nil
 }
 
+// AbortDKG abandons the DKG of a round when governance decides that round is
+// invalid. The running runDKG of that round would return ErrDKGAborted, and
+// so does any pending runTSig of that round.
+func (cc *configurationChain) AbortDKG(round uint64) {
+	func() {
+		cc.dkgLock.Lock()
+		defer cc.dkgLock.Unlock()
+		if cc.dkg == nil || cc.dkg.round != round {
+			return
+		}
+		if !cc.abortDKGNoLock(context.Background(), round, cc.dkg.reset) {
+			return
+		}
+		cc.pendingPrvShare = nil
+		cc.receivedPrvShare = nil
+		cc.mpkReady = false
+	}()
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	for _, tsig := range cc.tsig {
+		if tsig.nodePublicKeys.Round == round {
+			tsig.aborted = true
+		}
+	}
+	cc.tsigReady.Broadcast()
+}
+
+// CancelAll aborts the registered DKG and every pending runTSig, they would
+// return ErrDKGAborted. It returns after all of them return. Unlike AbortDKG,
+// the round of the DKG is not abandoned, and the chain could be used to
+// register DKGs and run TSIGs again, including the aborted ones.
+func (cc *configurationChain) CancelAll() {
+	func() {
+		cc.dkgLock.Lock()
+		defer cc.dkgLock.Unlock()
+		if cc.dkg == nil {
+			return
+		}
+		if !cc.abortDKGNoLock(context.Background(), cc.dkg.round, cc.dkg.reset) {
+			return
+		}
+		cc.pendingPrvShare = nil
+		cc.receivedPrvShare = nil
+		cc.mpkReady = false
+	}()
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	aborted := make(map[common.Hash]*tsigProtocol, len(cc.tsig))
+	for hash, tsig := range cc.tsig {
+		tsig.aborted = true
+		aborted[hash] = tsig
+	}
+	cc.tsigReady.Broadcast()
+	// Wait for aborted runTSig calls to return, new ones might be started
+	// for the same hashes meanwhile.
+	for len(aborted) > 0 {
+		cc.tsigReady.Wait()
+		for hash, tsig := range aborted {
+			if cc.tsig[hash] != tsig {
+				delete(aborted, hash)
+			}
+		}
+	}
+}
+
+// PurgeDKG drops cached DKG results, equivocations and traces of rounds not
+// later than round. Signers and node public keys purged would be recovered
+// from governance and DB when they are needed again.
+func (cc *configurationChain) PurgeDKG(round uint64) {
+	func() {
+		cc.dkgResult.Lock()
+		defer cc.dkgResult.Unlock()
+		for r := range cc.dkgSigner {
+			if r <= round {
+				delete(cc.dkgSigner, r)
+			}
+		}
+		for r := range cc.npks {
+			if r <= round {
+				delete(cc.npks, r)
+			}
+		}
+		for r := range cc.groupPubKeys {
+			if r <= round {
+				delete(cc.groupPubKeys, r)
+			}
+		}
+	}()
+	func() {
+		cc.equivocationLock.Lock()
+		defer cc.equivocationLock.Unlock()
+		for r := range cc.equivocations {
+			if r <= round {
+				delete(cc.equivocations, r)
+			}
+		}
+	}()
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	for r := range cc.dkgTrace.traces {
+		if r <= round {
+			delete(cc.dkgTrace.traces, r)
+		}
+	}
+}
+
+// SetDKGLookahead sets how many rounds ahead of the current round a DKG could
+// be registered, registerDKG returns ErrDKGRoundTooFarAhead for rounds beyond
+// it. The default is ConfigRoundShift+1. It's not thread-safe and should be
+// called before registerDKG.
+func (cc *configurationChain) SetDKGLookahead(lookahead uint64) {
+	cc.dkgLookahead = lookahead
+}
+
+// notifyRound updates the current round to limit the rounds allowed to
+// register DKG, rounds older than the current one are ignored.
+func (cc *configurationChain) notifyRound(round uint64) {
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	if round > cc.dkgCurrentRound {
+		cc.dkgCurrentRound = round
+	}
+}
+
 func (cc *configurationChain) registerDKG(
 	parentCtx context.Context,
 	round, reset uint64,
-	threshold int) {
+	threshold int) error {
+	if curRound, tooFar := func() (uint64, bool) {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		return cc.dkgCurrentRound, round > cc.dkgCurrentRound+cc.dkgLookahead
+	}(); tooFar {
+		cc.logger.Error("DKG round too far ahead",
+			"round", round,
+			"reset", reset,
+			"current-round", curRound)
+		return ErrDKGRoundTooFarAhead
+	}
+	notarySet, err := cc.getNotarySet(parentCtx, round)
+	if err != nil {
+		cc.logger.Error("Error getting notary set from cache", "error", err)
+		return err
+	}
 	cc.dkgLock.Lock()
 	defer cc.dkgLock.Unlock()
 	if cc.dkg != nil {
 		// Make sure we only proceed when cc.dkg is nil.
 		if !cc.abortDKGNoLock(parentCtx, round, reset) {
-			return
+			return nil
 		}
 		select {
 		case <-parentCtx.Done():
-			return
+			return parentCtx.Err()
 		default:
 		}
 		if cc.dkg != nil {
@@ -195,13 +653,11 @@ func (cc *configurationChain) registerDKG(
 			})
 		}
 	}
-	notarySet, err := cc.cache.GetNotarySet(round)
-	if err != nil {
-		cc.logger.Error("Error getting notary set from cache", "error", err)
-		return
-	}
 	cc.notarySet = notarySet
+	cc.presetMPKs = nil
 	cc.pendingPrvShare = make(map[types.NodeID]*typesDKG.PrivateShare)
+	cc.receivedPrvShare = make(
+		map[types.NodeID]map[types.NodeID]*typesDKG.PrivateShare)
 	cc.mpkReady = false
 	cc.dkg, err = recoverDKGProtocol(cc.ID, cc.recv, round, reset, cc.db)
 	cc.dkgCtx, cc.dkgCtxCancel = context.WithCancel(parentCtx)
@@ -209,21 +665,42 @@ func (cc *configurationChain) registerDKG(
 		panic(err)
 	}
 	if cc.dkg == nil {
-		cc.dkg = newDKGProtocol(
-			cc.ID,
-			cc.recv,
-			round,
-			reset,
-			threshold)
+		if cc.dkgRand == nil {
+			cc.dkg = newDKGProtocol(
+				cc.ID,
+				cc.recv,
+				round,
+				reset,
+				threshold)
+		} else {
+			prvShare, pubShare, err := dkg.NewPrivateKeySharesWithRand(
+				threshold, cc.dkgRand)
+			if err != nil {
+				cc.logger.Error("Error creating DKG private key shares",
+					"error", err)
+				return err
+			}
+			cc.dkg = newDKGProtocolWithShares(
+				cc.ID,
+				cc.recv,
+				round,
+				reset,
+				threshold,
+				prvShare,
+				pubShare)
+		}
 
 		err = cc.db.PutOrUpdateDKGProtocol(cc.dkg.toDKGProtocolInfo())
 		if err != nil {
 			cc.logger.Error("Error put or update DKG protocol", "error",
 				err)
-			return
+			return err
 		}
 	}
 
+	cc.dkgStats.RoundsRegistered++
+	cc.dkgTrace.begin(round, reset)
+
 	go func() {
 		ticker := newTicker(cc.gov, round, TickerDKG)
 		defer ticker.Stop()
@@ -234,6 +711,7 @@ func (cc *configurationChain) registerDKG(
 			cc.dkg.proposeMPKReady()
 		}
 	}()
+	return nil
 }
 
 func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
@@ -250,8 +728,14 @@ func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
 		cc.logger.Warn("DKG already final", "round", round)
 		return ErrSkipButNoError
 	}
+	if cc.presetMPKs != nil {
+		// Master public keys are known already, there is nothing to wait.
+		cc.dkg.proposeMPKReady()
+		return nil
+	}
 	cc.logger.Debug("Calling Governance.IsDKGMPKReady", "round", round)
 	var err error
+	cc.traceMPKs(round)
 	for err == nil && !cc.gov.IsDKGMPKReady(round) {
 		cc.dkgLock.Unlock()
 		cc.logger.Debug("DKG MPKs are not ready yet. Try again later...",
@@ -263,6 +747,7 @@ func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
 		case <-time.After(500 * time.Millisecond):
 		}
 		cc.dkgLock.Lock()
+		cc.traceMPKs(round)
 	}
 	return err
 }
@@ -270,8 +755,7 @@ func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
 func (cc *configurationChain) runDKGPhaseTwoAndThree(
 	round uint64, reset uint64) error {
 	// Check if this node successfully join the protocol.
-	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys", "round", round)
-	mpks := cc.gov.DKGMasterPublicKeys(round)
+	mpks := cc.dkgMasterPublicKeys(round)
 	inProtocol := false
 	for _, mpk := range mpks {
 		if mpk.ProposerID == cc.ID {
@@ -285,7 +769,64 @@ func (cc *configurationChain) runDKGPhaseTwoAndThree(
 			"reset", reset)
 		return ErrSkipButNoError
 	}
+	// MPKs are final from now on.
+	cc.traceDKG(dkgTraceFirstMPKSeen)
+	cc.traceDKG(dkgTraceAllMPKsSeen)
+	// Nodes never proposing their MPKs are excluded, the qualification would
+	// be calculated over received MPKs only.
+	excluded := missingMPKProposers(cc.notarySet, mpks)
+	if len(excluded) > 0 {
+		cc.logger.Warn("Nodes excluded from DKG without master public keys",
+			"round", round,
+			"reset", reset,
+			"received", len(mpks),
+			"excluded", excluded)
+	}
+	// MPKs proposed after the MPK phase are ignored, give up this DKG when
+	// there are not enough participants by then.
+	cfg := utils.GetConfigWithPanic(cc.gov, round, cc.logger)
+	participants := len(cc.notarySet) - len(excluded)
+	if minimum := utils.GetDKGMinParticipants(cfg); participants < minimum {
+		cc.logger.Error("Insufficient DKG participation",
+			"round", round,
+			"reset", reset,
+			"participants", participants,
+			"minimum", minimum)
+		return ErrInsufficientParticipation
+	}
+	cc.checkMasterPublicKeysEquivocation(mpks)
+	// Keys generated for a threshold other than the configured one are
+	// excluded from qualification, like missing ones. Give up this DKG when
+	// this node is the one with another threshold, or there are not enough
+	// participants left.
+	threshold := utils.GetDKGThreshold(cfg)
+	if mismatched := thresholdMismatchedProposers(
+		mpks, threshold); len(mismatched) > 0 {
+		if cc.dkg.threshold != threshold {
+			cc.logger.Error("DKG threshold mismatch",
+				"round", round,
+				"reset", reset,
+				"threshold", cc.dkg.threshold,
+				"configured", threshold)
+			return ErrThresholdMismatch
+		}
+		cc.logger.Warn("Nodes excluded from DKG with mismatched threshold",
+			"round", round,
+			"reset", reset,
+			"threshold", threshold,
+			"excluded", mismatched)
+		mpks = excludeMPKProposers(mpks, mismatched)
+		if participants -= len(mismatched); participants <
+			utils.GetDKGMinParticipants(cfg) {
+			cc.logger.Error("Insufficient DKG participation with matched threshold",
+				"round", round,
+				"reset", reset,
+				"participants", participants)
+			return ErrThresholdMismatch
+		}
+	}
 	// Phase 2(T = 0): Exchange DKG secret key share.
+	cc.traceDKG(dkgTraceFirstShareSent)
 	if err := cc.dkg.processMasterPublicKeys(mpks); err != nil {
 		cc.logger.Error("Failed to process master public key",
 			"round", round,
@@ -300,14 +841,23 @@ func (cc *configurationChain) runDKGPhaseTwoAndThree(
 		return ErrDKGAborted
 	default:
 	}
-	for _, prvShare := range cc.pendingPrvShare {
+	// Process private shares in order of proposers to make the complaints
+	// proposed deterministic.
+	proposers := make(types.NodeIDs, 0, len(cc.pendingPrvShare))
+	for nID := range cc.pendingPrvShare {
+		proposers = append(proposers, nID)
+	}
+	proposers.Sort()
+	for _, nID := range proposers {
+		prvShare := cc.pendingPrvShare[nID]
 		if err := cc.dkg.processPrivateShare(prvShare); err != nil {
-			cc.logger.Error("Failed to process private share",
+			cc.floodLogger.Error("Failed to process private share",
 				"round", round,
 				"reset", reset,
 				"error", err)
 		}
 	}
+	cc.traceSharesProcessed()
 
 	// Phase 3(T = 0~λ): Propose complaint.
 	// Propose complaint is done in `processMasterPublicKeys`.
@@ -321,6 +871,7 @@ func (cc *configurationChain) runDKGPhaseFour() {
 
 func (cc *configurationChain) runDKGPhaseFiveAndSix(round uint64, reset uint64) {
 	// Phase 5(T = 2λ): Propose Anti nack complaint.
+	cc.traceDKG(dkgTraceComplaintWindowClosed)
 	cc.logger.Debug("Calling Governance.DKGComplaints", "round", round)
 	cc.complaints = cc.gov.DKGComplaints(round)
 	if err := cc.dkg.processNackComplaints(cc.complaints); err != nil {
@@ -367,17 +918,23 @@ func (cc *configurationChain) runDKGPhaseNine(round uint64, reset uint64) error
 	if err != nil {
 		return err
 	}
-	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys", "round", round)
-	cc.logger.Debug("Calling Governance.DKGComplaints", "round", round)
-	npks, err := typesDKG.NewNodePublicKeys(round,
-		cc.gov.DKGMasterPublicKeys(round),
-		cc.gov.DKGComplaints(round),
+	cc.traceDKG(dkgTraceFinalized)
+	mpks := cc.dkgMasterPublicKeys(round)
+	npks, err := typesDKG.NewNodePublicKeysWithDisqualified(round,
+		mpks,
+		cc.disqualified(round, cc.dkg.threshold),
 		cc.dkg.threshold)
 	if err != nil {
 		return err
 	}
-	qualifies := ""
+	cc.dkgStats.Disqualifications += uint64(len(mpks) - len(npks.QualifyIDs))
+	qualifyNodeIDs := make(types.NodeIDs, 0, len(npks.QualifyNodeIDs))
 	for nID := range npks.QualifyNodeIDs {
+		qualifyNodeIDs = append(qualifyNodeIDs, nID)
+	}
+	qualifyNodeIDs.Sort()
+	qualifies := ""
+	for _, nID := range qualifyNodeIDs {
 		qualifies += fmt.Sprintf("%s ", nID.String()[:6])
 	}
 	cc.logger.Info("Qualify Nodes",
@@ -402,6 +959,8 @@ func (cc *configurationChain) runDKGPhaseNine(round uint64, reset uint64) error
 		return err
 	}
 	cc.dkg.proposeSuccess()
+	cc.dkgStats.RoundsQualified++
+	cc.dkgTotalFinalizeTime += time.Since(cc.dkgSchedule.begin)
 	cc.dkgResult.Lock()
 	defer cc.dkgResult.Unlock()
 	cc.dkgSigner[round] = signer
@@ -446,10 +1005,23 @@ func (cc *configurationChain) runDKG(
 	if _, _, err = cc.getDKGInfo(round, false); err == nil {
 		return ErrSkipButNoError
 	}
-	cfg := utils.GetConfigWithPanic(cc.gov, round, cc.logger)
+	cfg, err := cc.getConfiguration(cc.dkgContext(), round)
+	if err != nil {
+		return err
+	}
 	phaseHeight := uint64(
 		cfg.LambdaDKG.Nanoseconds() / cfg.MinBlockInterval.Nanoseconds())
-	skipPhase := int(dkgHeight / phaseHeight)
+	offsets := dkgPhaseOffsets(cfg, len(cc.dkgRunPhases))
+	if cc.hasPresetMPKs(round, reset) {
+		offsets = skipMPKPhaseOffsets(offsets)
+	}
+	skipPhase := 0
+	for skipPhase+1 < len(offsets) && offsets[skipPhase+1] <= dkgHeight {
+		skipPhase++
+	}
+	if dkgHeight >= offsets[len(offsets)-1]+phaseHeight {
+		skipPhase = len(offsets)
+	}
 	cc.logger.Info("Skipping DKG phase", "phase", skipPhase)
 	cc.dkgLock.Lock()
 	defer cc.dkgLock.Unlock()
@@ -469,12 +1041,25 @@ func (cc *configurationChain) runDKG(
 		panic(fmt.Errorf("duplicated call to runDKG: %d %d", round, reset))
 	}
 	cc.dkgRunning = true
+	cc.dkgDone = make(chan struct{})
+	cc.dkgSchedule = dkgSchedule{
+		begin:       time.Now(),
+		beginHeight: dkgHeight,
+		phaseHeight: phaseHeight,
+		interval:    cfg.MinBlockInterval,
+		offsets:     offsets,
+	}
 	defer func() {
 		// Here we should hold the cc.dkgLock, reset cc.dkg to nil when done.
 		if cc.dkg != nil {
 			cc.dkg = nil
 		}
+		cc.presetMPKs = nil
 		cc.dkgRunning = false
+		close(cc.dkgDone)
+		if err != nil {
+			cc.dkgStats.RoundsFailed++
+		}
 	}()
 	wg := sync.WaitGroup{}
 	var dkgError error
@@ -482,9 +1067,13 @@ func (cc *configurationChain) runDKG(
 	// context.
 	ctx := cc.dkgCtx
 	cc.dkg.step = skipPhase
+	if cc.isLoneDKGParticipant() {
+		return cc.runDKGAlone(round, reset)
+	}
 	for i := skipPhase; i < len(cc.dkgRunPhases); i++ {
+		i := i
 		wg.Add(1)
-		event.RegisterHeight(dkgBeginHeight+phaseHeight*uint64(i), func(uint64) {
+		event.RegisterHeight(dkgBeginHeight+offsets[i], func(uint64) {
 			go func() {
 				defer wg.Done()
 				cc.dkgLock.Lock()
@@ -495,13 +1084,16 @@ func (cc *configurationChain) runDKG(
 				select {
 				case <-ctx.Done():
 					dkgError = ErrDKGAborted
+					cc.observeDKGPhase(round, DKGPhase(i), dkgError)
 					return
 				default:
 				}
 
-				err := cc.dkgRunPhases[cc.dkg.step](round, reset)
+				step := cc.dkg.step
+				err := cc.dkgRunPhases[step](round, reset)
 				if err == nil || err == ErrSkipButNoError {
 					err = nil
+					step++
 					cc.dkg.step++
 					err = cc.db.PutOrUpdateDKGProtocol(cc.dkg.toDKGProtocolInfo())
 					if err != nil {
@@ -513,9 +1105,16 @@ func (cc *configurationChain) runDKG(
 				if err != nil && dkgError == nil {
 					dkgError = err
 				}
+				cc.observeDKGPhase(round, DKGPhase(step), dkgError)
 			}()
 		})
 	}
+	if cc.dkgStallTimeout > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go cc.watchDKGStall(round, skipPhase, cc.dkgStallTimeout, stop)
+	}
+	cc.observeDKGPhase(round, DKGPhase(skipPhase), nil)
 	cc.dkgLock.Unlock()
 	wgChan := make(chan struct{}, 1)
 	go func() {
@@ -535,6 +1134,263 @@ func (cc *configurationChain) runDKG(
 	return dkgError
 }
 
+// SetLogThrottle makes the log sites which might flood during a
+// byzantine-heavy round log at most limit messages of one kind in each
+// interval. It's not thread-safe and should be called before any DKG is
+// registered.
+func (cc *configurationChain) SetLogThrottle(
+	limit int, interval time.Duration) {
+	cc.floodLogger = common.NewThrottledLogger(cc.logger, limit, interval)
+}
+
+// SetDKGStallTimeout sets how long a running DKG could stay in one phase
+// before reported as stalled, 0 disables the watchdog. The default is
+// defaultDKGStallTimeout. It's not thread-safe and should be called before
+// runDKG.
+func (cc *configurationChain) SetDKGStallTimeout(timeout time.Duration) {
+	cc.dkgStallTimeout = timeout
+}
+
+// SetStallObserver sets the callback invoked when a running DKG stays in one
+// phase longer than the stall timeout, it's invoked once per stalled phase.
+func (cc *configurationChain) SetStallObserver(
+	observer func(round uint64, phase DKGPhase, stalledFor time.Duration)) {
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	cc.dkgStallObserver = observer
+}
+
+// SetPhaseObserver sets the callback invoked when a running DKG enters a
+// phase, including the first one once all phases are registered, and when it
+// fails. The phase is len(phases) when all phases are done. It's invoked with
+// the DKG locked and must not call back into the configuration chain.
+func (cc *configurationChain) SetPhaseObserver(
+	observer func(round uint64, phase DKGPhase, err error)) {
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	cc.dkgPhaseObserver = observer
+}
+
+// observeDKGPhase reports the phase of the running DKG to the phase observer,
+// it should be called with dkgLock held.
+func (cc *configurationChain) observeDKGPhase(
+	round uint64, phase DKGPhase, err error) {
+	if cc.dkgPhaseObserver == nil {
+		return
+	}
+	cc.dkgPhaseObserver(round, phase, err)
+}
+
+// watchDKGStall reports to the stall observer when the running DKG of a round
+// doesn't advance its phase within timeout, until stop is closed.
+func (cc *configurationChain) watchDKGStall(
+	round uint64, step int, timeout time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+	since := time.Now()
+	reported := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		observer, stalledFor, stalled := func() (
+			func(uint64, DKGPhase, time.Duration), time.Duration, bool) {
+			cc.dkgLock.RLock()
+			defer cc.dkgLock.RUnlock()
+			select {
+			case <-stop:
+				return nil, 0, false
+			default:
+			}
+			if cc.dkg == nil || cc.dkg.round != round {
+				return nil, 0, false
+			}
+			if cc.dkg.step != step {
+				step = cc.dkg.step
+				since = time.Now()
+				reported = false
+				return nil, 0, false
+			}
+			if reported || time.Since(since) < timeout {
+				return nil, 0, false
+			}
+			reported = true
+			return cc.dkgStallObserver, time.Since(since), true
+		}()
+		if stalled {
+			cc.logger.Warn("DKG stalled",
+				"round", round,
+				"phase", step,
+				"stalled-for", stalledFor)
+			if observer != nil {
+				observer(round, DKGPhase(step), stalledFor)
+			}
+		}
+	}
+}
+
+// PhaseDeadline returns the current phase of the running DKG of a round, and
+// the estimated time that the next phase would begin. ok is false when the DKG
+// of that round is not running.
+func (cc *configurationChain) PhaseDeadline(round uint64) (
+	phase DKGPhase, deadline time.Time, ok bool) {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	if !cc.dkgRunning || cc.dkg == nil || cc.dkg.round != round {
+		return
+	}
+	phase = DKGPhase(cc.dkg.step)
+	sched := cc.dkgSchedule
+	// The last phase is expected to be done in one phase height.
+	next := sched.offsets[len(sched.offsets)-1] + sched.phaseHeight
+	if int(phase)+1 < len(sched.offsets) {
+		next = sched.offsets[phase+1]
+	}
+	deadline = sched.begin.Add(time.Duration(
+		int64(next)-int64(sched.beginHeight)) * sched.interval)
+	ok = true
+	return
+}
+
+// PublicKeyShares returns the public key share of each qualified node of a
+// round, which can be used to verify partial signatures. The returned map is a
+// copy.
+func (cc *configurationChain) PublicKeyShares(
+	round uint64) (map[types.NodeID]*dkg.PublicKey, bool) {
+	npks, _, err := cc.getDKGInfo(round, true)
+	if err != nil {
+		return nil, false
+	}
+	pubKeys := make(map[types.NodeID]*dkg.PublicKey, len(npks.PublicKeys))
+	for nID, pubKey := range npks.PublicKeys {
+		copied := *pubKey
+		pubKeys[nID] = &copied
+	}
+	return pubKeys, true
+}
+
+// RecomputeGroupPublicKey derives the group public key of a round from the
+// public key shares of qualified nodes, with the share of this node derived
+// from the DKG private key in DB. ErrGroupPublicKeyMismatch is returned when
+// it's different from the one derived from master public keys in governance,
+// which means the DKG state of this node is corrupted.
+func (cc *configurationChain) RecomputeGroupPublicKey(
+	round uint64) (*dkg.PublicKey, error) {
+	npks, _, err := cc.getDKGInfo(round, true)
+	if err != nil {
+		return nil, err
+	}
+	pubKeys := make([]*dkg.PublicKey, 0, len(npks.QualifyIDs))
+	ids := make(dkg.IDs, 0, len(npks.QualifyIDs))
+	for nID, id := range npks.IDMap {
+		pubKey := npks.PublicKeys[nID]
+		if nID == cc.ID {
+			prvKey, err := cc.db.GetDKGPrivateKey(
+				round, cc.gov.DKGResetCount(round))
+			if err != nil {
+				return nil, err
+			}
+			// Make sure the public key is derived from the stored key.
+			var stored dkg.PrivateKey
+			if err = stored.SetBytes(prvKey.Bytes()); err != nil {
+				return nil, err
+			}
+			derived := stored.PublicKey().(dkg.PublicKey)
+			pubKey = &derived
+		}
+		pubKeys = append(pubKeys, pubKey)
+		ids = append(ids, id)
+	}
+	groupPubKey, err := dkg.RecoverPublicKey(pubKeys, ids)
+	if err != nil {
+		return nil, err
+	}
+	gpk, err := typesDKG.NewGroupPublicKeyWithDisqualified(round,
+		cc.gov.DKGMasterPublicKeys(round),
+		cc.disqualified(round, npks.Threshold),
+		npks.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(groupPubKey.Bytes(), gpk.GroupPublicKey.Bytes()) {
+		return nil, ErrGroupPublicKeyMismatch
+	}
+	return groupPubKey, nil
+}
+
+// LifetimeStats returns the statistics of all DKGs run by this node.
+func (cc *configurationChain) LifetimeStats() DKGLifetimeStats {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	stats := cc.dkgStats
+	if stats.RoundsQualified > 0 {
+		stats.AverageFinalizeTime = cc.dkgTotalFinalizeTime /
+			time.Duration(stats.RoundsQualified)
+	}
+	return stats
+}
+
+// MissingMasterPublicKeys returns the nodes in notary set of a round which
+// haven't proposed their master public keys.
+func (cc *configurationChain) MissingMasterPublicKeys(
+	round uint64) types.NodeIDs {
+	notarySet, err := cc.cache.GetNotarySet(round)
+	if err != nil {
+		cc.logger.Error("Error getting notary set from cache",
+			"round", round,
+			"error", err)
+		return nil
+	}
+	return missingMPKProposers(notarySet, cc.gov.DKGMasterPublicKeys(round))
+}
+
+// thresholdMismatchedProposers returns the proposers of mpks excluded from
+// qualification by typesDKG.CalcThresholdMismatched, sorted.
+func thresholdMismatchedProposers(
+	mpks []*typesDKG.MasterPublicKey, threshold int) types.NodeIDs {
+	nIDs := types.NodeIDs{}
+	for nID := range typesDKG.CalcThresholdMismatched(mpks, threshold) {
+		nIDs = append(nIDs, nID)
+	}
+	nIDs.Sort()
+	return nIDs
+}
+
+// excludeMPKProposers returns mpks without the ones proposed by nIDs.
+func excludeMPKProposers(mpks []*typesDKG.MasterPublicKey,
+	nIDs types.NodeIDs) []*typesDKG.MasterPublicKey {
+	excluded := make(map[types.NodeID]struct{}, len(nIDs))
+	for _, nID := range nIDs {
+		excluded[nID] = struct{}{}
+	}
+	ret := make([]*typesDKG.MasterPublicKey, 0, len(mpks))
+	for _, mpk := range mpks {
+		if _, exist := excluded[mpk.ProposerID]; !exist {
+			ret = append(ret, mpk)
+		}
+	}
+	return ret
+}
+
+func missingMPKProposers(notarySet map[types.NodeID]struct{},
+	mpks []*typesDKG.MasterPublicKey) types.NodeIDs {
+	missing := make(map[types.NodeID]struct{}, len(notarySet))
+	for nID := range notarySet {
+		missing[nID] = struct{}{}
+	}
+	for _, mpk := range mpks {
+		delete(missing, mpk.ProposerID)
+	}
+	nIDs := make(types.NodeIDs, 0, len(missing))
+	for nID := range missing {
+		nIDs = append(nIDs, nID)
+	}
+	nIDs.Sort()
+	return nIDs
+}
+
 func (cc *configurationChain) isDKGFinal(round uint64) bool {
 	if !cc.gov.IsDKGFinal(round) {
 		return false
@@ -587,10 +1443,9 @@ func (cc *configurationChain) recoverDKGInfo(
 	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys for recoverDKGInfo",
 		"round", round)
 	mpk := cc.gov.DKGMasterPublicKeys(round)
-	cc.logger.Debug("Calling Governance.DKGComplaints for recoverDKGInfo",
-		"round", round)
-	comps := cc.gov.DKGComplaints(round)
-	qualifies, _, err := typesDKG.CalcQualifyNodes(mpk, comps, threshold)
+	disqualifyIDs := cc.disqualified(round, threshold)
+	qualifies, _, err := typesDKG.CalcQualifyNodesWithDisqualified(
+		mpk, disqualifyIDs, threshold)
 	if err != nil {
 		return err
 	}
@@ -601,9 +1456,9 @@ func (cc *configurationChain) recoverDKGInfo(
 	}
 
 	if !npksExists {
-		npks, err := typesDKG.NewNodePublicKeys(round,
+		npks, err := typesDKG.NewNodePublicKeysWithDisqualified(round,
 			cc.gov.DKGMasterPublicKeys(round),
-			cc.gov.DKGComplaints(round),
+			disqualifyIDs,
 			threshold)
 		if err != nil {
 			cc.logger.Warn("Failed to create DKGNodePublicKeys",
@@ -690,51 +1545,197 @@ func (cc *configurationChain) untouchTSigHash(hash common.Hash) {
 func (cc *configurationChain) runTSig(
 	round uint64, hash common.Hash, wait time.Duration) (
 	crypto.Signature, error) {
+	return cc.runTSigWithProgress(round, hash, wait, nil)
+}
+
+// runTSigWithProgress is runTSig reporting the progress of collecting
+// partial signatures. progress is called once for each new distinct partial
+// signature collected, with the count of them and the threshold, and without
+// holding any lock of cc.
+func (cc *configurationChain) runTSigWithProgress(
+	round uint64, hash common.Hash, wait time.Duration,
+	progress func(have, needed int)) (crypto.Signature, error) {
+	result, err := cc.runTSigResultWithProgress(round, hash, wait, progress)
+	if err != nil {
+		return crypto.Signature{}, err
+	}
+	return result.Signature, nil
+}
+
+// collectTSig recovers the threshold signature of hash from partial
+// signatures collected in wait, the signature is not verified.
+func (cc *configurationChain) collectTSig(
+	round uint64, hash common.Hash, wait time.Duration,
+	progress func(have, needed int)) (
+	crypto.Signature, *typesDKG.NodePublicKeys, error) {
 	npks, _, _ := cc.getDKGInfo(round, false)
 	if npks == nil {
-		return crypto.Signature{}, ErrDKGNotReady
+		return crypto.Signature{}, nil, ErrDKGNotReady
+	}
+	if signature, ok, err := cc.runTSigAlone(npks, round, hash); ok {
+		if err == nil && progress != nil {
+			progress(1, 1)
+		}
+		return signature, npks, err
 	}
 	cc.tsigReady.L.Lock()
 	defer cc.tsigReady.L.Unlock()
 	if _, exist := cc.tsig[hash]; exist {
-		return crypto.Signature{}, ErrTSigAlreadyRunning
+		return crypto.Signature{}, nil, ErrTSigAlreadyRunning
 	}
 	cc.tsig[hash] = newTSigProtocol(npks, hash)
-	pendingPsig := cc.pendingPsig[hash]
-	delete(cc.pendingPsig, hash)
+	var pendingPsig []*typesDKG.PartialSignature
+	if buf, exist := cc.pendingPsig[hash]; exist {
+		pendingPsig = buf.psigs
+		cc.tsig[hash].firstPsigTime = buf.firstTime
+	}
+	cc.purgePendingPsig(hash)
 	go func() {
-		for _, psig := range pendingPsig {
-			if err := cc.processPartialSignature(psig); err != nil {
-				cc.logger.Error("Failed to process partial signature",
+		for _, err := range cc.ProcessPartialSignatures(pendingPsig) {
+			if err != nil {
+				cc.floodLogger.Error("Failed to process partial signature",
 					"nodeID", cc.ID,
 					"error", err)
 			}
 		}
 	}()
 	timeout := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		time.Sleep(wait)
+		select {
+		case <-done:
+			return
+		case <-time.After(wait):
+		}
 		timeout <- struct{}{}
 		cc.tsigReady.Broadcast()
 	}()
 	var signature crypto.Signature
 	var err error
+	reported := 0
 	for func() bool {
-		signature, err = cc.tsig[hash].signature()
+		if cc.tsig[hash].aborted {
+			signature, err = crypto.Signature{}, ErrDKGAborted
+			return false
+		}
+		if have := len(cc.tsig[hash].sigs); progress != nil && have > reported {
+			needed := cc.tsig[hash].nodePublicKeys.Threshold
+			cc.tsigReady.L.Unlock()
+			for reported < have {
+				reported++
+				progress(reported, needed)
+			}
+			cc.tsigReady.L.Lock()
+		}
+		var (
+			psigs []dkg.PartialSignature
+			ids   dkg.IDs
+		)
+		psigs, ids, err = cc.tsig[hash].partialSignatures()
+		if err == nil {
+			// Recovering signature is expensive, don't block processing
+			// partial signatures of other runTSig calls.
+			cc.tsigReady.L.Unlock()
+			signature, err = cc.recoverSignature(psigs, ids)
+			cc.tsigReady.L.Lock()
+		}
 		select {
 		case <-timeout:
 			return false
 		default:
 		}
+		// It might be aborted while tsigReady.L is released above.
+		if err == ErrNotEnoughtPartialSignatures && cc.tsig[hash].aborted {
+			signature, err = crypto.Signature{}, ErrDKGAborted
+			return false
+		}
 		return err == ErrNotEnoughtPartialSignatures
 	}() {
 		cc.tsigReady.Wait()
 	}
+	if err == ErrNotEnoughtPartialSignatures && cc.tsigPartialResult {
+		err = newTSigPartialResult(cc.tsig[hash])
+	}
+	if err == nil && !cc.tsig[hash].firstPsigTime.IsZero() {
+		cc.recordTSigLatency(time.Since(cc.tsig[hash].firstPsigTime))
+	}
 	delete(cc.tsig, hash)
+	cc.forgetPartialSignatures(hash)
+	// Wake up CancelAll waiting for this runTSig to return.
+	cc.tsigReady.Broadcast()
 	if err != nil {
-		return crypto.Signature{}, err
+		return crypto.Signature{}, nil, err
+	}
+	return signature, npks, nil
+}
+
+// SetTSigPartialResult makes runTSig return the buffered partial signatures
+// as TSigPartialResult when it times out, instead of
+// ErrNotEnoughtPartialSignatures. It's not thread-safe and should be called
+// before runTSig.
+func (cc *configurationChain) SetTSigPartialResult(enabled bool) {
+	cc.tsigPartialResult = enabled
+}
+
+// SetTSigLatencyLimit sets the count of latencies of recent TSIGs retained for
+// TSigLatencyHistogram, the default is defaultTSigLatencyLimit.
+func (cc *configurationChain) SetTSigLatencyLimit(limit int) {
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	cc.tsigLatencyLimit = limit
+}
+
+// recordTSigLatency retains the latency of a completed TSIG, the oldest one is
+// dropped when there are already tsigLatencyLimit of them. It should be
+// called with tsigReady.L held.
+func (cc *configurationChain) recordTSigLatency(latency time.Duration) {
+	cc.tsigLatencies = append(cc.tsigLatencies, latency)
+	if over := len(cc.tsigLatencies) - cc.tsigLatencyLimit; over > 0 {
+		cc.tsigLatencies = append(
+			[]time.Duration(nil), cc.tsigLatencies[over:]...)
+	}
+}
+
+// TSigLatencyHistogram returns the latencies from the first partial signature
+// buffered or processed to the threshold signature recovered, of recent
+// TSIGs completed by runTSig, in the order they are completed. TSIGs run by
+// the only participant of a DKG are not included.
+func (cc *configurationChain) TSigLatencyHistogram() []time.Duration {
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	return append([]time.Duration(nil), cc.tsigLatencies...)
+}
+
+// SetTSigWorkers sets the count of goroutines recovering signatures of runTSig
+// at the same time, it's GOMAXPROCS when workers is not positive, which is the
+// default. It's not thread-safe and should be called before runTSig.
+func (cc *configurationChain) SetTSigWorkers(workers int) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	cc.tsigWorkers = make(chan struct{}, workers)
+}
+
+// recoverSignature recovers the threshold signature in one of tsigWorkers.
+func (cc *configurationChain) recoverSignature(
+	psigs []dkg.PartialSignature, ids dkg.IDs) (crypto.Signature, error) {
+	cc.tsigWorkers <- struct{}{}
+	defer func() { <-cc.tsigWorkers }()
+	return dkg.RecoverSignature(psigs, ids)
+}
+
+func newTSigPartialResult(tsig *tsigProtocol) *TSigPartialResult {
+	psigs := make(map[dkg.ID]dkg.PartialSignature, len(tsig.sigs))
+	for id, psig := range tsig.sigs {
+		psigs[id] = psig
+	}
+	return &TSigPartialResult{
+		Hash:              tsig.hash,
+		Threshold:         tsig.nodePublicKeys.Threshold,
+		Count:             len(psigs),
+		PartialSignatures: psigs,
 	}
-	return signature, nil
 }
 
 func (cc *configurationChain) runCRSTSig(
@@ -748,6 +1749,14 @@ func (cc *configurationChain) runCRSTSig(
 }
 
 func (cc *configurationChain) processPrivateShare(
+	prvShare *typesDKG.PrivateShare) error {
+	if buffered, err := cc.bufferPausedPrivateShare(prvShare); buffered {
+		return err
+	}
+	return cc.processPrivateShareNoPause(prvShare)
+}
+
+func (cc *configurationChain) processPrivateShareNoPause(
 	prvShare *typesDKG.PrivateShare) error {
 	cc.dkgLock.Lock()
 	defer cc.dkgLock.Unlock()
@@ -757,6 +1766,9 @@ func (cc *configurationChain) processPrivateShare(
 	if _, exist := cc.notarySet[prvShare.ProposerID]; !exist {
 		return ErrNotDKGParticipant
 	}
+	if err := cc.checkPrivateShareEquivocation(prvShare); err != nil {
+		return err
+	}
 	if !cc.mpkReady {
 		// TODO(jimmy-dexon): remove duplicated signature check in dkg module.
 		ok, err := utils.VerifyDKGPrivateShareSignature(prvShare)
@@ -769,13 +1781,287 @@ func (cc *configurationChain) processPrivateShare(
 		cc.pendingPrvShare[prvShare.ProposerID] = prvShare
 		return nil
 	}
-	return cc.dkg.processPrivateShare(prvShare)
+	err := cc.dkg.processPrivateShare(prvShare)
+	cc.traceSharesProcessed()
+	return err
+}
+
+// VerifyPrivateShare checks a private share of the running DKG is signed by
+// its proposer and matches the master public key of the proposer, it's only
+// available after master public keys are processed. Private shares failing
+// this check are complained when processed.
+func (cc *configurationChain) VerifyPrivateShare(
+	prvShare *typesDKG.PrivateShare) error {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	if cc.dkg == nil {
+		return ErrDKGNotRegistered
+	}
+	if !cc.mpkReady {
+		return ErrDKGNotReady
+	}
+	receiverID, exist := cc.dkg.idMap[prvShare.ReceiverID]
+	if !exist {
+		return ErrNotDKGParticipant
+	}
+	return cc.dkg.verifyPrivateShare(receiverID, prvShare)
+}
+
+// checkMasterPublicKeysEquivocation records an equivocation for each proposer
+// with more than one distinct master public key in mpks.
+func (cc *configurationChain) checkMasterPublicKeysEquivocation(
+	mpks []*typesDKG.MasterPublicKey) {
+	for _, e := range masterPublicKeysEquivocations(mpks) {
+		cc.floodLogger.Warn("Equivocating master public key",
+			"proposer", e.ProposerID,
+			"round", e.Round,
+			"reset", e.Reset)
+		cc.addEquivocation(e)
+	}
+}
+
+// masterPublicKeysEquivocations returns an equivocation for each signed master
+// public key in mpks conflicting with the first signed one of the same
+// proposer.
+func masterPublicKeysEquivocations(
+	mpks []*typesDKG.MasterPublicKey) (equivocations []Equivocation) {
+	received := make(map[types.NodeID]*typesDKG.MasterPublicKey, len(mpks))
+	for _, mpk := range mpks {
+		// Only signed messages could be used as evidence, both of them.
+		if ok, err := utils.VerifyDKGMasterPublicKeySignature(mpk); err != nil ||
+			!ok {
+			continue
+		}
+		prev, exist := received[mpk.ProposerID]
+		if !exist {
+			received[mpk.ProposerID] = mpk
+			continue
+		}
+		if prev.Round != mpk.Round || prev.Reset != mpk.Reset ||
+			(prev.DKGID.GetHexString() == mpk.DKGID.GetHexString() &&
+				prev.PublicKeyShares.Equal(&mpk.PublicKeyShares)) {
+			continue
+		}
+		equivocations = append(equivocations, Equivocation{
+			ProposerID:       mpk.ProposerID,
+			Round:            mpk.Round,
+			Reset:            mpk.Reset,
+			MasterPublicKeys: [2]*typesDKG.MasterPublicKey{prev, mpk},
+		})
+	}
+	return
+}
+
+// checkPrivateShareEquivocation records an equivocation if the proposer of
+// prvShare already sent a different private share to the same receiver. It
+// should be called with the signature of prvShare unverified, and cc.dkgLock
+// held.
+func (cc *configurationChain) checkPrivateShareEquivocation(
+	prvShare *typesDKG.PrivateShare) error {
+	if prvShare.Round != cc.dkg.round || prvShare.Reset != cc.dkg.reset {
+		return nil
+	}
+	shares, exist := cc.receivedPrvShare[prvShare.ProposerID]
+	if !exist {
+		shares = make(map[types.NodeID]*typesDKG.PrivateShare)
+		cc.receivedPrvShare[prvShare.ProposerID] = shares
+	}
+	prev, exist := shares[prvShare.ReceiverID]
+	if exist && (prev == nil || bytes.Compare(prev.PrivateShare.Bytes(),
+		prvShare.PrivateShare.Bytes()) == 0) {
+		// Either it's the same private share or the equivocation is already
+		// reported.
+		return nil
+	}
+	ok, err := utils.VerifyDKGPrivateShareSignature(prvShare)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIncorrectPrivateShareSignature
+	}
+	if !exist {
+		shares[prvShare.ReceiverID] = prvShare
+		return nil
+	}
+	cc.floodLogger.Warn("Equivocating private share",
+		"proposer", prvShare.ProposerID,
+		"receiver", prvShare.ReceiverID,
+		"round", prvShare.Round,
+		"reset", prvShare.Reset)
+	cc.addEquivocation(Equivocation{
+		ProposerID:    prvShare.ProposerID,
+		Round:         prvShare.Round,
+		Reset:         prvShare.Reset,
+		PrivateShares: [2]*typesDKG.PrivateShare{prev, prvShare},
+	})
+	shares[prvShare.ReceiverID] = nil
+	return nil
+}
+
+func (cc *configurationChain) addEquivocation(e Equivocation) {
+	cc.equivocationLock.Lock()
+	defer cc.equivocationLock.Unlock()
+	cc.equivocations[e.Round] = append(cc.equivocations[e.Round], e)
+}
+
+// Equivocations returns the evidences of conflicting DKG messages detected in
+// one round.
+func (cc *configurationChain) Equivocations(round uint64) []Equivocation {
+	cc.equivocationLock.RLock()
+	defer cc.equivocationLock.RUnlock()
+	ret := make([]Equivocation, len(cc.equivocations[round]))
+	copy(ret, cc.equivocations[round])
+	return ret
+}
+
+// purgePendingPsig removes buffered partial signatures of the hash, it should
+// be called with cc.tsigReady.L held.
+func (cc *configurationChain) purgePendingPsig(hash common.Hash) {
+	if timer, exist := cc.pendingPsigTimer[hash]; exist {
+		timer.Stop()
+		delete(cc.pendingPsigTimer, hash)
+	}
+	delete(cc.pendingPsig, hash)
 }
 
 func (cc *configurationChain) processPartialSignature(
 	psig *typesDKG.PartialSignature) error {
 	cc.tsigReady.L.Lock()
 	defer cc.tsigReady.L.Unlock()
+	if err := cc.processPartialSignatureNoLock(psig); err != nil {
+		return err
+	}
+	cc.tsigReady.Broadcast()
+	return nil
+}
+
+// TSigRemaining returns the count of partial signatures from distinct
+// qualified proposers still needed to recover the threshold signature of
+// hash, and the count of them already received. Partial signatures buffered
+// before runTSig are counted without verifying their content. ok is false
+// when the DKG of round is not ready.
+func (cc *configurationChain) TSigRemaining(round uint64, hash common.Hash) (
+	needed int, have int, ok bool) {
+	npks, _, err := cc.getDKGInfo(round, true)
+	if err != nil {
+		return 0, 0, false
+	}
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	if tsig, exist := cc.tsig[hash]; exist {
+		npks = tsig.nodePublicKeys
+		have = len(tsig.sigs)
+	} else {
+		if buf, exist := cc.pendingPsig[hash]; exist {
+			for _, psig := range buf.psigs {
+				if psig.Round != round {
+					continue
+				}
+				if _, exist := npks.QualifyNodeIDs[psig.ProposerID]; exist {
+					have++
+				}
+			}
+		}
+	}
+	if needed = npks.Threshold - have; needed < 0 {
+		needed = 0
+	}
+	return needed, have, true
+}
+
+// ProcessPartialSignatures ingests a batch of partial signatures under one
+// lock acquisition, the returned errors are paired with psigs by index.
+// Partial signatures for a hash which already has enough of them are skipped.
+func (cc *configurationChain) ProcessPartialSignatures(
+	psigs []*typesDKG.PartialSignature) []error {
+	errs := make([]error, len(psigs))
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	processed := false
+	for i, psig := range psigs {
+		if tsig, exist := cc.tsig[psig.Hash]; exist &&
+			len(tsig.sigs) >= tsig.nodePublicKeys.Threshold {
+			continue
+		}
+		if errs[i] = cc.processPartialSignatureNoLock(psig); errs[i] == nil {
+			processed = true
+		}
+	}
+	if processed {
+		cc.tsigReady.Broadcast()
+	}
+	return errs
+}
+
+// SetPartialSignatureRateLimit sets the count of partial signatures from one
+// proposer buffered before runTSig in one second, 0 means unlimited, which is
+// the default. It's not thread-safe and should be called before any partial
+// signature is processed.
+func (cc *configurationChain) SetPartialSignatureRateLimit(limit int) {
+	cc.psigRateLimit = limit
+}
+
+// allowPendingPsig checks if one more partial signature could be buffered
+// before runTSig, and counts it if so. Only the ones from qualified nodes of
+// its round, or from the notary set when the DKG result is not known yet, are
+// buffered, up to psigRateLimit from one proposer in one second. It should be
+// called with cc.tsigReady.L held.
+func (cc *configurationChain) allowPendingPsig(
+	psig *typesDKG.PartialSignature) error {
+	if ok, err := cc.isPsigProposer(psig.Round, psig.ProposerID); err != nil {
+		return err
+	} else if !ok {
+		return ErrNotDKGParticipant
+	}
+	if cc.psigRateLimit <= 0 {
+		return nil
+	}
+	now := time.Now()
+	// Windows expired are evicted at most once per second.
+	if now.Sub(cc.psigRateSwept) >= time.Second {
+		for nID, window := range cc.psigRateWindow {
+			if now.Sub(window.begin) >= time.Second {
+				delete(cc.psigRateWindow, nID)
+			}
+		}
+		cc.psigRateSwept = now
+	}
+	window, exist := cc.psigRateWindow[psig.ProposerID]
+	if !exist || now.Sub(window.begin) >= time.Second {
+		window = &psigRateWindow{begin: now}
+		cc.psigRateWindow[psig.ProposerID] = window
+	}
+	if window.count >= cc.psigRateLimit {
+		return ErrPartialSignatureRateLimited
+	}
+	window.count++
+	return nil
+}
+
+// isPsigProposer checks if nID is a qualified node of round, or in the notary
+// set of round when the DKG result of that round is not known yet.
+func (cc *configurationChain) isPsigProposer(
+	round uint64, nID types.NodeID) (bool, error) {
+	npks := func() *typesDKG.NodePublicKeys {
+		cc.dkgResult.RLock()
+		defer cc.dkgResult.RUnlock()
+		return cc.npks[round]
+	}()
+	if npks != nil {
+		_, exist := npks.QualifyNodeIDs[nID]
+		return exist, nil
+	}
+	notarySet, err := cc.cache.GetNotarySet(round)
+	if err != nil {
+		return false, err
+	}
+	_, exist := notarySet[nID]
+	return exist, nil
+}
+
+func (cc *configurationChain) processPartialSignatureNoLock(
+	psig *typesDKG.PartialSignature) error {
 	if _, exist := cc.tsig[psig.Hash]; !exist {
 		ok, err := utils.VerifyDKGPartialSignatureSignature(psig)
 		if err != nil {
@@ -784,12 +2070,128 @@ func (cc *configurationChain) processPartialSignature(
 		if !ok {
 			return ErrIncorrectPartialSignatureSignature
 		}
-		cc.pendingPsig[psig.Hash] = append(cc.pendingPsig[psig.Hash], psig)
+		if err := cc.allowPendingPsig(psig); err != nil {
+			return err
+		}
+		cc.bufferPendingPsig(psig)
+		cc.persistPartialSignature(psig)
 		return nil
 	}
 	if err := cc.tsig[psig.Hash].processPartialSignature(psig); err != nil {
 		return err
 	}
-	cc.tsigReady.Broadcast()
+	cc.persistPartialSignature(psig)
 	return nil
 }
+
+// SetPendingPartialSignatureTTL sets how long partial signatures are buffered
+// without any runTSig for their hash before purged, the default is
+// defaultPendingPsigTTL. It's not thread-safe and should be called before any
+// partial signature is processed.
+func (cc *configurationChain) SetPendingPartialSignatureTTL(
+	ttl time.Duration) {
+	cc.pendingPsigTTL = ttl
+}
+
+// bufferPendingPsig buffers a partial signature received before runTSig of
+// its hash, the ones from the same proposer in the same round as a buffered
+// one are dropped. It should be called with cc.tsigReady.L held.
+func (cc *configurationChain) bufferPendingPsig(
+	psig *typesDKG.PartialSignature) {
+	if _, exist := cc.pendingPsigTimer[psig.Hash]; !exist {
+		hash := psig.Hash
+		var timer *time.Timer
+		timer = time.AfterFunc(cc.pendingPsigTTL, func() {
+			cc.tsigReady.L.Lock()
+			defer cc.tsigReady.L.Unlock()
+			// The buffer might be consumed and refilled before we get
+			// the lock.
+			if cc.pendingPsigTimer[hash] == timer {
+				cc.purgePendingPsig(hash)
+				cc.forgetPartialSignatures(hash)
+			}
+		})
+		cc.pendingPsigTimer[hash] = timer
+	}
+	buf, exist := cc.pendingPsig[psig.Hash]
+	if !exist {
+		buf = newPsigBuffer()
+		cc.pendingPsig[psig.Hash] = buf
+	}
+	buf.add(psig)
+}
+
+// SetPersistPartialSignatures enables or disables saving partial signatures
+// for each hash to DB until its runTSig is done, it's not thread-safe and
+// should be called before any partial signature is processed.
+func (cc *configurationChain) SetPersistPartialSignatures(enabled bool) {
+	cc.persistPsig = enabled
+}
+
+// recoverPendingPsig buffers partial signatures saved in DB, as if they are
+// received before runTSig.
+func (cc *configurationChain) recoverPendingPsig() {
+	psigs, err := cc.db.GetPartialSignatures()
+	if err != nil {
+		cc.logger.Warn("Failed to recover partial signatures from DB",
+			"error", err)
+		return
+	}
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	for i := range psigs {
+		psig := psigs[i]
+		cc.bufferPendingPsig(&psig)
+		cc.persistedPsigBuffer(psig.Hash).add(&psig)
+	}
+}
+
+// persistPartialSignature adds the partial signature to the ones saved in DB
+// when persistPsig is enabled, it should be called with cc.tsigReady.L held.
+func (cc *configurationChain) persistPartialSignature(
+	psig *typesDKG.PartialSignature) {
+	if !cc.persistPsig {
+		return
+	}
+	buf := cc.persistedPsigBuffer(psig.Hash)
+	persisted := *psig
+	if !buf.add(&persisted) {
+		return
+	}
+	if err := cc.db.AddPartialSignature(persisted); err != nil {
+		cc.floodLogger.Warn("Failed to save partial signatures",
+			"round", psig.Round,
+			"hash", psig.Hash,
+			"error", err)
+	}
+}
+
+// persistedPsigBuffer returns the buffer of partial signatures of a hash
+// saved to DB, it should be called with cc.tsigReady.L held.
+func (cc *configurationChain) persistedPsigBuffer(
+	hash common.Hash) *psigBuffer {
+	buf, exist := cc.persistedPsig[hash]
+	if !exist {
+		buf = newPsigBuffer()
+		cc.persistedPsig[hash] = buf
+	}
+	return buf
+}
+
+// forgetPartialSignatures removes partial signatures of a hash saved to DB,
+// it should be called with cc.tsigReady.L held.
+func (cc *configurationChain) forgetPartialSignatures(hash common.Hash) {
+	buf, exist := cc.persistedPsig[hash]
+	if !exist {
+		return
+	}
+	delete(cc.persistedPsig, hash)
+	for round := range buf.rounds {
+		if err := cc.db.PutPartialSignatures(round, hash, nil); err != nil {
+			cc.logger.Warn("Failed to remove partial signatures",
+				"round", round,
+				"hash", hash,
+				"error", err)
+		}
+	}
+}
@@ -20,7 +20,14 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -45,6 +52,33 @@ type ConfigurationChainTestSuite struct {
 	dkgIDs  map[types.NodeID]dkg.ID
 	signers map[types.NodeID]*utils.Signer
 	pubKeys []crypto.PublicKey
+	// The node sets provided to configuration chains created by runDKG,
+	// utils.NodeSetCache is used when it's nil.
+	nodeSets NodeSetProvider
+	// Enable DKG traces of configuration chains created by runDKG.
+	traceDKG bool
+	// The master public keys provided to configuration chains created by
+	// runDKG via registerDKGWithMPKs, registerDKG is used when it's nil.
+	presetMPKs []*typesDKG.MasterPublicKey
+	// Record the DKG inputs of one configuration chain created by runDKG.
+	dkgRecorder *testDKGRecorder
+}
+
+type testNodeSetProvider struct {
+	notarySets map[uint64]map[types.NodeID]struct{}
+}
+
+func (p *testNodeSetProvider) GetNotarySet(
+	round uint64) (map[types.NodeID]struct{}, error) {
+	notarySet, exist := p.notarySets[round]
+	if !exist {
+		return nil, utils.ErrNodeSetNotReady
+	}
+	ret := make(map[types.NodeID]struct{}, len(notarySet))
+	for nID := range notarySet {
+		ret[nID] = struct{}{}
+	}
+	return ret, nil
 }
 
 type testCCGlobalReceiver struct {
@@ -52,19 +86,59 @@ type testCCGlobalReceiver struct {
 
 	nodes map[types.NodeID]*configurationChain
 	govs  map[types.NodeID]Governance
+
+	// Bytes of DKG messages sent in each round, by message type.
+	bandwidthLock sync.Mutex
+	bandwidth     map[uint64]map[string]uint64
+
+	recorder *testDKGRecorder
+
+	// Private shares and anti nack complaints being delivered.
+	delivering sync.WaitGroup
 }
 
 func newTestCCGlobalReceiver(
 	s *ConfigurationChainTestSuite) *testCCGlobalReceiver {
 	return &testCCGlobalReceiver{
-		s:     s,
-		nodes: make(map[types.NodeID]*configurationChain),
-		govs:  make(map[types.NodeID]Governance),
+		s:         s,
+		nodes:     make(map[types.NodeID]*configurationChain),
+		govs:      make(map[types.NodeID]Governance),
+		bandwidth: make(map[uint64]map[string]uint64),
+	}
+}
+
+// tally adds the size of msg encoded by typesDKG.Encode to the bandwidth of
+// round.
+func (r *testCCGlobalReceiver) tally(round uint64, kind string,
+	msg interface{}) {
+	b, err := typesDKG.Encode(msg)
+	if err != nil {
+		panic(err)
+	}
+	r.bandwidthLock.Lock()
+	defer r.bandwidthLock.Unlock()
+	if _, exist := r.bandwidth[round]; !exist {
+		r.bandwidth[round] = make(map[string]uint64)
+	}
+	r.bandwidth[round][kind] += uint64(len(b))
+}
+
+// BandwidthReport returns the bytes of DKG messages sent in round, by message
+// type. Messages proposed to governance are counted once, and private shares
+// are counted for each receiver.
+func (r *testCCGlobalReceiver) BandwidthReport(round uint64) map[string]uint64 {
+	r.bandwidthLock.Lock()
+	defer r.bandwidthLock.Unlock()
+	report := make(map[string]uint64, len(r.bandwidth[round]))
+	for kind, bytes := range r.bandwidth[round] {
+		report[kind] = bytes
 	}
+	return report
 }
 
 func (r *testCCGlobalReceiver) ProposeDKGComplaint(
 	complaint *typesDKG.Complaint) {
+	r.tally(complaint.Round, "complaint", complaint)
 	for _, gov := range r.govs {
 		gov.AddDKGComplaint(test.CloneDKGComplaint(complaint))
 	}
@@ -72,6 +146,7 @@ func (r *testCCGlobalReceiver) ProposeDKGComplaint(
 
 func (r *testCCGlobalReceiver) ProposeDKGMasterPublicKey(
 	mpk *typesDKG.MasterPublicKey) {
+	r.tally(mpk.Round, "mpk", mpk)
 	for _, gov := range r.govs {
 		gov.AddDKGMasterPublicKey(test.CloneDKGMasterPublicKey(mpk))
 	}
@@ -79,42 +154,62 @@ func (r *testCCGlobalReceiver) ProposeDKGMasterPublicKey(
 
 func (r *testCCGlobalReceiver) ProposeDKGPrivateShare(
 	prv *typesDKG.PrivateShare) {
+	r.tally(prv.Round, "private-share", prv)
+	r.delivering.Add(1)
 	go func() {
+		defer r.delivering.Done()
 		receiver, exist := r.nodes[prv.ReceiverID]
 		if !exist {
 			panic(errors.New("should exist"))
 		}
-		if err := receiver.processPrivateShare(prv); err != nil {
+		err := receiver.processPrivateShare(prv)
+		if err == ErrNotDKGParticipant && r.s.nodeSets != nil {
+			// The proposer is excluded by the hand-crafted node sets.
+			return
+		}
+		if err != nil {
 			panic(err)
 		}
+		r.recorder.record(receiver, prv)
 	}()
 }
 
 func (r *testCCGlobalReceiver) ProposeDKGAntiNackComplaint(
 	prv *typesDKG.PrivateShare) {
+	r.tally(prv.Round, "anti-nack-complaint", prv)
+	r.delivering.Add(1)
 	go func() {
+		defer r.delivering.Done()
 		for _, cc := range r.nodes {
-			if err := cc.processPrivateShare(
-				test.CloneDKGPrivateShare(prv)); err != nil {
+			prvShare := test.CloneDKGPrivateShare(prv)
+			err := cc.processPrivateShare(prvShare)
+			if err == ErrNotDKGParticipant && r.s.nodeSets != nil {
+				continue
+			}
+			if err != nil {
 				panic(err)
 			}
+			r.recorder.record(cc, prvShare)
 		}
 	}()
 }
 
 func (r *testCCGlobalReceiver) ProposeDKGMPKReady(ready *typesDKG.MPKReady) {
+	r.tally(ready.Round, "mpk-ready", ready)
 	for _, gov := range r.govs {
 		gov.AddDKGMPKReady(test.CloneDKGMPKReady(ready))
 	}
 }
 
 func (r *testCCGlobalReceiver) ProposeDKGFinalize(final *typesDKG.Finalize) {
+	r.tally(final.Round, "finalize", final)
 	for _, gov := range r.govs {
 		gov.AddDKGFinalize(test.CloneDKGFinalize(final))
 	}
 }
 
 func (r *testCCGlobalReceiver) ProposeDKGSuccess(success *typesDKG.Success) {
+	r.tally(success.Round, "success", success)
 	for _, gov := range r.govs {
 		gov.AddDKGSuccess(test.CloneDKGSuccess(success))
 	}
@@ -189,9 +284,187 @@ func (r *testCCReceiver) ProposeDKGSuccess(success *typesDKG.Success) {
 	r.recv.ProposeDKGSuccess(success)
 }
 
+type testEquivocatingGovernance struct {
+	Governance
+
+	extraMPKs []*typesDKG.MasterPublicKey
+}
+
+func (g *testEquivocatingGovernance) DKGMasterPublicKeys(
+	round uint64) []*typesDKG.MasterPublicKey {
+	return append(g.Governance.DKGMasterPublicKeys(round), g.extraMPKs...)
+}
+
+// testComplaintsGovernance replaces the DKG complaints in governance.
+type testComplaintsGovernance struct {
+	Governance
+
+	complaints []*typesDKG.Complaint
+}
+
+func (g *testComplaintsGovernance) DKGComplaints(
+	round uint64) []*typesDKG.Complaint {
+	return g.complaints
+}
+
+// testStrictDisqualificationPolicy disqualifies participants with any
+// equivocation detected, besides the ones disqualified by default.
+type testStrictDisqualificationPolicy struct {
+	DefaultDisqualificationPolicy
+}
+
+func (p testStrictDisqualificationPolicy) Disqualify(round uint64,
+	complaints []*typesDKG.Complaint, equivocations []Equivocation,
+	threshold int) map[types.NodeID]struct{} {
+	disqualifyIDs := p.DefaultDisqualificationPolicy.Disqualify(
+		round, complaints, equivocations, threshold)
+	for _, e := range equivocations {
+		disqualifyIDs[e.ProposerID] = struct{}{}
+	}
+	return disqualifyIDs
+}
+
+// testDKGPhaseInputs is what a configuration chain receives in one DKG phase.
+type testDKGPhaseInputs struct {
+	// The DKG state in governance by the end of this phase.
+	snapshot *test.DKGGovSnapshot
+	mpkReady bool
+	final    bool
+	// The private shares and anti nack complaints processed after previous
+	// phase and before this phase.
+	prvShares []*typesDKG.PrivateShare
+}
+
+// testDKGRecorder records the inputs of one configuration chain in each DKG
+// phase, which could be replayed by replayDKG without other nodes present.
+type testDKGRecorder struct {
+	nID types.NodeID
+
+	lock   sync.Mutex
+	phases []*testDKGPhaseInputs
+}
+
+func newTestDKGRecorder(nID types.NodeID) *testDKGRecorder {
+	return &testDKGRecorder{nID: nID}
+}
+
+// attach makes the recorder record the DKG state in gov after each DKG phase
+// of cc, it should be called before the DKG is registered.
+func (rec *testDKGRecorder) attach(
+	cc *configurationChain, gov *test.Governance) {
+	rec.phases = make([]*testDKGPhaseInputs, len(cc.dkgRunPhases))
+	for i := range rec.phases {
+		rec.phases[i] = &testDKGPhaseInputs{}
+	}
+	for i := range cc.dkgRunPhases {
+		i, run := i, cc.dkgRunPhases[i]
+		cc.dkgRunPhases[i] = func(round uint64, reset uint64) error {
+			err := run(round, reset)
+			rec.lock.Lock()
+			defer rec.lock.Unlock()
+			rec.phases[i].snapshot = gov.SnapshotDKG(round)
+			rec.phases[i].mpkReady = gov.IsDKGMPKReady(round)
+			rec.phases[i].final = gov.IsDKGFinal(round)
+			return err
+		}
+	}
+}
+
+// record adds a private share processed by cc to the inputs of the DKG phase
+// it's about to run.
+func (rec *testDKGRecorder) record(
+	cc *configurationChain, prvShare *typesDKG.PrivateShare) {
+	if rec == nil || cc.ID != rec.nID {
+		return
+	}
+	step := func() int {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		if cc.dkg == nil {
+			return -1
+		}
+		return cc.dkg.step
+	}()
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	if step < 0 || step >= len(rec.phases) {
+		return
+	}
+	rec.phases[step].prvShares = append(rec.phases[step].prvShares, prvShare)
+}
+
+// testReplayGovernance provides the DKG state recorded by testDKGRecorder by
+// the end of the current phase.
+type testReplayGovernance struct {
+	Governance
+
+	lock   sync.RWMutex
+	inputs *testDKGPhaseInputs
+}
+
+func (g *testReplayGovernance) setPhase(inputs *testDKGPhaseInputs) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.inputs = inputs
+}
+
+func (g *testReplayGovernance) DKGMasterPublicKeys(
+	round uint64) []*typesDKG.MasterPublicKey {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.inputs.snapshot.MasterPublicKeys
+}
+
+func (g *testReplayGovernance) DKGComplaints(
+	round uint64) []*typesDKG.Complaint {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.inputs.snapshot.Complaints
+}
+
+func (g *testReplayGovernance) IsDKGMPKReady(round uint64) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.inputs.mpkReady
+}
+
+func (g *testReplayGovernance) IsDKGFinal(round uint64) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.inputs.final
+}
+
+// testDroppingCCReceiver drops all DKG messages, for a configuration chain
+// running DKG without other nodes present.
+type testDroppingCCReceiver struct{}
+
+func (r testDroppingCCReceiver) ProposeDKGComplaint(*typesDKG.Complaint) {}
+
+func (r testDroppingCCReceiver) ProposeDKGMasterPublicKey(
+	*typesDKG.MasterPublicKey) {
+}
+
+func (r testDroppingCCReceiver) ProposeDKGPrivateShare(
+	*typesDKG.PrivateShare) {
+}
+
+func (r testDroppingCCReceiver) ProposeDKGAntiNackComplaint(
+	*typesDKG.PrivateShare) {
+}
+
+func (r testDroppingCCReceiver) ProposeDKGMPKReady(*typesDKG.MPKReady) {}
+
+func (r testDroppingCCReceiver) ProposeDKGFinalize(*typesDKG.Finalize) {}
+
+func (r testDroppingCCReceiver) ProposeDKGSuccess(*typesDKG.Success) {}
+
 func (s *ConfigurationChainTestSuite) setupNodes(n int) {
 	s.nIDs = make(types.NodeIDs, 0, n)
 	s.signers = make(map[types.NodeID]*utils.Signer, n)
+	s.nodeSets = nil
+	s.traceDKG = false
+	s.presetMPKs = nil
+	s.dkgRecorder = nil
 	s.dkgIDs = make(map[types.NodeID]dkg.ID)
 	s.pubKeys = nil
 	ids := make(dkg.IDs, 0, n)
@@ -245,33 +518,88 @@ func (evt *testEvent) stop() {
 func (s *ConfigurationChainTestSuite) runDKG(
 	k, n int, round, reset uint64) map[types.NodeID]*configurationChain {
 	s.setupNodes(n)
+	return s.runDKGWithRand(k, round, reset, nil)
+}
 
+// runDKGWithRand runs DKG with nodes already setup, the DKG polynomials of
+// each node would be generated from rands if provided.
+func (s *ConfigurationChainTestSuite) runDKGWithRand(
+	k int, round, reset uint64,
+	rands map[types.NodeID]io.Reader) map[types.NodeID]*configurationChain {
+	cfgChains, _ := s.runDKGWithSnapshot(k, round, reset, rands, nil)
+	return cfgChains
+}
+
+// runDKGWithSnapshot runs DKG with nodes already setup, the governance of
+// each node would be constructed from snapshot if provided. The DKG state
+// snapshot at round start is returned.
+func (s *ConfigurationChainTestSuite) runDKGWithSnapshot(
+	k int, round, reset uint64,
+	rands map[types.NodeID]io.Reader,
+	snapshot *test.DKGGovSnapshot) (
+	map[types.NodeID]*configurationChain, *test.DKGGovSnapshot) {
+	n := len(s.nIDs)
 	evts := make(map[types.NodeID]*testEvent)
 	cfgChains := make(map[types.NodeID]*configurationChain)
 	recv := newTestCCGlobalReceiver(s)
 
 	for _, nID := range s.nIDs {
 		evts[nID] = newTestEvent()
-		gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
-			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
-		), ConfigRoundShift)
+		state := test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+		var (
+			gov *test.Governance
+			err error
+		)
+		if snapshot != nil {
+			gov, err = test.NewGovernanceFromDKGSnapshot(
+				state, ConfigRoundShift, snapshot)
+		} else {
+			gov, err = test.NewGovernance(state, ConfigRoundShift)
+		}
 		s.Require().NoError(err)
-		cache := utils.NewNodeSetCache(gov)
+		var cache NodeSetProvider = utils.NewNodeSetCache(gov)
+		if s.nodeSets != nil {
+			cache = s.nodeSets
+		}
 		dbInst, err := db.NewMemBackedDB()
 		s.Require().NoError(err)
+		if s.presetMPKs != nil {
+			// Preset master public keys are the ones already in governance,
+			// and only accepted with the DKG protocol dealing them kept in db.
+			for _, mpk := range s.presetMPKs {
+				gov.AddDKGMasterPublicKey(mpk)
+			}
+			s.putDKGProtocolWithRand(dbInst, nID, round, reset, k, rands[nID])
+		}
 		cfgChains[nID] = newConfigurationChain(nID,
 			newTestCCReceiver(nID, recv), gov, cache, dbInst,
 			&common.NullLogger{})
+		cfgChains[nID].dkgRand = rands[nID]
+		cfgChains[nID].SetDKGTrace(s.traceDKG)
 		recv.nodes[nID] = cfgChains[nID]
 		recv.govs[nID] = gov
+		if s.dkgRecorder != nil && s.dkgRecorder.nID == nID {
+			s.dkgRecorder.attach(cfgChains[nID], gov)
+			recv.recorder = s.dkgRecorder
+		}
 	}
 
 	for _, cc := range cfgChains {
+		if s.presetMPKs != nil {
+			s.Require().NoError(cc.registerDKGWithMPKs(
+				context.Background(), round, reset, k, s.presetMPKs))
+			continue
+		}
 		cc.registerDKG(context.Background(), round, reset, k)
 	}
 
+	var started *test.DKGGovSnapshot
 	for _, gov := range recv.govs {
 		s.Require().Len(gov.DKGMasterPublicKeys(round), n)
+		if started == nil {
+			started = gov.(*test.Governance).SnapshotDKG(round)
+		}
 	}
 
 	errs := make(chan error, n)
@@ -289,7 +617,119 @@ func (s *ConfigurationChainTestSuite) runDKG(
 	for range cfgChains {
 		s.Require().NoError(<-errs)
 	}
-	return cfgChains
+	return cfgChains, started
+}
+
+// replayDKG runs DKG of the node recorded by rec alone, the DKG polynomial
+// would be generated from rand. Each DKG phase is triggered after the recorded
+// inputs preceding it are processed, and the DKG state in governance is
+// replaced by the recorded one of that phase.
+func (s *ConfigurationChainTestSuite) replayDKG(
+	rec *testDKGRecorder, k int, round, reset uint64,
+	rand io.Reader) *configurationChain {
+	state := test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+	g, err := test.NewGovernance(state, ConfigRoundShift)
+	s.Require().NoError(err)
+	gov := &testReplayGovernance{Governance: g, inputs: rec.phases[0]}
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	cc := newConfigurationChain(rec.nID, testDroppingCCReceiver{}, gov,
+		utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+	cc.dkgRand = rand
+	done := make(chan struct{}, len(cc.dkgRunPhases))
+	for i := range cc.dkgRunPhases {
+		run := cc.dkgRunPhases[i]
+		cc.dkgRunPhases[i] = func(round uint64, reset uint64) error {
+			defer func() { done <- struct{}{} }()
+			return run(round, reset)
+		}
+	}
+	s.Require().NoError(
+		cc.registerDKG(context.Background(), round, reset, k))
+	cfg := utils.GetConfigWithPanic(gov, round, nil)
+	offsets := dkgPhaseOffsets(cfg, len(cc.dkgRunPhases))
+	dkgBeginHeight := uint64(10)
+	event := common.NewEvent()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- cc.runDKG(round, reset, event, dkgBeginHeight, 0)
+	}()
+	// Phases are scheduled once runDKG is running.
+	for !func() bool {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		return cc.dkgRunning
+	}() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i, inputs := range rec.phases {
+		for _, prvShare := range inputs.prvShares {
+			s.Require().NoError(cc.processPrivateShare(prvShare))
+		}
+		gov.setPhase(inputs)
+		event.NotifyHeight(dkgBeginHeight + offsets[i])
+		<-done
+	}
+	s.Require().NoError(<-errs)
+	return cc
+}
+
+// startDKG registers and runs DKG of a round on configuration chains which
+// might be still serving TSIG of previous rounds. The returned channel
+// receives the result of runDKG of each node.
+func (s *ConfigurationChainTestSuite) startDKG(
+	cfgChains map[types.NodeID]*configurationChain,
+	k int, round, reset uint64) <-chan error {
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+	errs := make(chan error, len(cfgChains))
+	for _, cc := range cfgChains {
+		evt := newTestEvent()
+		go func(cc *configurationChain) {
+			defer evt.stop()
+			errs <- cc.runDKG(round, reset, evt.event, 10, 0)
+		}(cc)
+		evt.run(100 * time.Millisecond)
+	}
+	return errs
+}
+
+// runTSig runs TSIG of a hash on all qualified nodes of a round, makes sure
+// they recover the same signature and returns it.
+func (s *ConfigurationChainTestSuite) runTSig(hash common.Hash, round uint64,
+	cfgChains map[types.NodeID]*configurationChain) crypto.Signature {
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	type result struct {
+		tsig crypto.Signature
+		err  error
+	}
+	results := make(chan result, len(cfgChains))
+	count := 0
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
+			continue
+		}
+		count++
+		go func(cc *configurationChain) {
+			tsig, err := cc.runTSig(round, hash, 5*time.Second)
+			results <- result{tsig, err}
+		}(cc)
+		for _, psig := range psigs {
+			s.Require().NoError(cc.processPartialSignature(psig))
+		}
+	}
+	var tsig crypto.Signature
+	for i := 0; i < count; i++ {
+		r := <-results
+		s.Require().NoError(r.err)
+		if i > 0 {
+			s.Require().Equal(tsig, r.tsig)
+		}
+		tsig = r.tsig
+	}
+	return tsig
 }
 
 func (s *ConfigurationChainTestSuite) preparePartialSignature(
@@ -316,6 +756,62 @@ func (s *ConfigurationChainTestSuite) preparePartialSignature(
 	return
 }
 
+// assertConsistentQualifiedSets makes sure all nodes qualified in a round
+// agree on the same qualified set. The set is calculated from the MPKs and
+// complaints in governance, which are the same for every node once DKG is
+// final, so any difference is a divergence of DKG results.
+func (s *ConfigurationChainTestSuite) assertConsistentQualifiedSets(
+	cfgChains map[types.NodeID]*configurationChain, round uint64) {
+	var (
+		expected map[types.NodeID]struct{}
+		from     types.NodeID
+	)
+	for nID, cc := range cfgChains {
+		npks, exist := cc.npks[round]
+		if !exist {
+			continue
+		}
+		if _, exist := npks.QualifyNodeIDs[nID]; !exist {
+			continue
+		}
+		if expected == nil {
+			expected, from = npks.QualifyNodeIDs, nID
+			continue
+		}
+		s.Require().Equal(expected, npks.QualifyNodeIDs,
+			"qualified sets of %s and %s are different", from, nID)
+	}
+	s.Require().NotNil(expected, "no node is qualified")
+}
+
+// assertConsistentDisqualifications makes sure all nodes with DKG results of a
+// round agree on the disqualified participants, and returns them. Policies
+// only depend on evidences in governance, so any difference is a divergence
+// of DKG results.
+func (s *ConfigurationChainTestSuite) assertConsistentDisqualifications(
+	cfgChains map[types.NodeID]*configurationChain,
+	round uint64) map[types.NodeID]struct{} {
+	var (
+		expected map[types.NodeID]struct{}
+		from     types.NodeID
+	)
+	for nID, cc := range cfgChains {
+		npks, exist := cc.npks[round]
+		if !exist {
+			continue
+		}
+		disqualified := cc.disqualified(round, npks.Threshold)
+		if expected == nil {
+			expected, from = disqualified, nID
+			continue
+		}
+		s.Require().Equal(expected, disqualified,
+			"disqualified sets of %s and %s are different", from, nID)
+	}
+	s.Require().NotNil(expected, "no node has DKG result")
+	return expected
+}
+
 // TestConfigurationChain will test the entire DKG+TISG protocol including
 // exchanging private shares, recovering share secret, creating partial sign and
 // recovering threshold signature.
@@ -326,6 +822,8 @@ func (s *ConfigurationChainTestSuite) TestConfigurationChain() {
 	round := DKGDelayRound
 	reset := uint64(0)
 	cfgChains := s.runDKG(k, n, round, reset)
+	s.assertConsistentQualifiedSets(cfgChains, round)
+	s.Require().Empty(s.assertConsistentDisqualifications(cfgChains, round))
 
 	hash := crypto.Keccak256Hash([]byte("🌚🌝"))
 	psigs := s.preparePartialSignature(hash, round, cfgChains)
@@ -369,6 +867,33 @@ func (s *ConfigurationChainTestSuite) TestConfigurationChain() {
 	}
 }
 
+func (s *ConfigurationChainTestSuite) TestDKGBandwidth() {
+	round := DKGDelayRound
+	reset := uint64(0)
+	report := func(n int) map[string]uint64 {
+		cfgChains := s.runDKG(n/2+1, n, round, reset)
+		recv := cfgChains[s.nIDs[0]].recv.(*testCCReceiver).recv
+		return recv.BandwidthReport(round)
+	}
+	small, large := report(4), report(8)
+	for _, kind := range []string{
+		"mpk", "private-share", "mpk-ready", "finalize", "success"} {
+		s.Require().NotZero(small[kind], kind)
+		s.Require().NotZero(large[kind], kind)
+	}
+	s.Require().Zero(small["complaint"])
+	// Each node sends one private share to every node, the cost of private
+	// shares grows with the square of the count of nodes.
+	perShareSmall := float64(small["private-share"]) / 16
+	perShareLarge := float64(large["private-share"]) / 64
+	s.Require().InEpsilon(perShareSmall, perShareLarge, 0.05)
+	// Master public keys are proposed once by each node, but the size of
+	// each grows with the threshold.
+	perMPKSmall := float64(small["mpk"]) / 4
+	perMPKLarge := float64(large["mpk"]) / 8
+	s.Require().True(perMPKLarge > perMPKSmall)
+}
+
 func (s *ConfigurationChainTestSuite) TestDKGMasterPublicKeyDelayAdd() {
 	k := 4
 	n := 7
@@ -442,48 +967,46 @@ func (s *ConfigurationChainTestSuite) TestDKGMasterPublicKeyDelayAdd() {
 	}
 }
 
-func (s *ConfigurationChainTestSuite) TestDKGComplaintDelayAdd() {
+func (s *ConfigurationChainTestSuite) TestDKGMasterPublicKeyNeverAdd() {
 	k := 4
 	n := 7
 	round := DKGDelayRound
 	reset := uint64(0)
-	lambdaDKG := 1000 * time.Millisecond
 	minBlockInterval := 100 * time.Millisecond
 	s.setupNodes(n)
 
 	cfgChains := make(map[types.NodeID]*configurationChain)
 	recv := newTestCCGlobalReceiver(s)
-	recvs := make(map[types.NodeID]*testCCReceiver)
+	absentNode := s.nIDs[0]
+
 	for _, nID := range s.nIDs {
 		state := test.NewState(DKGDelayRound,
 			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
 		gov, err := test.NewGovernance(state, ConfigRoundShift)
 		s.Require().NoError(err)
-		s.Require().NoError(state.RequestChange(
-			test.StateChangeLambdaDKG, lambdaDKG))
 		s.Require().NoError(state.RequestChange(
 			test.StateChangeMinBlockInterval, minBlockInterval))
 		cache := utils.NewNodeSetCache(gov)
 		dbInst, err := db.NewMemBackedDB()
 		s.Require().NoError(err)
-		recvs[nID] = newTestCCReceiver(nID, recv)
-		cfgChains[nID] = newConfigurationChain(nID, recvs[nID], gov, cache,
-			dbInst, &common.NullLogger{})
+		cfgChains[nID] = newConfigurationChain(
+			nID, newTestCCReceiver(nID, recv), gov, cache, dbInst,
+			&common.NullLogger{})
 		recv.nodes[nID] = cfgChains[nID]
 		recv.govs[nID] = gov
 	}
-
+	// The absent node never registers its DKG.
+	delete(cfgChains, absentNode)
 	for _, cc := range cfgChains {
 		cc.registerDKG(context.Background(), round, reset, k)
 	}
-
 	for _, gov := range recv.govs {
-		s.Require().Len(gov.DKGMasterPublicKeys(round), n)
+		s.Require().Len(gov.DKGMasterPublicKeys(round), n-1)
 	}
 
-	errs := make(chan error, n)
+	errs := make(chan error, len(cfgChains))
 	wg := sync.WaitGroup{}
-	wg.Add(n)
+	wg.Add(len(cfgChains))
 	for _, cc := range cfgChains {
 		evt := newTestEvent()
 		go func(cc *configurationChain) {
@@ -493,146 +1016,2212 @@ func (s *ConfigurationChainTestSuite) TestDKGComplaintDelayAdd() {
 		evt.run(minBlockInterval)
 		defer evt.stop()
 	}
-	complaints := -1
-	go func() {
-		// Node 0 proposes NackComplaint to all others at 3λ but they should
-		// be ignored because NackComplaint should be proposed before 2λ.
-		time.Sleep(lambdaDKG * 4)
-		for _, gov := range recv.govs {
-			if complaints == -1 {
-				complaints = len(gov.DKGComplaints(round))
-			}
-			s.Require().Len(gov.DKGComplaints(round), complaints)
-		}
-		nID := s.nIDs[0]
-		for _, targetNode := range s.nIDs {
-			if targetNode == nID {
-				continue
-			}
-			recvs[nID].ProposeDKGComplaint(&typesDKG.Complaint{
-				Round: round,
-				PrivateShare: typesDKG.PrivateShare{
-					ProposerID: targetNode,
-					Round:      round,
-				},
-			})
-		}
-	}()
 	wg.Wait()
-	complaints += len(s.nIDs) - 1
-	for _, gov := range recv.govs {
-		s.Require().Len(gov.DKGComplaints(round), complaints)
-	}
 	for range cfgChains {
 		s.Require().NoError(<-errs)
 	}
 	for nID, cc := range cfgChains {
-		if _, exist := cc.npks[round]; !exist {
-			s.FailNow("Should be qualified")
-		}
-		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
-			s.FailNow("Should be qualified")
-		}
+		npks, exist := cc.npks[round]
+		s.Require().True(exist)
+		s.Require().Len(npks.QualifyIDs, n-1)
+		_, exist = npks.QualifyNodeIDs[nID]
+		s.Require().True(exist)
+		_, exist = npks.QualifyNodeIDs[absentNode]
+		s.Require().False(exist)
+		s.Require().Equal(types.NodeIDs{absentNode},
+			cc.MissingMasterPublicKeys(round))
 	}
 }
 
-func (s *ConfigurationChainTestSuite) TestMultipleTSig() {
-	k := 2
+func (s *ConfigurationChainTestSuite) TestDKGMinParticipants() {
+	k := 4
 	n := 7
 	round := DKGDelayRound
 	reset := uint64(0)
-	cfgChains := s.runDKG(k, n, round, reset)
-
-	hash1 := crypto.Keccak256Hash([]byte("Hash1"))
-	hash2 := crypto.Keccak256Hash([]byte("Hash2"))
-
-	psigs1 := s.preparePartialSignature(hash1, round, cfgChains)
-	psigs2 := s.preparePartialSignature(hash2, round, cfgChains)
-
-	tsigs1 := make([]crypto.Signature, 0, n)
-	tsigs2 := make([]crypto.Signature, 0, n)
-
-	errs := make(chan error, n*2)
-	tsigChan1 := make(chan crypto.Signature, n)
-	tsigChan2 := make(chan crypto.Signature, n)
-	for nID, cc := range cfgChains {
-		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
-			continue
+	minBlockInterval := 100 * time.Millisecond
+	// One node never registers its DKG, and the others run DKG with the
+	// minimum participation.
+	runDKG := func(minimum uint32) map[types.NodeID]error {
+		s.setupNodes(n)
+		cfgChains := make(map[types.NodeID]*configurationChain)
+		recv := newTestCCGlobalReceiver(s)
+		for _, nID := range s.nIDs {
+			state := test.NewState(DKGDelayRound,
+				s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+			gov, err := test.NewGovernance(state, ConfigRoundShift)
+			s.Require().NoError(err)
+			s.Require().NoError(state.RequestChange(
+				test.StateChangeMinBlockInterval, minBlockInterval))
+			s.Require().NoError(state.RequestChange(
+				test.StateChangeMinDKGParticipants, minimum))
+			dbInst, err := db.NewMemBackedDB()
+			s.Require().NoError(err)
+			cfgChains[nID] = newConfigurationChain(
+				nID, newTestCCReceiver(nID, recv), gov,
+				utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+			recv.nodes[nID] = cfgChains[nID]
+			recv.govs[nID] = gov
 		}
-		go func(cc *configurationChain) {
-			tsig1, err := cc.runTSig(round, hash1, 5*time.Second)
-			// Prevent racing by collecting errors and check in main thread.
-			errs <- err
-			tsigChan1 <- tsig1
-		}(cc)
-		go func(cc *configurationChain) {
-			tsig2, err := cc.runTSig(round, hash2, 5*time.Second)
-			// Prevent racing by collecting errors and check in main thread.
-			errs <- err
+		delete(cfgChains, s.nIDs[0])
+		for _, cc := range cfgChains {
+			cc.registerDKG(context.Background(), round, reset, k)
+		}
+		errs := make(map[types.NodeID]error)
+		lock := sync.Mutex{}
+		wg := sync.WaitGroup{}
+		wg.Add(len(cfgChains))
+		for nID, cc := range cfgChains {
+			evt := newTestEvent()
+			go func(nID types.NodeID, cc *configurationChain) {
+				defer wg.Done()
+				err := cc.runDKG(round, reset, evt.event, 0, 0)
+				lock.Lock()
+				defer lock.Unlock()
+				errs[nID] = err
+			}(nID, cc)
+			evt.run(minBlockInterval)
+			defer evt.stop()
+		}
+		wg.Wait()
+		for nID, cc := range cfgChains {
+			_, exist := cc.npks[round]
+			s.Require().Equal(errs[nID] == nil, exist)
+		}
+		return errs
+	}
+	// The default minimum is the DKG threshold.
+	for _, err := range runDKG(0) {
+		s.Require().NoError(err)
+	}
+	for _, err := range runDKG(uint32(n - 1)) {
+		s.Require().NoError(err)
+	}
+	for _, err := range runDKG(uint32(n)) {
+		s.Require().Equal(ErrInsufficientParticipation, err)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGThresholdMismatch() {
+	// The threshold of 4 notaries is 3 by configuration.
+	k := 3
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	for _, nID := range s.nIDs {
+		state := test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+		gov, err := test.NewGovernance(state, ConfigRoundShift)
+		s.Require().NoError(err)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		cfgChains[nID] = newConfigurationChain(
+			nID, newTestCCReceiver(nID, recv), gov,
+			utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+	// One node is misconfigured with another threshold.
+	for nID, cc := range cfgChains {
+		threshold := k
+		if nID == s.nIDs[0] {
+			threshold = k + 1
+		}
+		cc.registerDKG(context.Background(), round, reset, threshold)
+	}
+	type result struct {
+		nID types.NodeID
+		err error
+	}
+	results := make(chan result, n)
+	for nID, cc := range cfgChains {
+		evt := newTestEvent()
+		// Phases begin at height 10 like runDKGWithRand, so they are
+		// registered before their heights are notified. Otherwise the phases
+		// with heights already passed would be triggered together.
+		go func(nID types.NodeID, cc *configurationChain) {
+			results <- result{nID, cc.runDKG(round, reset, evt.event, 10, 0)}
+		}(nID, cc)
+		evt.run(100 * time.Millisecond)
+		defer evt.stop()
+	}
+	// Only the misconfigured node gives up, others exclude it.
+	for range cfgChains {
+		r := <-results
+		if r.nID == s.nIDs[0] {
+			s.Require().Equal(ErrThresholdMismatch, r.err)
+		} else {
+			s.Require().NoError(r.err)
+		}
+	}
+	_, exist := cfgChains[s.nIDs[0]].npks[round]
+	s.Require().False(exist)
+	delete(cfgChains, s.nIDs[0])
+	for _, cc := range cfgChains {
+		s.Require().Len(cc.npks[round].QualifyNodeIDs, n-1)
+		s.Require().NotContains(cc.npks[round].QualifyNodeIDs, s.nIDs[0])
+		// The group public key from governance excludes it as well.
+		gpk, err := typesDKG.NewGroupPublicKey(round,
+			cc.gov.DKGMasterPublicKeys(round), cc.gov.DKGComplaints(round), k)
+		s.Require().NoError(err)
+		s.Require().Equal(cc.npks[round].QualifyNodeIDs, gpk.QualifyNodeIDs)
+	}
+	s.runTSig(crypto.Keccak256Hash([]byte("threshold")), round, cfgChains)
+}
+
+// flakyNodeSetProvider fails the first few reads with an error.
+type flakyNodeSetProvider struct {
+	NodeSetProvider
+	lock     sync.Mutex
+	failures int
+	err      error
+}
+
+func (p *flakyNodeSetProvider) GetNotarySet(round uint64) (
+	map[types.NodeID]struct{}, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return nil, p.err
+	}
+	return p.NodeSetProvider.GetNotarySet(round)
+}
+
+func (s *ConfigurationChainTestSuite) TestExponentialBackoffPolicy() {
+	policy := ExponentialBackoffPolicy{
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     30 * time.Millisecond,
+		MaxRetries:   3,
+	}
+	expected := []time.Duration{
+		10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond}
+	for i, d := range expected {
+		delay, retry := policy.Backoff(i+1, utils.ErrNodeSetNotReady)
+		s.Require().True(retry)
+		s.Require().Equal(d, delay)
+	}
+	_, retry := policy.Backoff(len(expected)+1, utils.ErrNodeSetNotReady)
+	s.Require().False(retry)
+	_, retry = policy.Backoff(1, ErrDKGNotReady)
+	s.Require().False(retry)
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGGovernanceRetry() {
+	k := 4
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	minBlockInterval := 100 * time.Millisecond
+	policy := ExponentialBackoffPolicy{
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     100 * time.Millisecond,
+		MaxRetries:   3,
+	}
+	// Every node fails its first read of the notary set with readErr.
+	runDKG := func(
+		readErr error, policy GovernanceRetryPolicy) map[types.NodeID]error {
+		s.setupNodes(n)
+		cfgChains := make(map[types.NodeID]*configurationChain)
+		recv := newTestCCGlobalReceiver(s)
+		for _, nID := range s.nIDs {
+			state := test.NewState(DKGDelayRound,
+				s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+			gov, err := test.NewGovernance(state, ConfigRoundShift)
+			s.Require().NoError(err)
+			s.Require().NoError(state.RequestChange(
+				test.StateChangeMinBlockInterval, minBlockInterval))
+			dbInst, err := db.NewMemBackedDB()
+			s.Require().NoError(err)
+			cache := &flakyNodeSetProvider{
+				NodeSetProvider: utils.NewNodeSetCache(gov),
+				failures:        1,
+				err:             readErr,
+			}
+			cfgChains[nID] = newConfigurationChain(
+				nID, newTestCCReceiver(nID, recv), gov, cache, dbInst,
+				&common.NullLogger{})
+			cfgChains[nID].SetGovernanceRetryPolicy(policy)
+			recv.nodes[nID] = cfgChains[nID]
+			recv.govs[nID] = gov
+		}
+		for _, cc := range cfgChains {
+			cc.registerDKG(context.Background(), round, reset, k)
+		}
+		errs := make(map[types.NodeID]error)
+		lock := sync.Mutex{}
+		wg := sync.WaitGroup{}
+		wg.Add(len(cfgChains))
+		for nID, cc := range cfgChains {
+			evt := newTestEvent()
+			go func(nID types.NodeID, cc *configurationChain) {
+				defer wg.Done()
+				err := cc.runDKG(round, reset, evt.event, 0, 0)
+				lock.Lock()
+				defer lock.Unlock()
+				errs[nID] = err
+			}(nID, cc)
+			evt.run(minBlockInterval)
+			defer evt.stop()
+		}
+		wg.Wait()
+		for nID, cc := range cfgChains {
+			_, exist := cc.npks[round]
+			s.Require().Equal(errs[nID] == nil, exist)
+		}
+		return errs
+	}
+	// Transient errors are retried.
+	for _, err := range runDKG(utils.ErrNodeSetNotReady, policy) {
+		s.Require().NoError(err)
+	}
+	// Nothing is retried by default.
+	for _, err := range runDKG(utils.ErrNodeSetNotReady, nil) {
+		s.Require().Equal(ErrDKGNotRegistered, err)
+	}
+	// Permanent errors are not retried.
+	for _, err := range runDKG(ErrDKGNotReady, policy) {
+		s.Require().Equal(ErrDKGNotRegistered, err)
+	}
+}
+
+// testDuplicateNodeGovernance registers the first node of the node set twice.
+type testDuplicateNodeGovernance struct {
+	*test.Governance
+}
+
+func (g *testDuplicateNodeGovernance) NodeSet(
+	round uint64) []crypto.PublicKey {
+	keys := g.Governance.NodeSet(round)
+	if len(keys) == 0 {
+		return keys
+	}
+	return append(keys, keys[0])
+}
+
+func (s *ConfigurationChainTestSuite) TestRegisterDKGDuplicateNodeID() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	state := test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+	gov, err := test.NewGovernance(state, ConfigRoundShift)
+	s.Require().NoError(err)
+	dupGov := &testDuplicateNodeGovernance{gov}
+	cache := utils.NewNodeSetCache(dupGov)
+	// The node set cache still serves other modules, like agreements.
+	notarySet, err := cache.GetNotarySet(round)
+	s.Require().NoError(err)
+	s.Require().Len(notarySet, n)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc := newConfigurationChain(nID, newTestCCReceiver(nID, recv), dupGov,
+		cache, dbInst, &common.NullLogger{})
+	// The duplicate is permanent, retrying would not help.
+	cc.SetGovernanceRetryPolicy(ExponentialBackoffPolicy{
+		InitialDelay: 10 * time.Millisecond,
+		MaxRetries:   3,
+	})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	s.Require().Equal(ErrDuplicateNodeID,
+		cc.registerDKG(context.Background(), round, reset, k))
+	s.Require().Nil(cc.dkg)
+	s.Require().Empty(gov.DKGMasterPublicKeys(round))
+}
+
+func (s *ConfigurationChainTestSuite) TestRegisterDKGTooFarAhead() {
+	k := 2
+	n := 4
+	reset := uint64(0)
+	s.setupNodes(n)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true),
+		ConfigRoundShift)
+	s.Require().NoError(err)
+	farRound := ConfigRoundShift + 2
+	gov.CatchUpWithRound(farRound)
+	for r := DKGDelayRound + 1; r <= farRound; r++ {
+		gov.ProposeCRS(r, common.NewRandomHash().Bytes())
+	}
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc := newConfigurationChain(nID, newTestCCReceiver(nID, recv), gov,
+		utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	// The config of farRound is ready, but it's too far ahead of round 0.
+	s.Require().Equal(ErrDKGRoundTooFarAhead,
+		cc.registerDKG(context.Background(), farRound, reset, k))
+	s.Require().Nil(cc.dkg)
+	// It's allowed once the current round is closer.
+	cc.notifyRound(1)
+	s.Require().NoError(
+		cc.registerDKG(context.Background(), farRound, reset, k))
+	s.Require().NotNil(cc.dkg)
+	s.Require().Equal(farRound, cc.dkg.round)
+	// Notifying an older round doesn't move the current round back.
+	cc.notifyRound(0)
+	s.Require().NoError(
+		cc.registerDKG(context.Background(), farRound, reset+1, k))
+	s.Require().Equal(reset+1, cc.dkg.reset)
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGComplaintDelayAdd() {
+	k := 4
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	lambdaDKG := 1000 * time.Millisecond
+	minBlockInterval := 100 * time.Millisecond
+	s.setupNodes(n)
+
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	recvs := make(map[types.NodeID]*testCCReceiver)
+	for _, nID := range s.nIDs {
+		state := test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+		gov, err := test.NewGovernance(state, ConfigRoundShift)
+		s.Require().NoError(err)
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeLambdaDKG, lambdaDKG))
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeMinBlockInterval, minBlockInterval))
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		recvs[nID] = newTestCCReceiver(nID, recv)
+		cfgChains[nID] = newConfigurationChain(nID, recvs[nID], gov, cache,
+			dbInst, &common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+
+	for _, gov := range recv.govs {
+		s.Require().Len(gov.DKGMasterPublicKeys(round), n)
+	}
+
+	errs := make(chan error, n)
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+	for _, cc := range cfgChains {
+		evt := newTestEvent()
+		go func(cc *configurationChain) {
+			defer wg.Done()
+			errs <- cc.runDKG(round, reset, evt.event, 0, 0)
+		}(cc)
+		evt.run(minBlockInterval)
+		defer evt.stop()
+	}
+	complaints := -1
+	go func() {
+		// Node 0 proposes NackComplaint to all others at 3λ but they should
+		// be ignored because NackComplaint should be proposed before 2λ.
+		time.Sleep(lambdaDKG * 4)
+		for _, gov := range recv.govs {
+			if complaints == -1 {
+				complaints = len(gov.DKGComplaints(round))
+			}
+			s.Require().Len(gov.DKGComplaints(round), complaints)
+		}
+		nID := s.nIDs[0]
+		for _, targetNode := range s.nIDs {
+			if targetNode == nID {
+				continue
+			}
+			recvs[nID].ProposeDKGComplaint(&typesDKG.Complaint{
+				Round: round,
+				PrivateShare: typesDKG.PrivateShare{
+					ProposerID: targetNode,
+					Round:      round,
+				},
+			})
+		}
+	}()
+	wg.Wait()
+	complaints += len(s.nIDs) - 1
+	for _, gov := range recv.govs {
+		s.Require().Len(gov.DKGComplaints(round), complaints)
+	}
+	for range cfgChains {
+		s.Require().NoError(<-errs)
+	}
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round]; !exist {
+			s.FailNow("Should be qualified")
+		}
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
+			s.FailNow("Should be qualified")
+		}
+	}
+	// The late complaints from node 0 disqualify no one on every node.
+	s.Require().Empty(s.assertConsistentDisqualifications(cfgChains, round))
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGComplaintWindow() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	lambdaDKG := 500 * time.Millisecond
+	minBlockInterval := 100 * time.Millisecond
+	s.setupNodes(n)
+
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	recvs := make(map[types.NodeID]*testCCReceiver)
+	for _, nID := range s.nIDs {
+		state := test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+		gov, err := test.NewGovernance(state, ConfigRoundShift)
+		s.Require().NoError(err)
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeLambdaDKG, lambdaDKG))
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeMinBlockInterval, minBlockInterval))
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeDKGComplaintWindow, 4*lambdaDKG))
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		recvs[nID] = newTestCCReceiver(nID, recv)
+		cfgChains[nID] = newConfigurationChain(nID, recvs[nID], gov, cache,
+			dbInst, &common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+
+	errs := make(chan error, n)
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+	for _, cc := range cfgChains {
+		evt := newTestEvent()
+		go func(cc *configurationChain) {
+			defer wg.Done()
+			errs <- cc.runDKG(round, reset, evt.event, 0, 0)
+		}(cc)
+		evt.run(minBlockInterval)
+		defer evt.stop()
+	}
+	// Node 0 proposes NackComplaint to all others at 3.5λ, it's outside of
+	// the default 2λ window but inside the widened one.
+	nID := s.nIDs[0]
+	time.Sleep(lambdaDKG * 7 / 2)
+	for _, targetNode := range s.nIDs {
+		if targetNode == nID {
+			continue
+		}
+		recvs[nID].ProposeDKGComplaint(&typesDKG.Complaint{
+			Round: round,
+			PrivateShare: typesDKG.PrivateShare{
+				ProposerID: targetNode,
+				Round:      round,
+			},
+		})
+	}
+	wg.Wait()
+	for range cfgChains {
+		s.Require().NoError(<-errs)
+	}
+	for _, cc := range cfgChains {
+		accepted := 0
+		for _, complaint := range cc.complaints {
+			if complaint.ProposerID == nID {
+				accepted++
+			}
+		}
+		s.Require().Equal(n-1, accepted)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGPhaseDurations() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	lambdaDKG := 500 * time.Millisecond
+	minBlockInterval := 100 * time.Millisecond
+	s.setupNodes(n)
+	// The offsets of each phase.
+	cfg := &types.Config{
+		LambdaDKG:        lambdaDKG,
+		MinBlockInterval: minBlockInterval,
+	}
+	s.Require().Equal([]uint64{0, 5, 10, 15, 20, 25, 30},
+		dkgPhaseOffsets(cfg, int(DKGPhaseRecoverSigner)+1))
+	cfg.DKGMPKPhase = 2 * lambdaDKG
+	cfg.DKGSharePhase = 3 * lambdaDKG
+	cfg.DKGFinalizePhase = 2 * lambdaDKG
+	s.Require().Equal([]uint64{0, 10, 25, 30, 35, 40, 50},
+		dkgPhaseOffsets(cfg, int(DKGPhaseRecoverSigner)+1))
+	cfg.DKGComplaintWindow = 5 * lambdaDKG
+	s.Require().Equal([]uint64{0, 10, 25, 35, 40, 45, 55},
+		dkgPhaseOffsets(cfg, int(DKGPhaseRecoverSigner)+1))
+
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	recvs := make(map[types.NodeID]*testCCReceiver)
+	for _, nID := range s.nIDs {
+		state := test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+		gov, err := test.NewGovernance(state, ConfigRoundShift)
+		s.Require().NoError(err)
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeLambdaDKG, lambdaDKG))
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeMinBlockInterval, minBlockInterval))
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeDKGSharePhase, 3*lambdaDKG))
+		s.Require().NoError(state.RequestChange(
+			test.StateChangeDKGFinalizePhase, 2*lambdaDKG))
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		recvs[nID] = newTestCCReceiver(nID, recv)
+		cfgChains[nID] = newConfigurationChain(nID, recvs[nID], gov, cache,
+			dbInst, &common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+
+	errs := make(chan error, n)
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+	for _, cc := range cfgChains {
+		evt := newTestEvent()
+		go func(cc *configurationChain) {
+			defer wg.Done()
+			errs <- cc.runDKG(round, reset, evt.event, 0, 0)
+		}(cc)
+		evt.run(minBlockInterval)
+		defer evt.stop()
+	}
+	// Node 0 proposes NackComplaint to all others at 3.5λ, after the default
+	// complaint phase but inside the one shifted by the longer share phase.
+	nID := s.nIDs[0]
+	time.Sleep(lambdaDKG * 7 / 2)
+	for _, targetNode := range s.nIDs {
+		if targetNode == nID {
+			continue
+		}
+		recvs[nID].ProposeDKGComplaint(&typesDKG.Complaint{
+			Round: round,
+			PrivateShare: typesDKG.PrivateShare{
+				ProposerID: targetNode,
+				Round:      round,
+			},
+		})
+	}
+	wg.Wait()
+	for range cfgChains {
+		s.Require().NoError(<-errs)
+	}
+	for _, cc := range cfgChains {
+		accepted := 0
+		for _, complaint := range cc.complaints {
+			if complaint.ProposerID == nID {
+				accepted++
+			}
+		}
+		s.Require().Equal(n-1, accepted)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGDriver() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	recvs := make(map[types.NodeID]*testCCReceiver)
+	for _, nID := range s.nIDs {
+		gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+		), ConfigRoundShift)
+		s.Require().NoError(err)
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		recvs[nID] = newTestCCReceiver(nID, recv)
+		cfgChains[nID] = newConfigurationChain(nID, recvs[nID], gov, cache,
+			dbInst, &common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+
+	var driver *test.DKGDriver
+	errs := make(chan error, n)
+	wg := sync.WaitGroup{}
+	wg.Add(n)
+	for _, cc := range cfgChains {
+		if driver == nil {
+			cfg := utils.GetConfigWithPanic(cc.gov, round, cc.logger)
+			driver = test.NewDKGDriver(0,
+				dkgPhaseOffsets(cfg, len(cc.dkgRunPhases)), 10*time.Second)
+		}
+		cc := cc
+		event, report := driver.NewEvent()
+		cc.SetPhaseObserver(func(_ uint64, phase DKGPhase, err error) {
+			report(int(phase), err)
+		})
+		go func() {
+			defer wg.Done()
+			errs <- cc.runDKG(round, reset, event, 0, 0)
+		}()
+	}
+	s.Require().NoError(driver.StepTo(int(DKGPhaseExchangePrivateShares)))
+	// Wait for private shares received before proposing nack complaints.
+	recv.delivering.Wait()
+	for _, cc := range cfgChains {
+		s.Require().True(func() bool {
+			cc.dkgLock.RLock()
+			defer cc.dkgLock.RUnlock()
+			return len(cc.dkg.prvSharesReceived) >= n
+		}())
+	}
+	s.Require().NoError(driver.Step())
+	// Phases after that are held.
+	for _, cc := range cfgChains {
+		phase, _, ok := cc.PhaseDeadline(round)
+		s.Require().True(ok)
+		s.Require().Equal(DKGPhaseProposeAntiNackComplaints, phase)
+		s.Require().Empty(cc.gov.DKGComplaints(round))
+	}
+	// Node 0 proposes NackComplaint to all others exactly before anti nack
+	// complaints are proposed.
+	nID := s.nIDs[0]
+	for _, targetNode := range s.nIDs {
+		if targetNode == nID {
+			continue
+		}
+		recvs[nID].ProposeDKGComplaint(&typesDKG.Complaint{
+			Round: round,
+			PrivateShare: typesDKG.PrivateShare{
+				ProposerID: targetNode,
+				Round:      round,
+			},
+		})
+	}
+	s.Require().NoError(driver.Step())
+	// Wait for anti nack complaints received before enforcing complaints.
+	recv.delivering.Wait()
+	for _, cc := range cfgChains {
+		if cc.ID == nID {
+			continue
+		}
+		s.Require().True(func() bool {
+			cc.dkgLock.RLock()
+			defer cc.dkgLock.RUnlock()
+			return len(cc.dkg.antiComplaintReceived[nID]) >= n-1
+		}())
+	}
+	driver.Release()
+	wg.Wait()
+	for range cfgChains {
+		s.Require().NoError(<-errs)
+	}
+	for _, cc := range cfgChains {
+		accepted := 0
+		for _, complaint := range cc.complaints {
+			if complaint.ProposerID == nID {
+				accepted++
+			}
+		}
+		s.Require().Equal(n-1, accepted)
+		// Those nack complaints are resolved by anti nack complaints.
+		npks, _, err := cc.getDKGInfo(round, true)
+		s.Require().NoError(err)
+		s.Require().Len(npks.QualifyIDs, n)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestMultipleTSig() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+
+	hash1 := crypto.Keccak256Hash([]byte("Hash1"))
+	hash2 := crypto.Keccak256Hash([]byte("Hash2"))
+
+	psigs1 := s.preparePartialSignature(hash1, round, cfgChains)
+	psigs2 := s.preparePartialSignature(hash2, round, cfgChains)
+
+	tsigs1 := make([]crypto.Signature, 0, n)
+	tsigs2 := make([]crypto.Signature, 0, n)
+
+	errs := make(chan error, n*2)
+	tsigChan1 := make(chan crypto.Signature, n)
+	tsigChan2 := make(chan crypto.Signature, n)
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
+			continue
+		}
+		go func(cc *configurationChain) {
+			tsig1, err := cc.runTSig(round, hash1, 5*time.Second)
+			// Prevent racing by collecting errors and check in main thread.
+			errs <- err
+			tsigChan1 <- tsig1
+		}(cc)
+		go func(cc *configurationChain) {
+			tsig2, err := cc.runTSig(round, hash2, 5*time.Second)
+			// Prevent racing by collecting errors and check in main thread.
+			errs <- err
 			tsigChan2 <- tsig2
 		}(cc)
-		for _, psig := range psigs1 {
-			err := cc.processPartialSignature(psig)
-			s.Require().NoError(err)
+		for _, psig := range psigs1 {
+			err := cc.processPartialSignature(psig)
+			s.Require().NoError(err)
+		}
+		for _, psig := range psigs2 {
+			err := cc.processPartialSignature(psig)
+			s.Require().NoError(err)
+		}
+	}
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
+			continue
+		}
+		s.Require().NoError(<-errs)
+		tsig1 := <-tsigChan1
+		for _, prevTsig := range tsigs1 {
+			s.Equal(prevTsig, tsig1)
+		}
+		s.Require().NoError(<-errs)
+		tsig2 := <-tsigChan2
+		for _, prevTsig := range tsigs2 {
+			s.Equal(prevTsig, tsig2)
+		}
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGOverlappingRounds() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	crs := common.NewRandomHash()
+	for _, cc := range cfgChains {
+		gov := cc.gov.(*test.Governance)
+		gov.CatchUpWithRound(round + 1)
+		gov.ProposeCRS(round+1, crs[:])
+	}
+	// Serve TSIG of this round while DKG of the next round is running.
+	dkgErrs := s.startDKG(cfgChains, k, round+1, reset)
+	hashes := common.Hashes{}
+	tsigs := []crypto.Signature{}
+	for left := len(cfgChains); left > 0; {
+		select {
+		case err := <-dkgErrs:
+			s.Require().NoError(err)
+			left--
+			continue
+		default:
+		}
+		hash := crypto.Keccak256Hash([]byte(fmt.Sprint("tsig", len(hashes))))
+		hashes = append(hashes, hash)
+		tsigs = append(tsigs, s.runTSig(hash, round, cfgChains))
+	}
+	s.Require().NotEmpty(hashes)
+	nextHash := crypto.Keccak256Hash([]byte("next round"))
+	nextTSig := s.runTSig(nextHash, round+1, cfgChains)
+	// Both rounds are qualified with their own group public keys.
+	for _, cc := range cfgChains {
+		gpk, err := cc.RecomputeGroupPublicKey(round)
+		s.Require().NoError(err)
+		nextGPK, err := cc.RecomputeGroupPublicKey(round + 1)
+		s.Require().NoError(err)
+		s.Require().NotEqual(gpk.Bytes(), nextGPK.Bytes())
+		for i, hash := range hashes {
+			s.Require().True(gpk.VerifySignature(hash, tsigs[i]))
+			s.Require().False(nextGPK.VerifySignature(hash, tsigs[i]))
+		}
+		s.Require().True(nextGPK.VerifySignature(nextHash, nextTSig))
+		s.Require().False(gpk.VerifySignature(nextHash, nextTSig))
+	}
+	s.assertConsistentQualifiedSets(cfgChains, round+1)
+}
+
+func (s *ConfigurationChainTestSuite) TestMemoryEstimate() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	before := make(map[types.NodeID]uint64)
+	cfgChains := s.runDKGWithRand(k, round, reset, nil)
+	for nID, cc := range cfgChains {
+		stats := cc.MemoryEstimate()
+		s.Require().NotZero(stats.Signers)
+		s.Require().NotZero(stats.NodePublicKeys[round])
+		before[nID] = stats.Total()
+	}
+	// Register and run DKG of the next round.
+	crs := common.NewRandomHash()
+	for _, cc := range cfgChains {
+		gov := cc.gov.(*test.Governance)
+		gov.CatchUpWithRound(round + 1)
+		gov.ProposeCRS(round+1, crs[:])
+	}
+	dkgErrs := s.startDKG(cfgChains, k, round+1, reset)
+	for range cfgChains {
+		s.Require().NoError(<-dkgErrs)
+	}
+	// Buffer partial signatures without running TSIG.
+	hash := crypto.Keccak256Hash([]byte("🦄"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	for nID, cc := range cfgChains {
+		for _, psig := range psigs {
+			s.Require().NoError(cc.processPartialSignature(psig))
+		}
+		stats := cc.MemoryEstimate()
+		s.Require().NotZero(stats.PartialSignatures)
+		s.Require().NotZero(stats.NodePublicKeys[round+1])
+		s.Require().True(stats.Total() > before[nID])
+		before[nID] = stats.Total()
+	}
+	for nID, cc := range cfgChains {
+		cc.PurgeDKG(round)
+		stats := cc.MemoryEstimate()
+		s.Require().True(stats.Total() < before[nID])
+		s.Require().NotContains(stats.NodePublicKeys, round)
+		s.Require().Contains(stats.NodePublicKeys, round+1)
+		// Purged results could be recovered.
+		_, _, err := cc.getDKGInfo(round, false)
+		s.Require().NoError(err)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestVerifier() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍇"))
+	tsig := s.runTSig(hash, round, cfgChains)
+	gov := cfgChains[s.nIDs[0]].gov
+	verifier := NewVerifier(gov, utils.NewNodeSetCache(gov))
+	gpk, err := verifier.GroupPublicKey(round)
+	s.Require().NoError(err)
+	for _, cc := range cfgChains {
+		groupPubKey, err := cc.RecomputeGroupPublicKey(round)
+		s.Require().NoError(err)
+		s.Require().Equal(groupPubKey.Bytes(), gpk.GroupPublicKey.Bytes())
+	}
+	ok, err := verifier.VerifyThresholdSignature(round, hash, tsig)
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	ok, err = verifier.VerifyThresholdSignature(
+		round, crypto.Keccak256Hash([]byte("🍈")), tsig)
+	s.Require().NoError(err)
+	s.Require().False(ok)
+	// The DKG of next round is not final.
+	_, err = verifier.VerifyThresholdSignature(round+1, hash, tsig)
+	s.Require().Equal(ErrDKGNotReady, err)
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGSingleNode() {
+	k := 1
+	n := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	begin := time.Now()
+	cfgChains := s.runDKG(k, n, round, reset)
+	cc := cfgChains[s.nIDs[0]]
+	cfg := cc.gov.Configuration(round)
+	// Phases are not scheduled by block heights for a lone node.
+	s.Require().True(time.Since(begin) < cfg.LambdaDKG)
+	s.Require().Empty(cc.gov.DKGComplaints(round))
+	npks, _, err := cc.getDKGInfo(round, false)
+	s.Require().NoError(err)
+	s.Require().Len(npks.QualifyIDs, 1)
+	gov := cc.gov
+	verifier := NewVerifier(gov, utils.NewNodeSetCache(gov))
+	gpk, err := verifier.GroupPublicKey(round)
+	s.Require().NoError(err)
+	groupPubKey, err := cc.RecomputeGroupPublicKey(round)
+	s.Require().NoError(err)
+	s.Require().Equal(groupPubKey.Bytes(), gpk.GroupPublicKey.Bytes())
+	// The threshold signature is signed without waiting for any partial
+	// signature, and verifies as the one recovered by others.
+	hash := crypto.Keccak256Hash([]byte("🍋"))
+	begin = time.Now()
+	tsig, err := cc.runTSig(round, hash, 5*time.Second)
+	s.Require().NoError(err)
+	s.Require().True(time.Since(begin) < time.Second)
+	ok, err := verifier.VerifyThresholdSignature(round, hash, tsig)
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().Len(psigs, 1)
+	recovered, err := dkg.RecoverSignature(
+		[]dkg.PartialSignature{psigs[0].PartialSignature},
+		dkg.IDs{s.dkgIDs[cc.ID]})
+	s.Require().NoError(err)
+	s.Require().Equal(recovered, tsig)
+}
+
+func (s *ConfigurationChainTestSuite) TestExportVerificationBundle() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	cc := cfgChains[s.nIDs[0]]
+	exported, err := cc.ExportVerificationBundle(round)
+	s.Require().NoError(err)
+	groupPubKey, err := cc.RecomputeGroupPublicKey(round)
+	s.Require().NoError(err)
+	s.Require().Equal(groupPubKey.Bytes(), exported.GroupPublicKey.Bytes())
+	s.Require().Equal(k, exported.Threshold)
+	s.Require().Len(exported.PublicKeyShares, len(cc.npks[round].IDMap))
+	// Reconstruct a verifier from the serialized bundle.
+	b, err := json.Marshal(exported)
+	s.Require().NoError(err)
+	bundle := &VerificationBundle{}
+	s.Require().NoError(json.Unmarshal(b, bundle))
+	s.Require().Equal(round, bundle.Round)
+	s.Require().Equal(k, bundle.Threshold)
+	s.Require().Equal(exported.GroupPublicKey.Bytes(),
+		bundle.GroupPublicKey.Bytes())
+	s.Require().Len(bundle.PublicKeyShares, len(exported.PublicKeyShares))
+	hash := crypto.Keccak256Hash([]byte("🍉"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().NotEmpty(psigs)
+	for _, psig := range psigs {
+		s.Require().True(bundle.VerifyPartialSignature(psig))
+	}
+	// A partial signature claimed by another node is rejected.
+	forged := *psigs[0]
+	forged.ProposerID = psigs[1].ProposerID
+	s.Require().False(bundle.VerifyPartialSignature(&forged))
+	tsig := s.runTSig(hash, round, cfgChains)
+	s.Require().True(bundle.VerifyThresholdSignature(hash, tsig))
+	s.Require().False(bundle.VerifyThresholdSignature(
+		crypto.Keccak256Hash([]byte("🍈")), tsig))
+	// The DKG of next round is not final.
+	_, err = cc.ExportVerificationBundle(round + 1)
+	s.Require().Equal(ErrDKGNotReady, err)
+}
+
+func (s *ConfigurationChainTestSuite) TestCancelAll() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	crs := common.NewRandomHash()
+	for _, cc := range cfgChains {
+		gov := cc.gov.(*test.Governance)
+		gov.CatchUpWithRound(round + 1)
+		gov.ProposeCRS(round+1, crs[:])
+	}
+	// Start DKG of next round and TSIGs never getting enough partial
+	// signatures.
+	dkgErrs := s.startDKG(cfgChains, k, round+1, reset)
+	hashes := common.Hashes{}
+	for i := 0; i < 3; i++ {
+		hashes = append(hashes, common.NewRandomHash())
+	}
+	tsigErrs := make(chan error, len(cfgChains)*len(hashes))
+	for _, cc := range cfgChains {
+		for _, hash := range hashes {
+			go func(cc *configurationChain, hash common.Hash) {
+				_, err := cc.runTSig(round, hash, time.Minute)
+				tsigErrs <- err
+			}(cc, hash)
+		}
+	}
+	for _, cc := range cfgChains {
+		for func() bool {
+			cc.dkgLock.RLock()
+			defer cc.dkgLock.RUnlock()
+			cc.tsigReady.L.Lock()
+			defer cc.tsigReady.L.Unlock()
+			return !cc.dkgRunning || len(cc.tsig) < len(hashes)
+		}() {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	for _, cc := range cfgChains {
+		cc.CancelAll()
+	}
+	// Every runDKG and runTSig should return right after CancelAll.
+	for i := 0; i < len(cfgChains); i++ {
+		select {
+		case err := <-dkgErrs:
+			s.Require().Equal(ErrDKGAborted, err)
+		case <-time.After(time.Second):
+			s.FailNow("runDKG should be aborted")
+		}
+	}
+	for i := 0; i < len(cfgChains)*len(hashes); i++ {
+		select {
+		case err := <-tsigErrs:
+			s.Require().Equal(ErrDKGAborted, err)
+		case <-time.After(time.Second):
+			s.FailNow("runTSig should be aborted")
+		}
+	}
+	for _, cc := range cfgChains {
+		cc.dkgLock.RLock()
+		s.Require().Nil(cc.dkg)
+		cc.dkgLock.RUnlock()
+		cc.tsigReady.L.Lock()
+		s.Require().Empty(cc.tsig)
+		cc.tsigReady.L.Unlock()
+	}
+	// The chains are still usable.
+	s.runTSig(hashes[0], round, cfgChains)
+	crs = common.NewRandomHash()
+	for _, cc := range cfgChains {
+		cc.gov.(*test.Governance).ResetDKG(crs[:])
+	}
+	dkgErrs = s.startDKG(cfgChains, k, round+1, reset+1)
+	for range cfgChains {
+		s.Require().NoError(<-dkgErrs)
+	}
+	for _, cc := range cfgChains {
+		_, exist := cc.npks[round+1]
+		s.Require().True(exist)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestCancelAllDuringProgress() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍒🍑"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	cc := cfgChains[s.nIDs[0]]
+	inProgress := make(chan struct{})
+	resume := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cc.runTSigWithProgress(round, hash, time.Minute,
+			func(have, needed int) {
+				if have == 1 {
+					close(inProgress)
+					<-resume
+				}
+			})
+		errs <- err
+	}()
+	s.Require().NoError(cc.processPartialSignature(psigs[0]))
+	<-inProgress
+	// Abort runTSig while it's reporting progress without tsigReady.L held.
+	cancelled := make(chan struct{})
+	go func() {
+		cc.CancelAll()
+		close(cancelled)
+	}()
+	func() {
+		cc.tsigReady.L.Lock()
+		defer cc.tsigReady.L.Unlock()
+		for !cc.tsig[hash].aborted {
+			cc.tsigReady.Wait()
+		}
+	}()
+	close(resume)
+	select {
+	case err := <-errs:
+		s.Require().Equal(ErrDKGAborted, err)
+	case <-time.After(5 * time.Second):
+		s.FailNow("runTSig should be aborted")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		s.FailNow("CancelAll should return")
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestTSigTimeout() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	timeout := 6 * time.Second
+
+	hash := crypto.Keccak256Hash([]byte("🍯🍋"))
+
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+
+	errs := make(chan error, n)
+	qualify := 0
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
+			continue
+		}
+		qualify++
+		go func(cc *configurationChain) {
+			_, err := cc.runTSig(round, hash, 5*time.Second)
+			// Prevent racing by collecting errors and check in main thread.
+			errs <- err
+		}(cc)
+		// Only 1 partial signature is provided.
+		err := cc.processPartialSignature(psigs[0])
+		s.Require().NoError(err)
+	}
+	time.Sleep(timeout)
+	s.Require().Len(errs, qualify)
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
+			continue
 		}
-		for _, psig := range psigs2 {
-			err := cc.processPartialSignature(psig)
+		s.Equal(<-errs, ErrNotEnoughtPartialSignatures)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestTSigPartialResult() {
+	k := 3
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍈🍒"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().True(len(psigs) >= k)
+	qualified := []*configurationChain{}
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; exist {
+			qualified = append(qualified, cc)
+		}
+	}
+	s.Require().True(len(qualified) >= 2)
+	// By default, only ErrNotEnoughtPartialSignatures is returned.
+	cc := qualified[0]
+	for _, psig := range psigs[:k-1] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	_, err := cc.runTSig(round, hash, 500*time.Millisecond)
+	s.Require().Equal(ErrNotEnoughtPartialSignatures, err)
+	// Partial result is returned when enabled.
+	cc = qualified[1]
+	cc.SetTSigPartialResult(true)
+	for _, psig := range psigs[:k-1] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	_, err = cc.runTSig(round, hash, 500*time.Millisecond)
+	result, ok := err.(*TSigPartialResult)
+	s.Require().True(ok)
+	s.Require().Equal(hash, result.Hash)
+	s.Require().Equal(k, result.Threshold)
+	s.Require().Equal(k-1, result.Count)
+	s.Require().Len(result.PartialSignatures, k-1)
+	for _, psig := range psigs[:k-1] {
+		id := cc.npks[round].IDMap[psig.ProposerID]
+		s.Require().Equal(psig.PartialSignature, result.PartialSignatures[id])
+	}
+	// Partial result isn't returned when the tsig succeeds.
+	for _, psig := range psigs[:k] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	_, err = cc.runTSig(round, hash, 5*time.Second)
+	s.Require().NoError(err)
+}
+
+func (s *ConfigurationChainTestSuite) TestPartialSignatureRateLimit() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	limit := 3
+	cfgChains := s.runDKG(k, n, round, reset)
+	qualified := []*configurationChain{}
+	for nID, cc := range cfgChains {
+		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; exist {
+			qualified = append(qualified, cc)
+		}
+	}
+	s.Require().True(len(qualified) >= 3)
+	cc, flooder, other := qualified[0], qualified[1], qualified[2]
+	cc.SetPartialSignatureRateLimit(limit)
+	newPsig := func(proposer *configurationChain) *typesDKG.PartialSignature {
+		psig, err := proposer.preparePartialSignature(
+			round, common.NewRandomHash())
+		s.Require().NoError(err)
+		s.Require().NoError(s.signers[proposer.ID].SignDKGPartialSignature(psig))
+		return psig
+	}
+	// Bursts within the limit are accepted.
+	for i := 0; i < limit; i++ {
+		s.Require().NoError(cc.processPartialSignature(newPsig(flooder)))
+	}
+	s.Require().Equal(ErrPartialSignatureRateLimited,
+		cc.processPartialSignature(newPsig(flooder)))
+	// Other proposers are not affected.
+	s.Require().NoError(cc.processPartialSignature(newPsig(other)))
+	cc.tsigReady.L.Lock()
+	s.Require().Len(cc.pendingPsig, limit+1)
+	cc.tsigReady.L.Unlock()
+	// Partial signatures from nodes not qualified are not buffered.
+	prvKey, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	stranger := newPsig(flooder)
+	s.Require().NoError(utils.NewSigner(prvKey).SignDKGPartialSignature(stranger))
+	s.Require().Equal(ErrNotDKGParticipant,
+		cc.processPartialSignature(stranger))
+	// It's accepted again in the next second, and expired windows are
+	// evicted.
+	time.Sleep(time.Second)
+	s.Require().NoError(cc.processPartialSignature(newPsig(flooder)))
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	s.Require().Len(cc.pendingPsig, limit+2)
+	s.Require().Len(cc.psigRateWindow, 1)
+}
+
+func (s *ConfigurationChainTestSuite) TestPendingPartialSignatureTTL() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	ttl := 500 * time.Millisecond
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍊🍎"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().True(len(psigs) >= k)
+	var cc *configurationChain
+	for nID, chain := range cfgChains {
+		if _, exist := chain.npks[round].QualifyNodeIDs[nID]; exist {
+			cc = chain
+			break
+		}
+	}
+	s.Require().NotNil(cc)
+	cc.SetPendingPartialSignatureTTL(ttl)
+	// Buffer partial signatures not enough to reach threshold.
+	for _, psig := range psigs[:k-1] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	isBuffered := func() bool {
+		cc.tsigReady.L.Lock()
+		defer cc.tsigReady.L.Unlock()
+		_, exist := cc.pendingPsig[hash]
+		return exist
+	}
+	s.Require().True(isBuffered())
+	time.Sleep(2 * ttl)
+	s.Require().False(isBuffered())
+	cc.tsigReady.L.Lock()
+	s.Require().Empty(cc.pendingPsigTimer)
+	cc.tsigReady.L.Unlock()
+	// A subsequent runTSig doesn't see those expired partial signatures.
+	_, err := cc.runTSig(round, hash, ttl)
+	s.Require().Equal(ErrNotEnoughtPartialSignatures, err)
+	// Partial signatures buffered before runTSig are still consumed.
+	for _, psig := range psigs[:k] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	_, err = cc.runTSig(round, hash, 5*time.Second)
+	s.Require().NoError(err)
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	s.Require().Empty(cc.pendingPsig)
+	s.Require().Empty(cc.pendingPsigTimer)
+}
+
+func (s *ConfigurationChainTestSuite) TestProcessPartialSignatures() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🥝🥥"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().True(len(psigs) > k)
+	var cc *configurationChain
+	for nID, chain := range cfgChains {
+		if _, exist := chain.npks[round].QualifyNodeIDs[nID]; exist {
+			cc = chain
+			break
+		}
+	}
+	s.Require().NotNil(cc)
+	// A tampered partial signature.
+	badPsig := *psigs[k]
+	badPsig.PartialSignature = psigs[0].PartialSignature
+	// Errors are reported per item when buffered.
+	errs := cc.ProcessPartialSignatures(
+		[]*typesDKG.PartialSignature{&badPsig, psigs[0]})
+	s.Require().Len(errs, 2)
+	s.Require().Equal(ErrIncorrectPartialSignatureSignature, errs[0])
+	s.Require().NoError(errs[1])
+	cc.tsigReady.L.Lock()
+	delete(cc.pendingPsig, hash)
+	cc.tsigReady.L.Unlock()
+	// Start a tsig and feed it a batch, those after threshold reached are
+	// skipped.
+	tsigErrs := make(chan error, 1)
+	go func() {
+		_, err := cc.runTSig(round, hash, 5*time.Second)
+		tsigErrs <- err
+	}()
+	for func() bool {
+		cc.tsigReady.L.Lock()
+		defer cc.tsigReady.L.Unlock()
+		_, exist := cc.tsig[hash]
+		return !exist
+	}() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	batch := append([]*typesDKG.PartialSignature{}, psigs[:k]...)
+	batch = append(batch, &badPsig)
+	for _, err := range cc.ProcessPartialSignatures(batch) {
+		s.Require().NoError(err)
+	}
+	s.Require().NoError(<-tsigErrs)
+}
+
+func (s *ConfigurationChainTestSuite) TestPublicKeyShares() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍋🍌"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().NotEmpty(psigs)
+	for _, cc := range cfgChains {
+		_, ok := cc.PublicKeyShares(round + 1)
+		s.Require().False(ok)
+		pubKeys, ok := cc.PublicKeyShares(round)
+		s.Require().True(ok)
+		s.Require().Len(pubKeys, len(cc.npks[round].QualifyIDs))
+		for _, psig := range psigs {
+			pubKey, exist := pubKeys[psig.ProposerID]
+			s.Require().True(exist)
+			s.Require().True(pubKey.VerifySignature(
+				hash, crypto.Signature(psig.PartialSignature)))
+		}
+		// Mutating the returned map doesn't affect internal state.
+		for nID := range pubKeys {
+			delete(pubKeys, nID)
+		}
+		pubKeys, ok = cc.PublicKeyShares(round)
+		s.Require().True(ok)
+		s.Require().Len(pubKeys, len(cc.npks[round].QualifyIDs))
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestRecomputeGroupPublicKey() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍓🍋"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	sigs := make([]dkg.PartialSignature, 0, len(psigs))
+	ids := make(dkg.IDs, 0, len(psigs))
+	var cc *configurationChain
+	for _, psig := range psigs {
+		cc = cfgChains[psig.ProposerID]
+		sigs = append(sigs, psig.PartialSignature)
+		ids = append(ids, cc.npks[round].IDMap[psig.ProposerID])
+	}
+	sig, err := dkg.RecoverSignature(sigs, ids)
+	s.Require().NoError(err)
+	for _, cc := range cfgChains {
+		groupPubKey, err := cc.RecomputeGroupPublicKey(round)
+		s.Require().NoError(err)
+		s.Require().True(groupPubKey.VerifySignature(hash, sig))
+	}
+	_, err = cc.RecomputeGroupPublicKey(round + 1)
+	s.Require().Equal(ErrDKGNotReady, err)
+	// The stored DKG private key is corrupted.
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NoError(dbInst.PutDKGPrivateKey(
+		round, reset, *dkg.NewPrivateKey()))
+	corruptedCC := newConfigurationChain(
+		cc.ID, cc.recv, cc.gov, cc.cache, dbInst, cc.logger)
+	_, err = corruptedCC.RecomputeGroupPublicKey(round)
+	s.Require().Equal(ErrGroupPublicKeyMismatch, err)
+	// The public key share of another node is corrupted.
+	for nID := range cc.npks[round].PublicKeys {
+		if nID == cc.ID {
+			continue
+		}
+		pubKey := dkg.NewPrivateKey().PublicKey().(dkg.PublicKey)
+		cc.npks[round].PublicKeys[nID] = &pubKey
+		break
+	}
+	_, err = cc.RecomputeGroupPublicKey(round)
+	s.Require().Equal(ErrGroupPublicKeyMismatch, err)
+}
+
+func (s *ConfigurationChainTestSuite) TestDisqualificationPolicy() {
+	k := 1
+	n := 4
+	round := DKGDelayRound
+	cfgChains := s.runDKG(k, n, round, 0)
+	cc := cfgChains[s.nIDs[0]]
+	equivocator := s.nIDs[1]
+	recompute := func() *typesDKG.NodePublicKeys {
+		func() {
+			cc.dkgResult.Lock()
+			defer cc.dkgResult.Unlock()
+			delete(cc.npks, round)
+		}()
+		npks, _, err := cc.getDKGInfo(round, true)
+		s.Require().NoError(err)
+		return npks
+	}
+	// A single nack complaint doesn't disqualify anyone by default, and
+	// equivocations are ignored.
+	complaints := []*typesDKG.Complaint{{
+		ProposerID:   s.nIDs[2],
+		PrivateShare: typesDKG.PrivateShare{ProposerID: s.nIDs[3]},
+	}}
+	s.Require().Empty(DefaultDisqualificationPolicy{}.Disqualify(
+		round, complaints,
+		[]Equivocation{{ProposerID: equivocator, Round: round}}, 2))
+	// Equivocations detected locally are not provided to the policy, other
+	// nodes might not see them.
+	cc.SetDisqualificationPolicy(testStrictDisqualificationPolicy{})
+	cc.addEquivocation(Equivocation{ProposerID: equivocator, Round: round})
+	npks := recompute()
+	s.Require().Len(npks.QualifyNodeIDs, n)
+	// The strict policy disqualifies the equivocator proposing conflicting
+	// master public keys to governance.
+	_, pubShare := dkg.NewPrivateKeyShares(k)
+	mpk := &typesDKG.MasterPublicKey{
+		Round:           round,
+		DKGID:           typesDKG.NewID(equivocator),
+		PublicKeyShares: *pubShare.Move(),
+	}
+	s.Require().NoError(s.signers[equivocator].SignDKGMasterPublicKey(mpk))
+	cc.gov = &testEquivocatingGovernance{
+		Governance: cc.gov,
+		extraMPKs:  []*typesDKG.MasterPublicKey{mpk},
+	}
+	npks = recompute()
+	s.Require().Len(npks.QualifyNodeIDs, n-1)
+	s.Require().NotContains(npks.QualifyNodeIDs, equivocator)
+	s.Require().NotContains(npks.IDMap, equivocator)
+	s.Require().NotContains(npks.PublicKeys, equivocator)
+	// The private key in DB is recovered from the qualified set of the
+	// default policy.
+	_, err := cc.RecomputeGroupPublicKey(round)
+	s.Require().Equal(ErrGroupPublicKeyMismatch, err)
+}
+
+func (s *ConfigurationChainTestSuite) TestSimulateQualification() {
+	// The threshold recovered from governance is decided by the
+	// configuration, having k the same as it makes both consistent.
+	k := 5
+	n := 7
+	round := DKGDelayRound
+	cfgChains := s.runDKG(k, n, round, 0)
+	cc := cfgChains[s.nIDs[0]]
+	sortedIDs := func(nIDs map[types.NodeID]struct{}) (ret types.NodeIDs) {
+		for nID := range nIDs {
+			ret = append(ret, nID)
+		}
+		ret.Sort()
+		return
+	}
+	npks := cc.npks[round]
+	govComplaints := cc.gov.DKGComplaints(round)
+	qualified, disqualified, err := cc.SimulateQualification(round, nil)
+	s.Require().NoError(err)
+	s.Require().Equal(sortedIDs(npks.QualifyNodeIDs), qualified)
+	s.Require().Empty(disqualified)
+	// The target is complained by k nodes, and the equivocator proposing
+	// conflicting master public keys is disqualified by the strict policy.
+	// The outlier proposing a master public key for another threshold is
+	// excluded as well.
+	target := s.nIDs[6]
+	equivocator := s.nIDs[2]
+	_, pubShare := dkg.NewPrivateKeyShares(k)
+	mpk := &typesDKG.MasterPublicKey{
+		Round:           round,
+		DKGID:           typesDKG.NewID(equivocator),
+		PublicKeyShares: *pubShare.Move(),
+	}
+	s.Require().NoError(s.signers[equivocator].SignDKGMasterPublicKey(mpk))
+	prvKey, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	outlier := types.NewNodeID(prvKey.PublicKey())
+	_, pubShare = dkg.NewPrivateKeyShares(k + 1)
+	outlierMPK := &typesDKG.MasterPublicKey{
+		Round:           round,
+		DKGID:           typesDKG.NewID(outlier),
+		PublicKeyShares: *pubShare.Move(),
+	}
+	s.Require().NoError(
+		utils.NewSigner(prvKey).SignDKGMasterPublicKey(outlierMPK))
+	cc.gov = &testEquivocatingGovernance{
+		Governance: cc.gov,
+		extraMPKs:  []*typesDKG.MasterPublicKey{mpk, outlierMPK},
+	}
+	cc.SetDisqualificationPolicy(testStrictDisqualificationPolicy{})
+	var hypothetical []*typesDKG.Complaint
+	for _, nID := range s.nIDs[:k] {
+		hypothetical = append(hypothetical, &typesDKG.Complaint{
+			ProposerID: nID,
+			Round:      round,
+			PrivateShare: typesDKG.PrivateShare{
+				ProposerID: target,
+				Round:      round,
+			},
+		})
+	}
+	qualified, disqualified, err = cc.SimulateQualification(
+		round, hypothetical)
+	s.Require().NoError(err)
+	expected := types.NodeIDs{target, equivocator, outlier}
+	expected.Sort()
+	s.Require().Equal(expected, disqualified)
+	s.Require().Len(qualified, n-2)
+	// Nothing is changed.
+	s.Require().Equal(npks, cc.npks[round])
+	s.Require().Len(cc.gov.DKGComplaints(round), len(govComplaints))
+	// The real qualification made from the complaints in governance along
+	// with the hypothetical ones is the same.
+	cc.gov = &testComplaintsGovernance{
+		Governance: cc.gov,
+		complaints: append(append([]*typesDKG.Complaint{}, govComplaints...),
+			hypothetical...),
+	}
+	func() {
+		cc.dkgResult.Lock()
+		defer cc.dkgResult.Unlock()
+		delete(cc.npks, round)
+	}()
+	npks, _, err = cc.getDKGInfo(round, true)
+	s.Require().NoError(err)
+	s.Require().Equal(sortedIDs(npks.QualifyNodeIDs), qualified)
+	// Complaining one more node leaves too few qualified.
+	hypothetical = nil
+	for _, nID := range s.nIDs[:k] {
+		hypothetical = append(hypothetical, &typesDKG.Complaint{
+			ProposerID: nID,
+			Round:      round,
+			PrivateShare: typesDKG.PrivateShare{
+				ProposerID: s.nIDs[5],
+				Round:      round,
+			},
+		})
+	}
+	_, _, err = cc.SimulateQualification(round, hypothetical)
+	s.Require().Equal(typesDKG.ErrNotReachThreshold, err)
+}
+
+func (s *ConfigurationChainTestSuite) TestDiffQualification() {
+	k := 4
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	cc := cfgChains[s.nIDs[0]]
+	// Fake the DKG results of the next two rounds, as if the node set changed
+	// by dropping different participants.
+	mpks := cc.gov.DKGMasterPublicKeys(round)
+	s.Require().Len(mpks, n)
+	npksA, err := typesDKG.NewNodePublicKeys(round+1, mpks[1:], nil, k)
+	s.Require().NoError(err)
+	npksB, err := typesDKG.NewNodePublicKeys(round+2, mpks[:n-1], nil, k)
+	s.Require().NoError(err)
+	func() {
+		cc.dkgResult.Lock()
+		defer cc.dkgResult.Unlock()
+		cc.npks[round+1] = npksA
+		cc.npks[round+2] = npksB
+	}()
+	added, removed, err := cc.DiffQualification(round, round+1)
+	s.Require().NoError(err)
+	s.Require().Empty(added)
+	s.Require().Equal(types.NodeIDs{mpks[0].ProposerID}, removed)
+	added, removed, err = cc.DiffQualification(round+1, round+2)
+	s.Require().NoError(err)
+	s.Require().Equal(types.NodeIDs{mpks[0].ProposerID}, added)
+	s.Require().Equal(types.NodeIDs{mpks[n-1].ProposerID}, removed)
+	added, removed, err = cc.DiffQualification(round+2, round+2)
+	s.Require().NoError(err)
+	s.Require().Empty(added)
+	s.Require().Empty(removed)
+	// The DKG of round+3 is not final.
+	_, _, err = cc.DiffQualification(round, round+3)
+	s.Require().Equal(ErrDKGNotReady, err)
+}
+
+func (s *ConfigurationChainTestSuite) TestTSigRemaining() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	cfgChains := s.runDKG(k, n, round, 0)
+	hash := crypto.Keccak256Hash([]byte("🍇🍉"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().True(len(psigs) > k+1)
+	cc := cfgChains[s.nIDs[0]]
+	threshold := cc.npks[round].Threshold
+	_, _, ok := cc.TSigRemaining(round+1, hash)
+	s.Require().False(ok)
+	needed, have, ok := cc.TSigRemaining(round, hash)
+	s.Require().True(ok)
+	s.Require().Equal(threshold, needed)
+	s.Require().Equal(0, have)
+	// Buffered partial signatures are counted once for each proposer.
+	s.Require().NoError(cc.processPartialSignature(psigs[0]))
+	s.Require().NoError(cc.processPartialSignature(psigs[0]))
+	needed, have, ok = cc.TSigRemaining(round, hash)
+	s.Require().True(ok)
+	s.Require().Equal(threshold-1, needed)
+	s.Require().Equal(1, have)
+	cc.tsigReady.L.Lock()
+	s.Require().Len(cc.pendingPsig[hash].psigs, 1)
+	cc.tsigReady.L.Unlock()
+	// Partial signatures of other rounds are not counted.
+	other := *psigs[1]
+	other.Round = round + 1
+	s.Require().NoError(s.signers[other.ProposerID].SignDKGPartialSignature(
+		&other))
+	// The notary set of round+1 is not known yet.
+	s.Require().Equal(utils.ErrNodeSetNotReady,
+		cc.processPartialSignature(&other))
+	_, have, _ = cc.TSigRemaining(round, hash)
+	s.Require().Equal(1, have)
+	// Partial signatures are verified and counted once TSig is running.
+	func() {
+		cc.tsigReady.L.Lock()
+		defer cc.tsigReady.L.Unlock()
+		cc.tsig[hash] = newTSigProtocol(cc.npks[round], hash)
+		cc.purgePendingPsig(hash)
+	}()
+	for i, psig := range psigs {
+		s.Require().NoError(cc.processPartialSignature(psig))
+		s.Require().NoError(cc.processPartialSignature(psig))
+		needed, have, ok = cc.TSigRemaining(round, hash)
+		s.Require().True(ok)
+		s.Require().Equal(i+1, have)
+		if i+1 >= threshold {
+			s.Require().Equal(0, needed)
+		} else {
+			s.Require().Equal(threshold-i-1, needed)
+		}
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestTSigProgress() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	cfgChains := s.runDKG(k, n, round, 0)
+	hash := crypto.Keccak256Hash([]byte("🥝"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	cc := cfgChains[s.nIDs[0]]
+	threshold := cc.npks[round].Threshold
+	s.Require().True(len(psigs) >= threshold)
+	type progress struct{ have, needed int }
+	progresses := []progress{}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cc.runTSigWithProgress(round, hash, 5*time.Second,
+			func(have, needed int) {
+				// It's fine to call back into the configuration chain.
+				cc.TSigRemaining(round, hash)
+				progresses = append(progresses, progress{have, needed})
+			})
+		// Prevent racing by collecting errors and check in main thread.
+		errs <- err
+	}()
+	for func() bool {
+		cc.tsigReady.L.Lock()
+		defer cc.tsigReady.L.Unlock()
+		_, exist := cc.tsig[hash]
+		return !exist
+	}() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, psig := range psigs[:threshold] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+		// Duplicated ones are not reported.
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	s.Require().NoError(<-errs)
+	s.Require().Len(progresses, threshold)
+	for i, p := range progresses {
+		s.Require().Equal(progress{i + 1, threshold}, p)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestNodeSetProvider() {
+	k := 1
+	n := 5
+	round := DKGDelayRound
+	s.setupNodes(n)
+	// The last node is not in the hand-crafted notary set, other nodes would
+	// reject its private shares.
+	outsider := s.nIDs[n-1]
+	notarySet := make(map[types.NodeID]struct{})
+	for _, nID := range s.nIDs[:n-1] {
+		notarySet[nID] = struct{}{}
+	}
+	s.nodeSets = &testNodeSetProvider{
+		notarySets: map[uint64]map[types.NodeID]struct{}{round: notarySet},
+	}
+	cfgChains := s.runDKGWithRand(k, round, 0, nil)
+	for nID, cc := range cfgChains {
+		if nID == outsider {
+			continue
+		}
+		s.Require().Len(cc.npks[round].QualifyNodeIDs, n-1)
+		s.Require().NotContains(cc.npks[round].QualifyNodeIDs, outsider)
+		s.Require().Empty(cc.MissingMasterPublicKeys(round))
+	}
+	s.assertConsistentQualifiedSets(cfgChains, round)
+	// No node set is provided for other rounds.
+	cc := cfgChains[s.nIDs[0]]
+	s.Require().Nil(cc.MissingMasterPublicKeys(round + 1))
+}
+
+func (s *ConfigurationChainTestSuite) TestPersistPartialSignatures() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	cfgChains := s.runDKG(k, n, round, 0)
+	hash := crypto.Keccak256Hash([]byte("🍍🥝"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	cc := cfgChains[s.nIDs[0]]
+	cc.SetPersistPartialSignatures(true)
+	buffered := 2
+	for _, psig := range psigs[:buffered] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	// Restart the node with the same DB.
+	restarted := newConfigurationChain(
+		cc.ID, cc.recv, cc.gov, cc.cache, cc.db, cc.logger)
+	restarted.SetPersistPartialSignatures(true)
+	needed, have, ok := restarted.TSigRemaining(round, hash)
+	s.Require().True(ok)
+	s.Require().Equal(buffered, have)
+	threshold := restarted.npks[round].Threshold
+	s.Require().Equal(threshold-buffered, needed)
+	s.Require().True(len(psigs) >= threshold)
+	// Resume TSig with the recovered partial signatures.
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		for _, psig := range psigs[buffered:threshold] {
+			s.Require().NoError(restarted.processPartialSignature(psig))
+		}
+	}()
+	sig, err := restarted.runTSig(round, hash, 5*time.Second)
+	s.Require().NoError(err)
+	gpk, err := typesDKG.NewGroupPublicKey(round,
+		cc.gov.DKGMasterPublicKeys(round),
+		cc.gov.DKGComplaints(round),
+		threshold)
+	s.Require().NoError(err)
+	s.Require().True(gpk.VerifySignature(hash, sig))
+	// Saved partial signatures are removed once TSig is done.
+	saved, err := cc.db.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Empty(saved)
+}
+
+func (s *ConfigurationChainTestSuite) TestLifetimeStats() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	for _, cc := range cfgChains {
+		stats := cc.LifetimeStats()
+		s.Require().Equal(uint64(1), stats.RoundsRegistered)
+		s.Require().Equal(uint64(1), stats.RoundsQualified)
+		s.Require().Equal(uint64(0), stats.RoundsFailed)
+		s.Require().Equal(uint64(0), stats.Disqualifications)
+		s.Require().True(stats.AverageFinalizeTime > 0)
+	}
+	// An aborted DKG is counted as failed.
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	s.Require().NoError(err)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc := newConfigurationChain(nID,
+		newTestCCReceiver(nID, recv), gov, utils.NewNodeSetCache(gov), dbInst,
+		&common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	s.Require().Equal(DKGLifetimeStats{}, cc.LifetimeStats())
+	cc.registerDKG(context.Background(), round, reset, k)
+	errs := make(chan error, 1)
+	evt := newTestEvent()
+	go func() {
+		errs <- cc.runDKG(round, reset, evt.event, 0, 0)
+	}()
+	evt.run(100 * time.Millisecond)
+	defer evt.stop()
+	for func() bool {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		return !cc.dkgRunning
+	}() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cc.AbortDKG(round)
+	s.Require().Equal(ErrDKGAborted, <-errs)
+	s.Require().Equal(DKGLifetimeStats{
+		RoundsRegistered: 1,
+		RoundsFailed:     1,
+	}, cc.LifetimeStats())
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGTrace() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	// Nothing is traced by default.
+	for _, cc := range s.runDKG(k, n, round, reset) {
+		s.Require().Nil(cc.DKGTrace(round))
+	}
+	s.setupNodes(n)
+	s.traceDKG = true
+	for _, cc := range s.runDKGWithRand(k, round, reset, nil) {
+		trace := cc.DKGTrace(round)
+		s.Require().NotNil(trace)
+		s.Require().Equal(round, trace.Round)
+		s.Require().Equal(reset, trace.Reset)
+		events := []time.Time{
+			trace.FirstMPKSeen,
+			trace.AllMPKsSeen,
+			trace.FirstShareSent,
+			trace.AllSharesProcessed,
+			trace.ComplaintWindowClosed,
+			trace.Finalized,
+		}
+		for i, t := range events {
+			s.Require().False(t.IsZero(), "event %d", i)
+			if i > 0 {
+				s.Require().False(t.Before(events[i-1]), "event %d", i)
+			}
+		}
+		s.Require().Nil(cc.DKGTrace(round + 1))
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDeterministicDKG() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	seed := int64(1234)
+	s.setupNodes(n)
+	run := func(seed int64) (
+		gpk []byte, shares map[types.NodeID][]byte) {
+		rands := make(map[types.NodeID]io.Reader)
+		for i, nID := range s.nIDs {
+			rands[nID] = rand.New(rand.NewSource(seed + int64(i)))
+		}
+		cfgChains := s.runDKGWithRand(k, round, reset, rands)
+		shares = make(map[types.NodeID][]byte)
+		for nID, cc := range cfgChains {
+			_, signer, err := cc.getDKGInfo(round, false)
 			s.Require().NoError(err)
+			shares[nID] = signer.privateKey.Bytes()
+			if gpk == nil {
+				groupPK, err := typesDKG.NewGroupPublicKey(round,
+					cc.gov.DKGMasterPublicKeys(round),
+					cc.gov.DKGComplaints(round), k)
+				s.Require().NoError(err)
+				gpk = groupPK.GroupPublicKey.Bytes()
+			}
 		}
+		return
 	}
-	for nID, cc := range cfgChains {
-		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
-			continue
-		}
-		s.Require().NoError(<-errs)
-		tsig1 := <-tsigChan1
-		for _, prevTsig := range tsigs1 {
-			s.Equal(prevTsig, tsig1)
+	gpk1, shares1 := run(seed)
+	gpk2, shares2 := run(seed)
+	s.Require().Equal(gpk1, gpk2)
+	s.Require().Equal(shares1, shares2)
+	// Different seed should lead to different result.
+	gpk3, shares3 := run(seed + int64(n))
+	s.Require().NotEqual(gpk1, gpk3)
+	s.Require().NotEqual(shares1, shares3)
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGGoldenGroupPublicKey() {
+	// The group public key is the sum of public keys dealt by qualified
+	// participants, it only depends on the seeds when all of them are
+	// honest, no matter what their node IDs are. The reference is made by
+	// dealing from the same seeds without running DKG, changing the output
+	// of DKG makes them differ.
+	k := 3
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	newRands := func() map[types.NodeID]io.Reader {
+		rands := make(map[types.NodeID]io.Reader)
+		for i, nID := range s.nIDs {
+			rands[nID] = rand.New(rand.NewSource(int64(i)))
 		}
-		s.Require().NoError(<-errs)
-		tsig2 := <-tsigChan2
-		for _, prevTsig := range tsigs2 {
-			s.Equal(prevTsig, tsig2)
+		return rands
+	}
+	mpks := make([]*typesDKG.MasterPublicKey, 0, n)
+	for nID, r := range newRands() {
+		_, pubShares, err := dkg.NewPrivateKeySharesWithRand(k, r)
+		s.Require().NoError(err)
+		mpk := &typesDKG.MasterPublicKey{
+			Round:           round,
+			Reset:           reset,
+			DKGID:           typesDKG.NewID(nID),
+			PublicKeyShares: *pubShares.Move(),
 		}
+		s.Require().NoError(s.signers[nID].SignDKGMasterPublicKey(mpk))
+		mpks = append(mpks, mpk)
+	}
+	expected, err := typesDKG.NewGroupPublicKey(round, mpks, nil, k)
+	s.Require().NoError(err)
+	expectedGPK := hex.EncodeToString(expected.GroupPublicKey.Bytes())
+	cfgChains := s.runDKGWithRand(k, round, reset, newRands())
+	for _, cc := range cfgChains {
+		bundle, err := cc.ExportVerificationBundle(round)
+		s.Require().NoError(err)
+		s.Require().Len(bundle.PublicKeyShares, n)
+		s.Require().Equal(expectedGPK,
+			hex.EncodeToString(bundle.GroupPublicKey.Bytes()))
 	}
 }
 
-func (s *ConfigurationChainTestSuite) TestTSigTimeout() {
-	k := 2
+// putDKGProtocolWithRand keeps a DKG protocol dealt from r in db, like the
+// one a node registered before restarting.
+func (s *ConfigurationChainTestSuite) putDKGProtocolWithRand(
+	dbInst db.Database, nID types.NodeID, round, reset uint64, k int,
+	r io.Reader) {
+	prvShare, pubShare, err := dkg.NewPrivateKeySharesWithRand(k, r)
+	s.Require().NoError(err)
+	protocol := newDKGProtocolWithShares(nID, testDroppingCCReceiver{}, round,
+		reset, k, prvShare, pubShare)
+	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(
+		protocol.toDKGProtocolInfo()))
+}
+
+// testMPKCountingCCReceiver counts the master public keys proposed.
+type testMPKCountingCCReceiver struct {
+	testDroppingCCReceiver
+	mpks int
+}
+
+func (r *testMPKCountingCCReceiver) ProposeDKGMasterPublicKey(
+	*typesDKG.MasterPublicKey) {
+	r.mpks++
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGWithPresetMPKs() {
+	k := 4
 	n := 7
 	round := DKGDelayRound
 	reset := uint64(0)
-	cfgChains := s.runDKG(k, n, round, reset)
-	timeout := 6 * time.Second
-
-	hash := crypto.Keccak256Hash([]byte("🍯🍋"))
-
+	seed := int64(9012)
+	s.setupNodes(n)
+	newRands := func() map[types.NodeID]io.Reader {
+		rands := make(map[types.NodeID]io.Reader)
+		for i, nID := range s.nIDs {
+			rands[nID] = rand.New(rand.NewSource(seed + int64(i)))
+		}
+		return rands
+	}
+	// Prepare the master public keys dealt from the same seeds.
+	mpks := make([]*typesDKG.MasterPublicKey, 0, n)
+	for nID, r := range newRands() {
+		_, pubShares, err := dkg.NewPrivateKeySharesWithRand(k, r)
+		s.Require().NoError(err)
+		mpk := &typesDKG.MasterPublicKey{
+			Round:           round,
+			Reset:           reset,
+			DKGID:           typesDKG.NewID(nID),
+			PublicKeyShares: *pubShares.Move(),
+		}
+		s.Require().NoError(s.signers[nID].SignDKGMasterPublicKey(mpk))
+		mpks = append(mpks, mpk)
+	}
+	start := time.Now()
+	s.runDKGWithRand(k, round, reset, newRands())
+	elapsed := time.Since(start)
+	s.presetMPKs = mpks
+	start = time.Now()
+	cfgChains := s.runDKGWithRand(k, round, reset, newRands())
+	elapsedWithMPKs := time.Since(start)
+	s.Require().True(elapsedWithMPKs < elapsed,
+		"%s, %s", elapsedWithMPKs, elapsed)
+	// The group public key is the one dealt by preset master public keys.
+	gpk, err := typesDKG.NewGroupPublicKey(round, mpks, nil, k)
+	s.Require().NoError(err)
+	for _, cc := range cfgChains {
+		bundle, err := cc.ExportVerificationBundle(round)
+		s.Require().NoError(err)
+		s.Require().Equal(gpk.GroupPublicKey.Bytes(),
+			bundle.GroupPublicKey.Bytes())
+	}
+	hash := crypto.Keccak256Hash([]byte("🍍🥭"))
 	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	cc := cfgChains[s.nIDs[0]]
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cc.runTSig(round, hash, 5*time.Second)
+		errs <- err
+	}()
+	for _, psig := range psigs[:k] {
+		s.Require().NoError(cc.processPartialSignature(psig))
+	}
+	s.Require().NoError(<-errs)
+	// Preset master public keys should include the one of this node, and
+	// belong to the registered round.
+	var others []*typesDKG.MasterPublicKey
+	for _, mpk := range mpks {
+		if mpk.ProposerID != cc.ID {
+			others = append(others, mpk)
+		}
+	}
+	s.Require().Equal(ErrSelfMPKNotRegister, cc.registerDKGWithMPKs(
+		context.Background(), round, reset, k, others))
+	s.Require().Equal(ErrIncorrectPresetMasterPublicKey,
+		cc.registerDKGWithMPKs(
+			context.Background(), round+1, reset, k, mpks))
+	// Nothing is registered or proposed when the DKG protocol can't be
+	// recovered, or it doesn't match the preset master public keys.
+	newCC := func(dbInst db.Database) (
+		*configurationChain, *testMPKCountingCCReceiver) {
+		recv := &testMPKCountingCCReceiver{}
+		gov := cfgChains[cc.ID].gov
+		return newConfigurationChain(cc.ID, recv, gov,
+			utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{}), recv
+	}
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	fresh, recv := newCC(dbInst)
+	s.Require().Equal(ErrDKGProtocolNotRecovered, fresh.registerDKGWithMPKs(
+		context.Background(), round, reset, k, mpks))
+	s.Require().Nil(fresh.dkg)
+	s.Require().Zero(recv.mpks)
+	dbInst, err = db.NewMemBackedDB()
+	s.Require().NoError(err)
+	s.putDKGProtocolWithRand(dbInst, cc.ID, round, reset, k,
+		rand.New(rand.NewSource(seed-1)))
+	mismatched, recv := newCC(dbInst)
+	s.Require().Equal(ErrSelfPrvShareMismatch,
+		mismatched.registerDKGWithMPKs(
+			context.Background(), round, reset, k, mpks))
+	s.Require().Nil(mismatched.dkg)
+	s.Require().Zero(recv.mpks)
+}
 
-	errs := make(chan error, n)
-	qualify := 0
-	for nID, cc := range cfgChains {
-		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
-			continue
+func (s *ConfigurationChainTestSuite) TestDKGReplaySnapshot() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	seed := int64(5678)
+	s.setupNodes(n)
+	newRands := func() map[types.NodeID]io.Reader {
+		rands := make(map[types.NodeID]io.Reader)
+		for i, nID := range s.nIDs {
+			rands[nID] = rand.New(rand.NewSource(seed + int64(i)))
 		}
-		qualify++
-		go func(cc *configurationChain) {
-			_, err := cc.runTSig(round, hash, 5*time.Second)
-			// Prevent racing by collecting errors and check in main thread.
-			errs <- err
-		}(cc)
-		// Only 1 partial signature is provided.
-		err := cc.processPartialSignature(psigs[0])
-		s.Require().NoError(err)
+		return rands
 	}
-	time.Sleep(timeout)
-	s.Require().Len(errs, qualify)
-	for nID, cc := range cfgChains {
-		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
-			continue
+	getResult := func(cfgChains map[types.NodeID]*configurationChain) (
+		qualifies map[types.NodeID]dkg.IDs,
+		shares map[types.NodeID][]byte) {
+		qualifies = make(map[types.NodeID]dkg.IDs)
+		shares = make(map[types.NodeID][]byte)
+		for nID, cc := range cfgChains {
+			npks, signer, err := cc.getDKGInfo(round, false)
+			s.Require().NoError(err)
+			qualifies[nID] = npks.QualifyIDs
+			shares[nID] = signer.privateKey.Bytes()
 		}
-		s.Equal(<-errs, ErrNotEnoughtPartialSignatures)
+		return
+	}
+	cfgChains, snapshot := s.runDKGWithSnapshot(
+		k, round, reset, newRands(), nil)
+	s.Require().Len(snapshot.MasterPublicKeys, n)
+	s.Require().Empty(snapshot.MPKReadys)
+	s.Require().Empty(snapshot.Finalizes)
+	qualifies1, shares1 := getResult(cfgChains)
+	// Replay the DKG against the governance reconstructed from snapshot.
+	cfgChains, _ = s.runDKGWithSnapshot(k, round, reset, newRands(), snapshot)
+	qualifies2, shares2 := getResult(cfgChains)
+	s.Require().Len(qualifies2, len(qualifies1))
+	for nID, qualifies := range qualifies1 {
+		s.Require().ElementsMatch(qualifies, qualifies2[nID])
+	}
+	s.Require().Equal(shares1, shares2)
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGReplaySingleNode() {
+	k := 3
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	seed := int64(3456)
+	s.setupNodes(n)
+	rands := make(map[types.NodeID]io.Reader)
+	for i, nID := range s.nIDs {
+		rands[nID] = rand.New(rand.NewSource(seed + int64(i)))
+	}
+	target := s.nIDs[0]
+	s.dkgRecorder = newTestDKGRecorder(target)
+	cfgChains := s.runDKGWithRand(k, round, reset, rands)
+	received := 0
+	for _, inputs := range s.dkgRecorder.phases {
+		s.Require().NotNil(inputs.snapshot)
+		received += len(inputs.prvShares)
 	}
+	s.Require().Equal(n, received)
+	npks1, signer1, err := cfgChains[target].getDKGInfo(round, false)
+	s.Require().NoError(err)
+	// Replay the recorded inputs on the target node only.
+	cc := s.replayDKG(s.dkgRecorder, k, round, reset,
+		rand.New(rand.NewSource(seed)))
+	npks2, signer2, err := cc.getDKGInfo(round, false)
+	s.Require().NoError(err)
+	s.Require().ElementsMatch(npks1.QualifyIDs, npks2.QualifyIDs)
+	s.Require().Equal(npks1.QualifyNodeIDs, npks2.QualifyNodeIDs)
+	s.Require().Equal(
+		signer1.privateKey.Bytes(), signer2.privateKey.Bytes())
 }
 
 func (s *ConfigurationChainTestSuite) TestDKGSignerRecoverFromDB() {
@@ -661,17 +3250,418 @@ func (s *ConfigurationChainTestSuite) TestDKGSignerRecoverFromDB() {
 	}
 }
 
-func (s *ConfigurationChainTestSuite) TestDKGPhasesSnapShot() {
+func (s *ConfigurationChainTestSuite) TestDKGInfoRecoverOnConstruction() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🍑🍐"))
+	for _, cc := range cfgChains {
+		psig1, err := cc.preparePartialSignature(round, hash)
+		s.Require().NoError(err)
+		// Nothing is recovered by default.
+		lazyCC := newConfigurationChain(
+			cc.ID, cc.recv, cc.gov, cc.cache, cc.db, cc.logger)
+		s.Require().Empty(lazyCC.npks)
+		s.Require().Empty(lazyCC.dkgSigner)
+		// DKG results are ready right after construction.
+		recoveredCC := newConfigurationChainWithRecovery(
+			cc.ID, cc.recv, cc.gov, cc.cache, cc.db, cc.logger, true)
+		s.Require().Len(recoveredCC.npks, 1)
+		s.Require().Len(recoveredCC.dkgSigner, 1)
+		npks := recoveredCC.npks[round]
+		s.Require().NotNil(npks)
+		s.Require().NotNil(recoveredCC.dkgSigner[round])
+		s.Require().ElementsMatch(cc.npks[round].QualifyIDs, npks.QualifyIDs)
+		psig2, err := recoveredCC.preparePartialSignature(round, hash)
+		s.Require().NoError(err)
+		s.Require().Equal(psig1.PartialSignature, psig2.PartialSignature)
+		s.Require().True(npks.PublicKeys[cc.ID].VerifySignature(
+			hash, crypto.Signature(psig2.PartialSignature)))
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGMaterial() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	for _, password := range []string{"", "🔑"} {
+		for _, cc := range cfgChains {
+			prvKey, err := cc.db.GetDKGPrivateKey(round, reset)
+			s.Require().NoError(err)
+			blob, err := cc.ExportDKGMaterial(password)
+			s.Require().NoError(err)
+			// Restore the exported material into an empty DB.
+			dbInst, err := db.NewMemBackedDB()
+			s.Require().NoError(err)
+			restoredCC := newConfigurationChain(
+				cc.ID, cc.recv, cc.gov, cc.cache, dbInst, cc.logger)
+			s.Require().NoError(restoredCC.ImportDKGMaterial(blob, password))
+			restored, err := dbInst.GetDKGPrivateKey(round, reset)
+			s.Require().NoError(err)
+			s.Require().Equal(prvKey.Bytes(), restored.Bytes())
+			// Importing again is fine.
+			s.Require().NoError(restoredCC.ImportDKGMaterial(blob, password))
+		}
+	}
+	// Unable to import encrypted material with wrong password.
+	for _, cc := range cfgChains {
+		blob, err := cc.ExportDKGMaterial("correct")
+		s.Require().NoError(err)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		restoredCC := newConfigurationChain(
+			cc.ID, cc.recv, cc.gov, cc.cache, dbInst, cc.logger)
+		for _, password := range []string{"wrong", ""} {
+			s.Require().Equal(ErrDKGMaterialDecryption,
+				restoredCC.ImportDKGMaterial(blob, password))
+		}
+		_, err = dbInst.GetDKGPrivateKey(round, reset)
+		s.Require().Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+		break
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGPhasesSnapShot() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	cfgChains := s.runDKG(k, n, round, 0)
+
+	for _, cfgChain := range cfgChains {
+		info, err := cfgChain.db.GetDKGProtocol()
+		s.Require().NoError(err)
+		s.Require().Equal(uint64(7), info.Step)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestPhaseDeadline() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	lambdaDKG := 1000 * time.Millisecond
+	minBlockInterval := 100 * time.Millisecond
+	s.setupNodes(n)
+	state := test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+	gov, err := test.NewGovernance(state, ConfigRoundShift)
+	s.Require().NoError(err)
+	s.Require().NoError(state.RequestChange(
+		test.StateChangeLambdaDKG, lambdaDKG))
+	s.Require().NoError(state.RequestChange(
+		test.StateChangeMinBlockInterval, minBlockInterval))
+	cache := utils.NewNodeSetCache(gov)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc := newConfigurationChain(nID,
+		newTestCCReceiver(nID, recv), gov, cache, dbInst,
+		&common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	_, _, ok := cc.PhaseDeadline(round)
+	s.Require().False(ok)
+	cc.registerDKG(context.Background(), round, reset, k)
+	// Registered but not running yet.
+	_, _, ok = cc.PhaseDeadline(round)
+	s.Require().False(ok)
+	// Start DKG in the middle of the second phase, the heights are not
+	// advanced so DKG would stay in that phase.
+	evt := newTestEvent()
+	errs := make(chan error, 1)
+	before := time.Now()
+	go func() {
+		errs <- cc.runDKG(round, reset, evt.event, 0, 15)
+	}()
+	for func() bool {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		return !cc.dkgRunning
+	}() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	after := time.Now()
+	phase, deadline, ok := cc.PhaseDeadline(round)
+	s.Require().True(ok)
+	s.Require().Equal(DKGPhaseExchangePrivateShares, phase)
+	// Each phase lasts for 10 blocks, the next phase begins at height 20.
+	expected := 5 * minBlockInterval
+	s.Require().False(deadline.Before(before.Add(expected)))
+	s.Require().False(deadline.After(after.Add(expected)))
+	_, _, ok = cc.PhaseDeadline(round + 1)
+	s.Require().False(ok)
+	s.Require().True(cc.abortDKG(context.Background(), round, reset))
+	s.Require().Equal(ErrDKGAborted, <-errs)
+	_, _, ok = cc.PhaseDeadline(round)
+	s.Require().False(ok)
+}
+
+func (s *ConfigurationChainTestSuite) TestDKGStallObserver() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	s.Require().NoError(err)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc := newConfigurationChain(nID,
+		newTestCCReceiver(nID, recv), gov, utils.NewNodeSetCache(gov), dbInst,
+		&common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	stallTimeout := 200 * time.Millisecond
+	cc.SetDKGStallTimeout(stallTimeout)
+	type stall struct {
+		round      uint64
+		phase      DKGPhase
+		stalledFor time.Duration
+	}
+	stalls := make(chan stall, 10)
+	cc.SetStallObserver(
+		func(round uint64, phase DKGPhase, stalledFor time.Duration) {
+			stalls <- stall{round, phase, stalledFor}
+		})
+	// Other nodes in notary set never send anything, this node would be
+	// stuck waiting for MPK readys.
+	cc.registerDKG(context.Background(), round, reset, k)
+	errs := make(chan error, 1)
+	evt := newTestEvent()
+	go func() {
+		errs <- cc.runDKG(round, reset, evt.event, 0, 0)
+	}()
+	evt.run(100 * time.Millisecond)
+	defer evt.stop()
+	select {
+	case st := <-stalls:
+		s.Require().Equal(round, st.round)
+		s.Require().Equal(DKGPhaseWaitMPKReady, st.phase)
+		s.Require().True(st.stalledFor >= stallTimeout)
+	case <-time.After(5 * time.Second):
+		s.FailNow("stall observer is not invoked")
+	}
+	// The same stalled phase is reported only once.
+	select {
+	case <-stalls:
+		s.FailNow("stalled phase is reported twice")
+	case <-time.After(2 * stallTimeout):
+	}
+	// The watchdog stops with the aborted DKG.
+	cc.AbortDKG(round)
+	s.Require().Equal(ErrDKGAborted, <-errs)
+	select {
+	case <-stalls:
+		s.FailNow("stall observer is invoked after DKG aborted")
+	case <-time.After(2 * stallTimeout):
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestMissingMasterPublicKeys() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	for _, nID := range s.nIDs {
+		gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+		), ConfigRoundShift)
+		s.Require().NoError(err)
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		cfgChains[nID] = newConfigurationChain(nID,
+			newTestCCReceiver(nID, recv), gov, cache, dbInst,
+			&common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+	cc := cfgChains[s.nIDs[0]]
+	expected := make(types.NodeIDs, len(s.nIDs))
+	copy(expected, s.nIDs)
+	sort.Sort(expected)
+	s.Require().Equal(expected, cc.MissingMasterPublicKeys(round))
+	// One node withholds its master public key.
+	withheld := s.nIDs[1]
+	for nID, cc := range cfgChains {
+		if nID == withheld {
+			continue
+		}
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+	s.Require().Equal(
+		types.NodeIDs{withheld}, cc.MissingMasterPublicKeys(round))
+	cfgChains[withheld].registerDKG(context.Background(), round, reset, k)
+	s.Require().Empty(cc.MissingMasterPublicKeys(round))
+}
+
+func (s *ConfigurationChainTestSuite) TestVerifyPrivateShare() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	for _, nID := range s.nIDs {
+		gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+		), ConfigRoundShift)
+		s.Require().NoError(err)
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		cfgChains[nID] = newConfigurationChain(nID,
+			newTestCCReceiver(nID, recv), gov, cache, dbInst,
+			&common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+	cc := cfgChains[s.nIDs[0]]
+	proposerID := s.nIDs[1]
+	badShare := &typesDKG.PrivateShare{
+		ProposerID:   proposerID,
+		ReceiverID:   cc.ID,
+		Round:        round,
+		Reset:        reset,
+		PrivateShare: *dkg.NewPrivateKey(),
+	}
+	s.Require().NoError(s.signers[proposerID].SignDKGPrivateShare(badShare))
+	s.Require().Equal(ErrDKGNotRegistered, cc.VerifyPrivateShare(badShare))
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+	s.Require().Equal(ErrDKGNotReady, cc.VerifyPrivateShare(badShare))
+	// Process master public keys.
+	func() {
+		cc.dkgLock.Lock()
+		defer cc.dkgLock.Unlock()
+		s.Require().NoError(cc.runDKGPhaseTwoAndThree(round, reset))
+	}()
+	// The private share to itself matches its master public key.
+	cc.dkgLock.RLock()
+	share, ok := cc.dkg.masterPrivateShare.Share(cc.dkg.idMap[cc.ID])
+	cc.dkgLock.RUnlock()
+	s.Require().True(ok)
+	goodShare := &typesDKG.PrivateShare{
+		ProposerID:   cc.ID,
+		ReceiverID:   cc.ID,
+		Round:        round,
+		Reset:        reset,
+		PrivateShare: *share,
+	}
+	s.Require().NoError(s.signers[cc.ID].SignDKGPrivateShare(goodShare))
+	s.Require().NoError(cc.VerifyPrivateShare(goodShare))
+	// A private share not matching the master public key of its proposer.
+	s.Require().Equal(ErrIncorrectPrivateShare, cc.VerifyPrivateShare(badShare))
+	s.Require().Empty(cc.gov.DKGComplaints(round))
+	s.Require().NoError(cc.processPrivateShare(badShare))
+	complaints := cc.gov.DKGComplaints(round)
+	s.Require().Len(complaints, 1)
+	s.Require().Equal(cc.ID, complaints[0].ProposerID)
+	s.Require().Equal(proposerID, complaints[0].PrivateShare.ProposerID)
+	// Private shares with incorrect signature are rejected.
+	badShare.Signature = goodShare.Signature
+	s.Require().Equal(ErrIncorrectPrivateShareSignature,
+		cc.VerifyPrivateShare(badShare))
+}
+
+func (s *ConfigurationChainTestSuite) TestAbortDKGFromGovernance() {
 	k := 2
-	n := 7
+	n := 4
 	round := DKGDelayRound
-	cfgChains := s.runDKG(k, n, round, 0)
-
-	for _, cfgChain := range cfgChains {
-		info, err := cfgChain.db.GetDKGProtocol()
-		s.Require().NoError(err)
-		s.Require().Equal(uint64(7), info.Step)
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	// Pending runTSig of the aborted round would fail.
+	var cc *configurationChain
+	for nID, chain := range cfgChains {
+		if _, exist := chain.npks[round].QualifyNodeIDs[nID]; exist {
+			cc = chain
+			break
+		}
+	}
+	s.Require().NotNil(cc)
+	hash := crypto.Keccak256Hash([]byte("🍇🍉"))
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cc.runTSig(round, hash, 10*time.Second)
+		errs <- err
+	}()
+	for func() bool {
+		cc.tsigReady.L.Lock()
+		defer cc.tsigReady.L.Unlock()
+		_, exist := cc.tsig[hash]
+		return !exist
+	}() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Aborting other rounds doesn't affect it.
+	cc.AbortDKG(round + 1)
+	select {
+	case <-errs:
+		s.FailNow("tsig should not be aborted")
+	case <-time.After(100 * time.Millisecond):
+	}
+	cc.AbortDKG(round)
+	select {
+	case err := <-errs:
+		s.Require().Equal(ErrDKGAborted, err)
+	case <-time.After(time.Second):
+		s.FailNow("tsig should be aborted")
+	}
+	cc.tsigReady.L.Lock()
+	s.Require().Empty(cc.tsig)
+	cc.tsigReady.L.Unlock()
+	// Abort a running DKG in the middle of its first phase, it would never be
+	// ready because only one node registers.
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	s.Require().NoError(err)
+	gov.CatchUpWithRound(round + 1)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc = newConfigurationChain(nID,
+		newTestCCReceiver(nID, recv), gov, utils.NewNodeSetCache(gov), dbInst,
+		&common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	cc.registerDKG(context.Background(), round, reset, k)
+	evt := newTestEvent()
+	go func() {
+		errs <- cc.runDKG(round, reset, evt.event, 0, 0)
+	}()
+	evt.run(100 * time.Millisecond)
+	defer evt.stop()
+	for func() bool {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		return !cc.dkgRunning
+	}() {
+		time.Sleep(10 * time.Millisecond)
 	}
+	cc.AbortDKG(round)
+	s.Require().Equal(ErrDKGAborted, <-errs)
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	s.Require().Nil(cc.dkg)
+	s.Require().False(cc.dkgRunning)
+	s.Require().Nil(cc.pendingPrvShare)
+	s.Require().Nil(cc.receivedPrvShare)
 }
 
 func (s *ConfigurationChainTestSuite) TestDKGAbort() {
@@ -776,6 +3766,611 @@ func (s *ConfigurationChainTestSuite) TestDKGAbort() {
 	s.Require().True(aborted)
 }
 
+func (s *ConfigurationChainTestSuite) TestDKGEquivocation() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	for _, nID := range s.nIDs {
+		gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+		), ConfigRoundShift)
+		s.Require().NoError(err)
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		cfgChains[nID] = newConfigurationChain(nID,
+			newTestCCReceiver(nID, recv), gov, cache, dbInst,
+			&common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+	cc := cfgChains[s.nIDs[0]]
+	equivocator := s.nIDs[1]
+	s.Require().Empty(cc.Equivocations(round))
+	// Make the governance return a second, conflicting but correctly signed
+	// master public key from the equivocator.
+	_, pubShare := dkg.NewPrivateKeyShares(k)
+	mpk := &typesDKG.MasterPublicKey{
+		Round:           round,
+		Reset:           reset,
+		DKGID:           typesDKG.NewID(equivocator),
+		PublicKeyShares: *pubShare.Move(),
+	}
+	// An unsigned one before the genuine one is not evidence.
+	_, forgedShare := dkg.NewPrivateKeyShares(k)
+	forged := &typesDKG.MasterPublicKey{
+		ProposerID:      equivocator,
+		Round:           round,
+		Reset:           reset,
+		DKGID:           typesDKG.NewID(equivocator),
+		PublicKeyShares: *forgedShare.Move(),
+	}
+	for _, genuine := range cc.gov.DKGMasterPublicKeys(round) {
+		if genuine.ProposerID == equivocator {
+			s.Require().Empty(masterPublicKeysEquivocations(
+				[]*typesDKG.MasterPublicKey{forged, genuine}))
+		}
+	}
+	s.Require().NoError(s.signers[equivocator].SignDKGMasterPublicKey(mpk))
+	cc.gov = &testEquivocatingGovernance{
+		Governance: cc.gov,
+		extraMPKs:  []*typesDKG.MasterPublicKey{mpk},
+	}
+	func() {
+		cc.dkgLock.Lock()
+		defer cc.dkgLock.Unlock()
+		s.Require().NoError(cc.runDKGPhaseTwoAndThree(round, reset))
+	}()
+	equivocations := cc.Equivocations(round)
+	s.Require().Len(equivocations, 1)
+	s.Require().Equal(equivocator, equivocations[0].ProposerID)
+	s.Require().NotNil(equivocations[0].MasterPublicKeys[0])
+	s.Require().True(equivocations[0].MasterPublicKeys[1].Equal(mpk))
+	s.Require().Nil(equivocations[0].PrivateShares[0])
+	// Two different private shares to the same receiver.
+	prvShares := make([]*typesDKG.PrivateShare, 2)
+	for i := range prvShares {
+		prvShares[i] = &typesDKG.PrivateShare{
+			ReceiverID:   cc.ID,
+			Round:        round,
+			Reset:        reset,
+			PrivateShare: *dkg.NewPrivateKey(),
+		}
+		s.Require().NoError(
+			s.signers[equivocator].SignDKGPrivateShare(prvShares[i]))
+		s.Require().NoError(cc.processPrivateShare(prvShares[i]))
+	}
+	// The equivocation is reported only once.
+	s.Require().NoError(cc.processPrivateShare(prvShares[0]))
+	s.Require().NoError(cc.processPrivateShare(prvShares[1]))
+	equivocations = cc.Equivocations(round)
+	s.Require().Len(equivocations, 2)
+	s.Require().Equal(equivocator, equivocations[1].ProposerID)
+	s.Require().True(equivocations[1].PrivateShares[0].Equal(prvShares[0]))
+	s.Require().True(equivocations[1].PrivateShares[1].Equal(prvShares[1]))
+	s.Require().Empty(cc.Equivocations(round + 1))
+}
+
+func (s *ConfigurationChainTestSuite) TestPauseShareProcessing() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	cfgChains := make(map[types.NodeID]*configurationChain)
+	recv := newTestCCGlobalReceiver(s)
+	for _, nID := range s.nIDs {
+		gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+			s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+		), ConfigRoundShift)
+		s.Require().NoError(err)
+		cache := utils.NewNodeSetCache(gov)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		cfgChains[nID] = newConfigurationChain(nID,
+			newTestCCReceiver(nID, recv), gov, cache, dbInst,
+			&common.NullLogger{})
+		recv.nodes[nID] = cfgChains[nID]
+		recv.govs[nID] = gov
+	}
+	for _, cc := range cfgChains {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+	// Phases are driven manually instead of by block heights, so the test
+	// decides when each phase boundary is passed.
+	runPhaseTwo := func(cc *configurationChain) {
+		cc.dkgLock.Lock()
+		defer cc.dkgLock.Unlock()
+		s.Require().NoError(cc.runDKGPhaseTwoAndThree(round, reset))
+	}
+	cc := cfgChains[s.nIDs[0]]
+	cc.PauseShareProcessing()
+	// Other nodes send their private shares before cc passes the MPK phase.
+	for _, nID := range s.nIDs[1:] {
+		runPhaseTwo(cfgChains[nID])
+	}
+	runPhaseTwo(cc)
+	paused := func() int {
+		cc.pausedPrvShareLock.Lock()
+		defer cc.pausedPrvShareLock.Unlock()
+		return len(cc.pausedPrvShare)
+	}
+	for deadline := time.Now().Add(5 * time.Second); paused() < n; {
+		s.Require().True(time.Now().Before(deadline),
+			"only %d shares are buffered", paused())
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Buffered shares are not processed, neither pending for the MPK phase.
+	func() {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		s.Require().Empty(cc.pendingPrvShare)
+		s.Require().Empty(cc.dkg.prvSharesReceived)
+	}()
+	// Junk shares are rejected before buffered.
+	s.Require().Equal(ErrNotDKGParticipant,
+		cc.processPrivateShare(&typesDKG.PrivateShare{Round: round}))
+	s.Require().Equal(n, paused())
+	// Shares beyond the limit are rejected.
+	cc.SetPausedPrivateShareLimit(n)
+	share := func() *typesDKG.PrivateShare {
+		cc.pausedPrvShareLock.Lock()
+		defer cc.pausedPrvShareLock.Unlock()
+		return test.CloneDKGPrivateShare(cc.pausedPrvShare[0])
+	}()
+	s.Require().Equal(ErrPausedPrivateShareFull,
+		cc.processPrivateShare(share))
+	cc.ResumeShareProcessing()
+	s.Require().Zero(paused())
+	func() {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		s.Require().Len(cc.dkg.prvSharesReceived, n)
+	}()
+	// All shares are processed, no nack complaint is proposed when the nack
+	// phase comes.
+	complaints := len(cc.gov.DKGComplaints(round))
+	func() {
+		cc.dkgLock.Lock()
+		defer cc.dkgLock.Unlock()
+		cc.runDKGPhaseFour()
+	}()
+	s.Require().Len(cc.gov.DKGComplaints(round), complaints)
+}
+
+func (s *ConfigurationChainTestSuite) TestPrivateShareBufferLimit() {
+	n := 4
+	k := 1
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	s.Require().NoError(err)
+	gov.CatchUpWithRound(round + 1)
+	gov.ProposeCRS(round+1, common.NewRandomHash().Bytes())
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	cc := newConfigurationChain(nID, newTestCCReceiver(nID, recv), gov,
+		utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	cc.registerDKG(context.Background(), round, reset, k)
+	cc.PauseShareProcessing()
+	newShare := func(round uint64) *typesDKG.PrivateShare {
+		proposerID := s.nIDs[1]
+		prvShare := &typesDKG.PrivateShare{
+			ProposerID: proposerID,
+			ReceiverID: nID,
+			Round:      round,
+		}
+		s.Require().NoError(
+			s.signers[proposerID].SignDKGPrivateShare(prvShare))
+		return prvShare
+	}
+	// Shares not signed by their proposers don't take the buffer.
+	junk := newShare(round)
+	junk.Reset++
+	s.Require().Equal(ErrIncorrectPrivateShareSignature,
+		cc.processPrivateShare(junk))
+	junk = newShare(round)
+	junk.ProposerID = types.NodeID{Hash: common.NewRandomHash()}
+	s.Require().Equal(ErrNotDKGParticipant, cc.processPrivateShare(junk))
+	// Flood the registered round, shares beyond 2*n are rejected.
+	for i := 0; i < 2*n; i++ {
+		s.Require().NoError(cc.processPrivateShare(newShare(round)))
+	}
+	s.Require().Equal(ErrShareBufferFull,
+		cc.processPrivateShare(newShare(round)))
+	// Shares of the next round are still buffered.
+	s.Require().NoError(cc.processPrivateShare(newShare(round + 1)))
+	func() {
+		cc.pausedPrvShareLock.Lock()
+		defer cc.pausedPrvShareLock.Unlock()
+		s.Require().Len(cc.pausedPrvShare, 2*n+1)
+		s.Require().Equal(2*n, cc.pausedPrvShareCount[round])
+		s.Require().Equal(1, cc.pausedPrvShareCount[round+1])
+	}()
+	// The limit is cleared once resumed.
+	cc.ResumeShareProcessing()
+	cc.SetPrivateShareBufferLimit(1)
+	cc.PauseShareProcessing()
+	s.Require().NoError(cc.processPrivateShare(newShare(round)))
+	s.Require().Equal(ErrShareBufferFull,
+		cc.processPrivateShare(newShare(round)))
+	s.Require().NoError(cc.processPrivateShare(newShare(round + 1)))
+	cc.ResumeShareProcessing()
+}
+
+func (s *ConfigurationChainTestSuite) TestTSigVerifyMode() {
+	k := 4
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("🥝🥥"))
+	psigs := s.preparePartialSignature(hash, round, cfgChains)
+	run := func(
+		cc *configurationChain, mode TSigVerifyMode) *TSigResult {
+		cc.SetTSigVerifyMode(mode)
+		type resultWithErr struct {
+			result *TSigResult
+			err    error
+		}
+		ch := make(chan resultWithErr, 1)
+		go func() {
+			result, err := cc.runTSigResult(round, hash, 5*time.Second)
+			ch <- resultWithErr{result, err}
+		}()
+		for _, psig := range psigs[:k] {
+			s.Require().NoError(cc.processPartialSignature(psig))
+		}
+		r := <-ch
+		s.Require().NoError(r.err)
+		return r.result
+	}
+	eager := run(cfgChains[s.nIDs[0]], TSigVerifyEager)
+	s.Require().True(eager.verified)
+	s.Require().NoError(eager.Verify())
+	lazy := run(cfgChains[s.nIDs[1]], TSigVerifyLazy)
+	s.Require().False(lazy.verified)
+	s.Require().Equal(eager.Signature, lazy.Signature)
+	// A tampered signature is only caught when verified.
+	tampered := *lazy
+	tampered.Hash = common.NewRandomHash()
+	s.Require().Equal(ErrIncorrectThresholdSignature, tampered.Verify())
+	s.Require().NoError(lazy.Verify())
+	s.Require().True(lazy.verified)
+	// The group public key is recovered once per round and reused.
+	cached := func(cc *configurationChain) *cachedGroupPublicKey {
+		cc.dkgResult.RLock()
+		defer cc.dkgResult.RUnlock()
+		return cc.groupPubKeys[round]
+	}
+	cc := cfgChains[s.nIDs[0]]
+	first := cached(cc)
+	s.Require().NotNil(first)
+	hash = crypto.Keccak256Hash([]byte("🍓🍇"))
+	psigs = s.preparePartialSignature(hash, round, cfgChains)
+	s.Require().True(run(cc, TSigVerifyEager).verified)
+	s.Require().True(first == cached(cc))
+	cc.PurgeDKG(round)
+	s.Require().Nil(cached(cc))
+}
+
+func (s *ConfigurationChainTestSuite) TestTSigLatencyHistogram() {
+	k := 4
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	timeout := 5 * time.Second
+	cfgChains := s.runDKG(k, n, round, reset)
+	cc := cfgChains[s.nIDs[0]]
+	s.Require().Empty(cc.TSigLatencyHistogram())
+	cc.SetTSigLatencyLimit(3)
+	for i := 0; i < 4; i++ {
+		hash := common.NewRandomHash()
+		psigs := s.preparePartialSignature(hash, round, cfgChains)
+		// Some partial signatures are buffered before runTSig.
+		s.Require().NoError(cc.processPartialSignature(psigs[0]))
+		errs := make(chan error, 1)
+		go func() {
+			_, err := cc.runTSig(round, hash, timeout)
+			errs <- err
+		}()
+		for _, psig := range psigs[1:k] {
+			s.Require().NoError(cc.processPartialSignature(psig))
+		}
+		s.Require().NoError(<-errs)
+	}
+	latencies := cc.TSigLatencyHistogram()
+	// Only the latest ones are retained.
+	s.Require().Len(latencies, 3)
+	for _, latency := range latencies {
+		s.Require().True(latency >= 0, "latency %s", latency)
+		s.Require().True(latency <= timeout, "latency %s", latency)
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestAntiNackGossip() {
+	var (
+		n      = 31
+		fanout = 3
+	)
+	nodes := make(map[types.NodeID]struct{})
+	gossips := make(map[types.NodeID]*antiNackGossip)
+	for i := 0; i < n; i++ {
+		nID := types.NodeID{Hash: common.NewRandomHash()}
+		nodes[nID] = struct{}{}
+		gossips[nID] = newAntiNackGossip(nID, fanout)
+	}
+	type msg struct {
+		to  types.NodeID
+		prv *typesDKG.PrivateShare
+	}
+	// propagate sends the complaint from the proposer and returns the nodes
+	// reached and the count of messages sent.
+	propagate := func(proposer types.NodeID,
+		prv *typesDKG.PrivateShare) (map[types.NodeID]struct{}, int) {
+		reached := map[types.NodeID]struct{}{proposer: {}}
+		queue := []msg{}
+		for _, nID := range gossips[proposer].peers(prv, nodes) {
+			queue = append(queue, msg{to: nID, prv: prv})
+		}
+		sent := len(queue)
+		for len(queue) > 0 {
+			m := queue[0]
+			queue = queue[1:]
+			reached[m.to] = struct{}{}
+			for _, nID := range gossips[m.to].peers(m.prv, nodes) {
+				s.Require().NotEqual(m.to, nID)
+				queue = append(queue, msg{to: nID, prv: m.prv})
+				sent++
+			}
+		}
+		return reached, sent
+	}
+	var proposer types.NodeID
+	for nID := range nodes {
+		proposer = nID
+		break
+	}
+	prv := &typesDKG.PrivateShare{
+		ProposerID: proposer,
+		ReceiverID: types.NodeID{Hash: common.NewRandomHash()},
+		Round:      1,
+	}
+	reached, sent := propagate(proposer, prv)
+	s.Require().Len(reached, n)
+	s.Require().True(sent <= n*fanout, "sent %d", sent)
+	// Complaints already seen are dropped.
+	for nID := range nodes {
+		s.Require().Empty(gossips[nID].peers(prv, nodes))
+	}
+	// The complaint of another reset is propagated again.
+	prv2 := *prv
+	prv2.Reset = 1
+	reached, sent = propagate(proposer, &prv2)
+	s.Require().Len(reached, n)
+	s.Require().True(sent <= n*fanout, "sent %d", sent)
+	// A conflicting complaint of the same share is propagated once.
+	conflicting := *prv
+	conflicting.PrivateShare = *dkg.NewPrivateKey()
+	reached, sent = propagate(proposer, &conflicting)
+	s.Require().Len(reached, n)
+	s.Require().True(sent <= n*fanout, "sent %d", sent)
+	for nID := range nodes {
+		s.Require().Empty(gossips[nID].peers(&conflicting, nodes))
+	}
+	// Complaints of old rounds are forgotten when newer rounds come.
+	prv3 := *prv
+	prv3.Round = 3
+	s.Require().NotEmpty(gossips[proposer].peers(&prv3, nodes))
+	s.Require().NotEmpty(gossips[proposer].peers(prv, nodes))
+}
+
 func TestConfigurationChain(t *testing.T) {
 	suite.Run(t, new(ConfigurationChainTestSuite))
 }
+
+func BenchmarkProcessPartialSignature(b *testing.B) {
+	benchmarkProcessPartialSignatures(b, 16, false, false)
+}
+
+func BenchmarkProcessPartialSignatures(b *testing.B) {
+	benchmarkProcessPartialSignatures(b, 16, true, false)
+}
+
+func BenchmarkProcessPartialSignaturesLargeN(b *testing.B) {
+	for _, n := range []int{64, 256, 1024} {
+		for _, persist := range []bool{false, true} {
+			b.Run(fmt.Sprintf("n=%d/persist=%v", n, persist),
+				func(b *testing.B) {
+					benchmarkProcessPartialSignatures(b, n, true, persist)
+				})
+		}
+	}
+}
+
+func benchmarkProcessPartialSignatures(
+	b *testing.B, n int, batch bool, persist bool) {
+	round := DKGDelayRound
+	prvKeys, pubKeys, err := test.NewKeys(n)
+	if err != nil {
+		panic(err)
+	}
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	if err != nil {
+		panic(err)
+	}
+	dbInst, err := db.NewMemBackedDB()
+	if err != nil {
+		panic(err)
+	}
+	nID := types.NewNodeID(pubKeys[0])
+	cc := newConfigurationChain(nID, nil, gov, utils.NewNodeSetCache(gov),
+		dbInst, &common.NullLogger{})
+	cc.SetPersistPartialSignatures(persist)
+	hash := crypto.Keccak256Hash([]byte("🍒🍑"))
+	psigs := make([]*typesDKG.PartialSignature, 0, n)
+	for _, prvKey := range prvKeys {
+		dkgPrvKey := dkg.NewPrivateKey()
+		sig, err := dkgPrvKey.Sign(hash)
+		if err != nil {
+			panic(err)
+		}
+		psig := &typesDKG.PartialSignature{
+			ProposerID:       types.NewNodeID(prvKey.PublicKey()),
+			Round:            round,
+			Hash:             hash,
+			PartialSignature: dkg.PartialSignature(sig),
+		}
+		if err = utils.NewSigner(prvKey).SignDKGPartialSignature(
+			psig); err != nil {
+			panic(err)
+		}
+		psigs = append(psigs, psig)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			cc.ProcessPartialSignatures(psigs)
+		} else {
+			for _, psig := range psigs {
+				if err := cc.processPartialSignature(psig); err != nil {
+					panic(err)
+				}
+			}
+		}
+		cc.tsigReady.L.Lock()
+		cc.purgePendingPsig(hash)
+		cc.forgetPartialSignatures(hash)
+		cc.tsigReady.L.Unlock()
+	}
+}
+
+func BenchmarkConcurrentTSigSingleWorker(b *testing.B) {
+	benchmarkConcurrentTSig(b, 1, TSigVerifyEager)
+}
+
+func BenchmarkConcurrentTSig(b *testing.B) {
+	benchmarkConcurrentTSig(b, runtime.GOMAXPROCS(0), TSigVerifyEager)
+}
+
+func BenchmarkConcurrentTSigUnbounded(b *testing.B) {
+	benchmarkConcurrentTSig(b, 0, TSigVerifyEager)
+}
+
+func BenchmarkConcurrentTSigLazyVerify(b *testing.B) {
+	benchmarkConcurrentTSig(b, runtime.GOMAXPROCS(0), TSigVerifyLazy)
+}
+
+// benchmarkConcurrentTSig runs many TSigs concurrently with signatures
+// recovered by at most workers goroutines, 0 means one worker for each TSig.
+func benchmarkConcurrentTSig(b *testing.B, workers int, mode TSigVerifyMode) {
+	n := 7
+	k := 3
+	m := 64
+	round := DKGDelayRound
+	prvKeys, pubKeys, err := test.NewKeys(n)
+	if err != nil {
+		panic(err)
+	}
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	if err != nil {
+		panic(err)
+	}
+	dbInst, err := db.NewMemBackedDB()
+	if err != nil {
+		panic(err)
+	}
+	nID := types.NewNodeID(pubKeys[0])
+	cc := newConfigurationChain(nID, nil, gov, utils.NewNodeSetCache(gov),
+		dbInst, &common.NullLogger{})
+	if workers == 0 {
+		workers = m
+	}
+	cc.SetTSigWorkers(workers)
+	cc.SetTSigVerifyMode(mode)
+	// Prepare DKG results dealt by one node.
+	prvShares, pubShares := dkg.NewPrivateKeyShares(k)
+	npks := &typesDKG.NodePublicKeys{
+		Round:          round,
+		QualifyNodeIDs: make(map[types.NodeID]struct{}),
+		IDMap:          make(map[types.NodeID]dkg.ID),
+		PublicKeys:     make(map[types.NodeID]*dkg.PublicKey),
+		Threshold:      k,
+	}
+	for _, pubKey := range pubKeys {
+		nID := types.NewNodeID(pubKey)
+		id := dkg.NewID(nID.Hash[:])
+		npks.QualifyIDs = append(npks.QualifyIDs, id)
+		npks.QualifyNodeIDs[nID] = struct{}{}
+		npks.IDMap[nID] = id
+		if npks.PublicKeys[nID], err = pubShares.Share(id); err != nil {
+			panic(err)
+		}
+	}
+	prvShares.SetParticipants(npks.QualifyIDs)
+	share, _ := prvShares.Share(npks.IDMap[nID])
+	cc.npks[round] = npks
+	cc.dkgSigner[round] = &dkgShareSecret{privateKey: share}
+	hashes := make([]common.Hash, 0, m)
+	psigs := make([]*typesDKG.PartialSignature, 0, m*k)
+	for i := 0; i < m; i++ {
+		hash := common.NewRandomHash()
+		hashes = append(hashes, hash)
+		for _, prvKey := range prvKeys[:k] {
+			proposerID := types.NewNodeID(prvKey.PublicKey())
+			share, _ := prvShares.Share(npks.IDMap[proposerID])
+			sig, err := share.Sign(hash)
+			if err != nil {
+				panic(err)
+			}
+			psig := &typesDKG.PartialSignature{
+				ProposerID:       proposerID,
+				Round:            round,
+				Hash:             hash,
+				PartialSignature: dkg.PartialSignature(sig),
+			}
+			if err = utils.NewSigner(prvKey).SignDKGPartialSignature(
+				psig); err != nil {
+				panic(err)
+			}
+			psigs = append(psigs, psig)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wg := sync.WaitGroup{}
+		wg.Add(len(hashes))
+		for _, hash := range hashes {
+			go func(hash common.Hash) {
+				defer wg.Done()
+				if _, err := cc.runTSig(round, hash, time.Minute); err != nil {
+					panic(err)
+				}
+			}(hash)
+		}
+		for _, err := range cc.ProcessPartialSignatures(psigs) {
+			if err != nil {
+				panic(err)
+			}
+		}
+		wg.Wait()
+	}
+}
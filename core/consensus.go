@@ -413,6 +413,9 @@ type consensusDKGReceiver struct {
 	cfgModule    *configurationChain
 	network      Network
 	logger       common.Logger
+	// antiNack propagates anti nack complaints with bounded fanout, they are
+	// broadcasted when it's nil.
+	antiNack *antiNackGossip
 }
 
 // ProposeDKGComplaint proposes a DKGComplaint.
@@ -473,10 +476,40 @@ func (recv *consensusDKGReceiver) ProposeDKGAntiNackComplaint(
 			return
 		}
 	}
+	if recv.antiNack != nil {
+		recv.relayDKGAntiNackComplaint(prv)
+		return
+	}
 	recv.logger.Debug("Calling Network.BroadcastDKGPrivateShare", "share", prv)
 	recv.network.BroadcastDKGPrivateShare(prv)
 }
 
+// relayDKGAntiNackComplaint forwards an anti complaint to peers chosen by
+// antiNack, complaints already relayed are dropped.
+func (recv *consensusDKGReceiver) relayDKGAntiNackComplaint(
+	prv *typesDKG.PrivateShare) {
+	if recv.antiNack == nil {
+		return
+	}
+	nodes, err := recv.nodeSetCache.GetNotarySet(prv.Round)
+	if err != nil {
+		recv.logger.Error("Failed to get notary set for anti nack complaint",
+			"round", prv.Round, "error", err)
+		return
+	}
+	for _, nID := range recv.antiNack.peers(prv, nodes) {
+		pubKey, exists := recv.nodeSetCache.GetPublicKey(nID)
+		if !exists {
+			recv.logger.Error("Public key for peer not found",
+				"peer", nID.String()[:6])
+			continue
+		}
+		recv.logger.Debug("Calling Network.SendDKGPrivateShare",
+			"receiver", hex.EncodeToString(pubKey.Bytes()), "share", prv)
+		recv.network.SendDKGPrivateShare(pubKey, prv)
+	}
+}
+
 // ProposeDKGMPKReady propose a DKGMPKReady message.
 func (recv *consensusDKGReceiver) ProposeDKGMPKReady(ready *typesDKG.MPKReady) {
 	if err := recv.signer.SignDKGMPKReady(ready); err != nil {
@@ -521,6 +554,7 @@ type Consensus struct {
 	dkgRunning int32
 	dkgReady   *sync.Cond
 	cfgModule  *configurationChain
+	dkgRecv    *consensusDKGReceiver
 
 	// Interfaces.
 	db       db.Database
@@ -637,6 +671,18 @@ func NewConsensusFromSyncer(
 	return con, nil
 }
 
+// SetDKGAntiNackFanout makes anti nack complaints of DKG forwarded to at most
+// fanout peers by each node, and dropped when already seen, instead of being
+// broadcasted to all nodes. It's not thread-safe and should be called before
+// Run.
+func (con *Consensus) SetDKGAntiNackFanout(fanout int) {
+	if fanout <= 0 {
+		con.dkgRecv.antiNack = nil
+		return
+	}
+	con.dkgRecv.antiNack = newAntiNackGossip(con.ID, fanout)
+}
+
 // newConsensusForRound creates a Consensus instance.
 func newConsensusForRound(
 	initBlock *types.Block,
@@ -703,6 +749,7 @@ func newConsensusForRound(
 		baConfirmedBlock:         make(map[common.Hash]chan<- *types.Block),
 		dkgReady:                 sync.NewCond(&sync.Mutex{}),
 		cfgModule:                cfgModule,
+		dkgRecv:                  recv,
 		bcModule:                 bcModule,
 		dMoment:                  dMoment,
 		nodeSetCache:             nodeSetCache,
@@ -749,6 +796,7 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 			panic("not implemented yet")
 		}
 	}
+	con.cfgModule.notifyRound(initRound)
 	// Measure time elapse for each handler of round events.
 	elapse := func(what string, lastE utils.RoundEventParam) func() {
 		start := time.Now()
@@ -775,6 +823,11 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 			con.tsigVerifierCache.Purge(e.Round + 1)
 		}
 	})
+	// Register round event handler to update the current round of
+	// configuration chain, which limits how far ahead DKG could be registered.
+	con.roundEvent.Register(func(evts []utils.RoundEventParam) {
+		con.cfgModule.notifyRound(evts[len(evts)-1].Round)
+	})
 	// Register round event handler to abort previous running DKG if any.
 	con.roundEvent.Register(func(evts []utils.RoundEventParam) {
 		e := evts[len(evts)-1]
@@ -980,8 +1033,14 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 					"reset", e.Reset)
 				nextConfig := utils.GetConfigWithPanic(con.gov, nextRound,
 					con.logger)
-				con.cfgModule.registerDKG(con.ctx, nextRound, e.Reset,
-					utils.GetDKGThreshold(nextConfig))
+				if err := con.cfgModule.registerDKG(con.ctx, nextRound,
+					e.Reset, utils.GetDKGThreshold(nextConfig)); err != nil {
+					con.logger.Error("Error registering DKG",
+						"round", nextRound,
+						"reset", e.Reset,
+						"error", err)
+					return
+				}
 				con.event.RegisterHeight(e.NextDKGPreparationHeight(),
 					func(h uint64) {
 						func() {
@@ -1352,9 +1411,20 @@ MessageLoop:
 			}
 		case *typesDKG.PrivateShare:
 			if err := con.cfgModule.processPrivateShare(val); err != nil {
+				if err == ErrPausedPrivateShareFull ||
+					err == ErrShareBufferFull {
+					// The buffer is full of valid shares, it's not the fault
+					// of the peer.
+					con.logger.Warn("Private share dropped",
+						"error", err)
+					continue MessageLoop
+				}
 				con.logger.Error("Failed to process private share",
 					"error", err)
 				con.network.ReportBadPeerChan() <- peer
+			} else if val.ReceiverID != con.ID {
+				// It's an anti nack complaint.
+				con.dkgRecv.relayDKGAntiNackComplaint(val)
 			}
 
 		case *typesDKG.PartialSignature:
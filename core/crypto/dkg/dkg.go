@@ -366,6 +366,31 @@ func NewPrivateKeyShares(t int) (*PrivateKeyShares, *PublicKeyShares) {
 	}, pubShare
 }
 
+// NewPrivateKeySharesWithRand creates a DKG private key shares of threshold
+// t, the polynomial is generated from the given entropy source. It's for
+// creating reproducible DKG results, NewPrivateKeyShares should be used for
+// real cases.
+func NewPrivateKeySharesWithRand(t int, rand io.Reader) (
+	*PrivateKeyShares, *PublicKeyShares, error) {
+	msk := make([]bls.SecretKey, t)
+	buf := make([]byte, 32)
+	for i := range msk {
+		if _, err := io.ReadFull(rand, buf); err != nil {
+			return nil, nil, err
+		}
+		if err := msk[i].SetLittleEndian(buf); err != nil {
+			return nil, nil, err
+		}
+	}
+	mpk := bls.GetMasterPublicKey(msk)
+	pubShare := NewEmptyPublicKeyShares()
+	pubShare.masterPublicKey = mpk
+	return &PrivateKeyShares{
+		masterPrivateKey: msk,
+		shareIndex:       make(map[ID]int),
+	}, pubShare, nil
+}
+
 // NewEmptyPrivateKeyShares creates an empty private key shares.
 func NewEmptyPrivateKeyShares() *PrivateKeyShares {
 	return &PrivateKeyShares{
@@ -462,6 +487,11 @@ func (pubs *PublicKeyShares) Move() *PublicKeyShares {
 	return pubs
 }
 
+// Threshold returns the threshold of the DKG these shares are generated for.
+func (pubs *PublicKeyShares) Threshold() int {
+	return len(pubs.masterPublicKey)
+}
+
 // Share returns the share for the ID.
 func (pubs *PublicKeyShares) Share(ID ID) (*PublicKey, error) {
 	cache := pubs.cache.Load().(*publicKeySharesCache)
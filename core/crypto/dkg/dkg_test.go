@@ -196,6 +196,38 @@ func (s *DKGTestSuite) TestVerifyKeyShares() {
 	s.False(pubKey.VerifySignature(hash, recoverSig))
 }
 
+func (s *DKGTestSuite) TestRecoverPublicKey() {
+	ids := []ID{NewID([]byte{1}), NewID([]byte{2}), NewID([]byte{3})}
+	prvShares, pubShares := NewPrivateKeyShares(2)
+	prvShares.SetParticipants(ids)
+	groupPubKey := RecoverGroupPublicKey([]*PublicKeyShares{pubShares})
+	pubKeys := make([]*PublicKey, 0, len(ids))
+	for _, id := range ids {
+		pubKey, err := pubShares.Share(id)
+		s.Require().NoError(err)
+		pubKeys = append(pubKeys, pubKey)
+	}
+	// Any threshold of public key shares recovers the group public key.
+	for _, signers := range [][]int{{0, 1}, {1, 2}, {0, 1, 2}} {
+		shares := make([]*PublicKey, 0, len(signers))
+		signerIDs := make(IDs, 0, len(signers))
+		for _, i := range signers {
+			shares = append(shares, pubKeys[i])
+			signerIDs = append(signerIDs, ids[i])
+		}
+		pubKey, err := RecoverPublicKey(shares, signerIDs)
+		s.Require().NoError(err)
+		s.Require().Equal(groupPubKey.Bytes(), pubKey.Bytes())
+	}
+	// A faulty public key share recovers another public key.
+	invalidPubShare, ok := NewPrivateKey().PublicKey().(PublicKey)
+	s.Require().True(ok)
+	pubKey, err := RecoverPublicKey(
+		[]*PublicKey{&invalidPubShare, pubKeys[1]}, ids[:2])
+	s.Require().NoError(err)
+	s.Require().NotEqual(groupPubKey.Bytes(), pubKey.Bytes())
+}
+
 func (s *DKGTestSuite) TestDKGProtocol() {
 	k := 5
 	members := []member{}
@@ -446,6 +478,12 @@ func (s *DKGTestSuite) TestPublicKeySharesClone() {
 	s.Require().True(pubShares1.Equal(pubShares2))
 }
 
+func (s *DKGTestSuite) TestPublicKeySharesThreshold() {
+	_, pubShares := NewPrivateKeyShares(4)
+	s.Require().Equal(4, pubShares.Threshold())
+	s.Require().Equal(4, pubShares.Clone().Threshold())
+}
+
 func TestDKG(t *testing.T) {
 	suite.Run(t, new(DKGTestSuite))
 }
@@ -56,6 +56,22 @@ func RecoverSignature(sigs []PartialSignature, signerIDs IDs) (
 		Signature: recoverSig.Serialize()}, nil
 }
 
+// RecoverPublicKey recovers the public key from public key shares of signers,
+// e.g. the group public key from public keys of qualified nodes.
+func RecoverPublicKey(pubKeys []*PublicKey, signerIDs IDs) (
+	*PublicKey, error) {
+	blsPubKeys := make([]bls.PublicKey, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		blsPubKeys[i] = pubKey.publicKey
+	}
+	var pub PublicKey
+	if err := pub.publicKey.Recover(
+		blsPubKeys, []bls.ID(signerIDs)); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
 // RecoverGroupPublicKey recovers group public key.
 func RecoverGroupPublicKey(pubShares []*PublicKeyShares) *PublicKey {
 	var pub *PublicKey
@@ -0,0 +1,138 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+)
+
+var (
+	// ErrCompactionChainHeightMismatch means the height of a block in the
+	// compaction chain is not the height of its parent plus one.
+	ErrCompactionChainHeightMismatch = errors.New(
+		"compaction chain height mismatch")
+	// ErrCompactionChainNotGenesis means the compaction chain ends at a block
+	// which is not the genesis block.
+	ErrCompactionChainNotGenesis = errors.New(
+		"compaction chain not ending at genesis")
+	// ErrFinalizationProofDoesNotExist means the block is stored without
+	// finalization proof, i.e. the randomness.
+	ErrFinalizationProofDoesNotExist = errors.New(
+		"finalization proof does not exist")
+)
+
+// CompactionChainReorg records the tip of compaction chain before and after a
+// reorg.
+type CompactionChainReorg struct {
+	FromHash   common.Hash
+	FromHeight uint64
+	ToHash     common.Hash
+	ToHeight   uint64
+}
+
+// checkCompactionChainReorg checks if the tip of compaction chain could be
+// moved to height by a reorg.
+func checkCompactionChainReorg(
+	tipHeight, height uint64, confirmed bool) error {
+	if !confirmed {
+		return ErrCompactionChainReorgNotConfirmed
+	}
+	if height > tipHeight {
+		return ErrInvalidCompactionChainTipHeight
+	}
+	return nil
+}
+
+// ErrInvalidCompactionChain reports the first block at which a compaction
+// chain breaks, walking from the tip towards genesis. Height is the height
+// expected for that block.
+type ErrInvalidCompactionChain struct {
+	Hash   common.Hash
+	Height uint64
+	Reason error
+}
+
+func (e *ErrInvalidCompactionChain) Error() string {
+	return fmt.Sprintf("invalid compaction chain at %s(%d): %s",
+		e.Hash.String()[:6], e.Height, e.Reason)
+}
+
+// VerifyCompactionChain walks from the tip of the compaction chain stored in
+// the database to genesis through the parent hash of each block. It makes sure
+// each block is stored with height one more than its parent, and the walk ends
+// at the genesis block. An empty compaction chain is valid.
+func VerifyCompactionChain(d Reader) error {
+	hash, height := d.GetCompactionChainTipInfo()
+	if (hash == common.Hash{}) {
+		return nil
+	}
+	for {
+		b, err := d.GetBlock(hash)
+		if err != nil {
+			return &ErrInvalidCompactionChain{
+				Hash: hash, Height: height, Reason: err}
+		}
+		if b.Position.Height != height {
+			return &ErrInvalidCompactionChain{
+				Hash:   hash,
+				Height: height,
+				Reason: ErrCompactionChainHeightMismatch,
+			}
+		}
+		if b.IsGenesis() {
+			return nil
+		}
+		if (b.ParentHash == common.Hash{}) || height <= types.GenesisHeight {
+			return &ErrInvalidCompactionChain{
+				Hash:   hash,
+				Height: height,
+				Reason: ErrCompactionChainNotGenesis,
+			}
+		}
+		hash, height = b.ParentHash, height-1
+	}
+}
+
+// GetFinalizedBlock returns the block stored in the database only when its
+// finalization proof, i.e. the randomness, passes verify.
+// ErrFinalizationProofDoesNotExist is returned when the block is not
+// finalized, and the error from verify is returned when the proof is invalid.
+func GetFinalizedBlock(d Reader, hash common.Hash,
+	verify func(b *types.Block, proof crypto.Signature) error) (
+	*types.Block, error) {
+	b, err := d.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Randomness) == 0 {
+		return nil, ErrFinalizationProofDoesNotExist
+	}
+	proof := crypto.Signature{
+		Type:      "bls",
+		Signature: common.CopyBytes(b.Randomness),
+	}
+	if err = verify(&b, proof); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// dkgCipher seals the DKG secret material persisted by AES-GCM, the kind,
+// round and reset of the material are bound as additional data, so it can't
+// be opened as the material of another round or reset.
+type dkgCipher struct {
+	aead cipher.AEAD
+}
+
+func newDKGCipher(key []byte) (*dkgCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &dkgCipher{aead: aead}, nil
+}
+
+// dkgSealed is the persisted form of sealed DKG secret material, the round
+// and reset are kept in plaintext to locate the additional data.
+type dkgSealed struct {
+	Round  uint64
+	Reset  uint64
+	Sealed []byte
+}
+
+func dkgAdditionalData(kind []byte, round, reset uint64) []byte {
+	ad := make([]byte, len(kind)+16)
+	copy(ad, kind)
+	binary.LittleEndian.PutUint64(ad[len(kind):], round)
+	binary.LittleEndian.PutUint64(ad[len(kind)+8:], reset)
+	return ad
+}
+
+func (c *dkgCipher) seal(
+	kind []byte, round, reset uint64, plain []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(&dkgSealed{
+		Round: round,
+		Reset: reset,
+		Sealed: c.aead.Seal(
+			nonce, nonce, plain, dkgAdditionalData(kind, round, reset)),
+	})
+}
+
+func (c *dkgCipher) open(kind []byte, encrypted []byte) (
+	sealed dkgSealed, plain []byte, ok bool) {
+	if err := rlp.DecodeBytes(encrypted, &sealed); err != nil {
+		return
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed.Sealed) < nonceSize {
+		return
+	}
+	plain, err := c.aead.Open(nil, sealed.Sealed[:nonceSize],
+		sealed.Sealed[nonceSize:],
+		dkgAdditionalData(kind, sealed.Round, sealed.Reset))
+	if err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// encodeDKGPrivateKey encodes the DKG private key of round and reset, it's
+// sealed when c is not nil.
+func encodeDKGPrivateKey(
+	c *dkgCipher, round, reset uint64, prv dkg.PrivateKey) ([]byte, error) {
+	marshaled, err := rlp.EncodeToBytes(&dkgPrivateKey{
+		PK:    prv,
+		Reset: reset,
+	})
+	if err != nil || c == nil {
+		return marshaled, err
+	}
+	return c.seal(dkgPrivateKeyKeyPrefix, round, reset, marshaled)
+}
+
+// decodeDKGPrivateKey decodes the DKG private key of round and reset encoded
+// by encodeDKGPrivateKey with the same c.
+func decodeDKGPrivateKey(c *dkgCipher, round, reset uint64, b []byte) (
+	prv dkg.PrivateKey, err error) {
+	if c != nil {
+		sealed, plain, ok := c.open(dkgPrivateKeyKeyPrefix, b)
+		if !ok || sealed.Round != round {
+			err = ErrDKGPrivateKeyDecryption
+			return
+		}
+		if sealed.Reset != reset {
+			err = ErrDKGPrivateKeyDoesNotExist
+			return
+		}
+		b = plain
+	}
+	pk := dkgPrivateKey{}
+	if err = rlp.DecodeBytes(b, &pk); err != nil {
+		return
+	}
+	if pk.Reset != reset {
+		err = ErrDKGPrivateKeyDoesNotExist
+		return
+	}
+	prv = pk.PK
+	return
+}
+
+// encodeDKGProtocolInfo encodes the DKG protocol, it's sealed as a whole when
+// c is not nil, private shares included.
+func encodeDKGProtocolInfo(c *dkgCipher, info *DKGProtocolInfo) (
+	[]byte, error) {
+	marshaled, err := rlp.EncodeToBytes(info)
+	if err != nil || c == nil {
+		return marshaled, err
+	}
+	return c.seal(dkgProtocolInfoKeyPrefix, info.Round, info.Reset, marshaled)
+}
+
+// decodeDKGProtocolInfo decodes the DKG protocol encoded by
+// encodeDKGProtocolInfo with the same c.
+func decodeDKGProtocolInfo(c *dkgCipher, b []byte) (
+	info DKGProtocolInfo, err error) {
+	if c == nil {
+		err = rlp.DecodeBytes(b, &info)
+		return
+	}
+	sealed, plain, ok := c.open(dkgProtocolInfoKeyPrefix, b)
+	if !ok {
+		err = ErrDKGProtocolDecryption
+		return
+	}
+	if err = rlp.DecodeBytes(plain, &info); err != nil {
+		return
+	}
+	if info.Round != sealed.Round || info.Reset != sealed.Reset {
+		err = ErrDKGProtocolDecryption
+	}
+	return
+}
@@ -20,10 +20,12 @@ package db
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 )
 
 var (
@@ -56,6 +58,45 @@ var (
 	// ErrDKGProtocolDoesNotExist raised when the DKG protocol of the
 	// requested round does not exists.
 	ErrDKGProtocolDoesNotExist = errors.New("dkg protocol does not exists")
+	// ErrCRSDoesNotExist raised when the CRS of the requested round does not
+	// exists.
+	ErrCRSDoesNotExist = errors.New("crs does not exists")
+	// ErrCompactionChainBroken raised when the parent of the newly updated
+	// tip of compaction chain is not the current one.
+	ErrCompactionChainBroken = errors.New("compaction chain broken")
+	// ErrDKGPrivateKeyDecryption raised when the stored DKG private key can't
+	// be decrypted by the provided encryption key.
+	ErrDKGPrivateKeyDecryption = errors.New("dkg private key decryption failed")
+	// ErrDKGProtocolDecryption raised when the stored DKG protocol can't be
+	// decrypted by the provided encryption key.
+	ErrDKGProtocolDecryption = errors.New("dkg protocol decryption failed")
+	// ErrUnknownPersistFormat raised when the format or version of a
+	// persisted file is not supported.
+	ErrUnknownPersistFormat = errors.New("unknown persist format")
+	// ErrBlockHashMismatch raised when the hash of a block doesn't match the
+	// one computed from its content.
+	ErrBlockHashMismatch = errors.New("block hash mismatch")
+	// ErrBlockTooLarge raised when the size of payload and witness of a block
+	// exceeds the limit.
+	ErrBlockTooLarge = errors.New("block too large")
+	// ErrTooManyOpenFiles raised when opening a file-backed database while
+	// the limit set by SetMaxOpenFiles is reached.
+	ErrTooManyOpenFiles = errors.New("too many open files")
+	// ErrMultipleGenesisBlocks raised when more than one genesis block is
+	// stored in a database.
+	ErrMultipleGenesisBlocks = errors.New("multiple genesis blocks")
+	// ErrDBReadOnly raised when writing to a database set to be read-only.
+	ErrDBReadOnly = errors.New("db is read-only")
+	// ErrInvalidRoundRange raised when the lower bound of a range of rounds
+	// is greater than the upper bound.
+	ErrInvalidRoundRange = errors.New("invalid round range")
+	// ErrInvalidShardCount raised when the count of shards to construct a
+	// database is out of range.
+	ErrInvalidShardCount = errors.New("invalid shard count")
+	// ErrCompactionChainReorgNotConfirmed raised when reorganizing the tip of
+	// compaction chain without confirmation.
+	ErrCompactionChainReorgNotConfirmed = errors.New(
+		"compaction chain reorg not confirmed")
 )
 
 // Database is the interface for a Database.
@@ -73,15 +114,42 @@ type Reader interface {
 	HasBlock(hash common.Hash) bool
 	GetBlock(hash common.Hash) (types.Block, error)
 	GetAllBlocks() (BlockIterator, error)
+	// GetBlocksByTimeRange returns blocks whose timestamp falls in
+	// [start, end], ordered by timestamp and then hash.
+	GetBlocksByTimeRange(start, end time.Time) (BlockIterator, error)
+	// GetFinalizedBlocks returns blocks with finalization proof, i.e. the
+	// randomness, ordered by height and then hash.
+	GetFinalizedBlocks() (BlockIterator, error)
 
 	// GetCompactionChainTipInfo returns the block hash and finalization height
 	// of the tip block of compaction chain. Empty hash and zero height means
 	// the compaction chain is empty.
 	GetCompactionChainTipInfo() (common.Hash, uint64)
+	// GetCompactionChainTipBlock returns the tip block of compaction chain,
+	// ErrBlockDoesNotExist is returned when the compaction chain is empty or
+	// the tip block is not stored.
+	GetCompactionChainTipBlock() (*types.Block, error)
+	// GetCompactionChainReorgs returns the reorgs made by
+	// ReorgCompactionChainTip, in the order they are made.
+	GetCompactionChainReorgs() ([]CompactionChainReorg, error)
 
 	// DKG Private Key related methods.
 	GetDKGPrivateKey(round, reset uint64) (dkg.PrivateKey, error)
+	// HasDKGPrivateKeyInRange returns if a DKG private key of any reset is
+	// saved for each round in [low, high].
+	HasDKGPrivateKeyInRange(low, high uint64) (map[uint64]bool, error)
 	GetDKGProtocol() (dkgProtocol DKGProtocolInfo, err error)
+
+	// GetCRS returns the CRS of one round.
+	GetCRS(round uint64) (common.Hash, error)
+
+	// GetPartialSignatures returns all partial signatures saved by
+	// PutPartialSignatures and AddPartialSignature.
+	GetPartialSignatures() ([]typesDKG.PartialSignature, error)
+
+	// GetBlockLabel returns the label set by SetBlockLabel for the block,
+	// exists is false when it's not labeled.
+	GetBlockLabel(hash common.Hash) (label string, exists bool, err error)
 }
 
 // Writer defines the interface for writing blocks into DB.
@@ -89,8 +157,23 @@ type Writer interface {
 	UpdateBlock(block types.Block) error
 	PutBlock(block types.Block) error
 	PutCompactionChainTipInfo(common.Hash, uint64) error
+	// ReorgCompactionChainTip moves the tip of compaction chain to a height
+	// not higher than the current one, it's refused unless confirmed is true.
+	ReorgCompactionChainTip(
+		hash common.Hash, height uint64, confirmed bool) error
 	PutDKGPrivateKey(round, reset uint64, pk dkg.PrivateKey) error
 	PutOrUpdateDKGProtocol(dkgProtocol DKGProtocolInfo) error
+	PutCRS(round uint64, crs common.Hash) error
+	// PutPartialSignatures replaces the partial signatures saved for the hash
+	// in one round, they are removed when psigs is empty.
+	PutPartialSignatures(round uint64, hash common.Hash,
+		psigs []typesDKG.PartialSignature) error
+	// AddPartialSignature saves one more partial signature for its hash in
+	// its round, without rewriting the saved ones.
+	AddPartialSignature(psig typesDKG.PartialSignature) error
+	// SetBlockLabel attaches a free-form label to a stored block for tooling,
+	// without modifying the block. The label is removed when it's empty.
+	SetBlockLabel(hash common.Hash, label string) error
 }
 
 // BlockIterator defines an iterator on blocks hold
@@ -98,3 +181,19 @@ type Writer interface {
 type BlockIterator interface {
 	NextBlock() (types.Block, error)
 }
+
+// newRoundPresence makes a map with a false entry for each round in
+// [low, high].
+func newRoundPresence(low, high uint64) (map[uint64]bool, error) {
+	if low > high {
+		return nil, ErrInvalidRoundRange
+	}
+	ret := make(map[uint64]bool)
+	for round := low; ; round++ {
+		ret[round] = false
+		if round == high {
+			break
+		}
+	}
+	return ret, nil
+}
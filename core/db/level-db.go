@@ -20,20 +20,28 @@ package db
 import (
 	"encoding/binary"
 	"io"
+	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 	"github.com/dexon-foundation/dexon/rlp"
 )
 
 var (
 	blockKeyPrefix            = []byte("b-")
 	compactionChainTipInfoKey = []byte("cc-tip")
+	compactionChainReorgsKey  = []byte("cc-reorgs")
 	dkgPrivateKeyKeyPrefix    = []byte("dkg-prvs")
 	dkgProtocolInfoKeyPrefix  = []byte("dkg-protocol-info")
+	crsKeyPrefix              = []byte("crs")
+	psigsKeyPrefix            = []byte("psigs-")
+	labelKeyPrefix            = []byte("label-")
+	namespaceKeyPrefix        = []byte("ns-")
 )
 
 type compactionChainTipInfo struct {
@@ -343,23 +351,72 @@ func (m *NodeIDToDKGID) DecodeRLP(s *rlp.Stream) error {
 
 // LevelDBBackedDB is a leveldb backed DB implementation.
 type LevelDBBackedDB struct {
-	db *leveldb.DB
+	db        *leveldb.DB
+	validator BlockValidator
+	hashCheck bool
+	linkCheck bool
+	dkgCipher *dkgCipher
+	sizeLimit int
+	namespace []byte
+	fileSlot  *openFileSlot
 }
 
 // NewLevelDBBackedDB initialize a leveldb-backed database.
 func NewLevelDBBackedDB(
 	path string) (lvl *LevelDBBackedDB, err error) {
-
+	fileSlot, err := acquireOpenFileSlot()
+	if err != nil {
+		return
+	}
 	dbInst, err := leveldb.OpenFile(path, nil)
 	if err != nil {
+		fileSlot.release()
 		return
 	}
-	lvl = &LevelDBBackedDB{db: dbInst}
+	lvl = &LevelDBBackedDB{db: dbInst, fileSlot: fileSlot}
 	return
 }
 
+// NewEncryptedLevelDBBackedDB initialize a leveldb-backed database, the DKG
+// private keys and DKG protocol, which carries private shares, would be
+// encrypted by AES with the provided key, which should be 16, 24 or 32 bytes
+// long.
+func NewEncryptedLevelDBBackedDB(
+	path string, key []byte) (lvl *LevelDBBackedDB, err error) {
+	c, err := newDKGCipher(key)
+	if err != nil {
+		return
+	}
+	if lvl, err = NewLevelDBBackedDB(path); err != nil {
+		return
+	}
+	lvl.dkgCipher = c
+	return
+}
+
+// Namespaced creates a leveldb-backed database sharing the leveldb of inner,
+// all keys are prefixed by the namespace so it's isolated from inner and
+// other namespaces. DKG secrets are encrypted in the same way as inner.
+// The shared leveldb is closed when any of them is closed.
+func Namespaced(inner *LevelDBBackedDB, prefix string) *LevelDBBackedDB {
+	length := make([]byte, binary.MaxVarintLen64)
+	length = length[:binary.PutUvarint(length, uint64(len(prefix)))]
+	namespace := make([]byte, 0,
+		len(inner.namespace)+len(namespaceKeyPrefix)+len(length)+len(prefix))
+	namespace = append(namespace, inner.namespace...)
+	namespace = append(namespace, namespaceKeyPrefix...)
+	namespace = append(namespace, length...)
+	namespace = append(namespace, prefix...)
+	return &LevelDBBackedDB{
+		db:        inner.db,
+		dkgCipher: inner.dkgCipher,
+		namespace: namespace,
+	}
+}
+
 // Close implement Closer interface, which would release allocated resource.
 func (lvl *LevelDBBackedDB) Close() error {
+	defer lvl.fileSlot.release()
 	return lvl.db.Close()
 }
 
@@ -411,8 +468,41 @@ func (lvl *LevelDBBackedDB) UpdateBlock(block types.Block) (err error) {
 	return
 }
 
+// SetBlockValidator sets the validator to check blocks before PutBlock, it's
+// not thread-safe and should be called before any block is put.
+func (lvl *LevelDBBackedDB) SetBlockValidator(v BlockValidator) {
+	lvl.validator = v
+}
+
+// SetBlockHashCheck enables or disables the check that the hash of a block
+// matches its content before PutBlock, it's not thread-safe and should be
+// called before any block is put.
+func (lvl *LevelDBBackedDB) SetBlockHashCheck(enabled bool) {
+	lvl.hashCheck = enabled
+}
+
+// SetMaxBlockSize sets the limit in bytes of payload and witness data of
+// blocks accepted by PutBlock, zero means unlimited. It's not thread-safe and
+// should be called before any block is put.
+func (lvl *LevelDBBackedDB) SetMaxBlockSize(bytes int) {
+	lvl.sizeLimit = bytes
+}
+
 // PutBlock implements the Writer.PutBlock method.
 func (lvl *LevelDBBackedDB) PutBlock(block types.Block) (err error) {
+	if err = checkBlockSize(&block, lvl.sizeLimit); err != nil {
+		return
+	}
+	if lvl.hashCheck {
+		if err = checkBlockHash(&block); err != nil {
+			return
+		}
+	}
+	if lvl.validator != nil {
+		if err = lvl.validator(&block); err != nil {
+			return
+		}
+	}
 	marshaled, err := rlp.EncodeToBytes(&block)
 	if err != nil {
 		return
@@ -431,11 +521,56 @@ func (lvl *LevelDBBackedDB) PutBlock(block types.Block) (err error) {
 }
 
 // GetAllBlocks implements Reader.GetAllBlocks method, which allows callers
-// to retrieve all blocks in DB.
+// to retrieve all blocks in DB, the order of blocks is not defined.
 func (lvl *LevelDBBackedDB) GetAllBlocks() (BlockIterator, error) {
+	blocks, err := lvl.getAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+	return &blockListIterator{blocks: blocks}, nil
+}
+
+// GetFinalizedBlocks implements Reader.GetFinalizedBlocks method.
+func (lvl *LevelDBBackedDB) GetFinalizedBlocks() (BlockIterator, error) {
+	blocks, err := lvl.getAllBlocks()
+	if err != nil {
+		return nil, err
+	}
+	return newFinalizedBlockIterator(blocks), nil
+}
+
+func (lvl *LevelDBBackedDB) getAllBlocks() ([]types.Block, error) {
+	iter := lvl.db.NewIterator(
+		util.BytesPrefix(lvl.withNamespace(blockKeyPrefix)), nil)
+	defer iter.Release()
+	blocks := []types.Block{}
+	for iter.Next() {
+		b := types.Block{}
+		if err := rlp.DecodeBytes(iter.Value(), &b); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// GetBlocksByTimeRange implements Reader.GetBlocksByTimeRange method.
+func (lvl *LevelDBBackedDB) GetBlocksByTimeRange(
+	start, end time.Time) (BlockIterator, error) {
 	return nil, ErrNotImplemented
 }
 
+// SetCompactionChainLinkCheck enables or disables the check that the parent
+// of the new tip of compaction chain is the current tip, it's only applied
+// when both blocks are stored. It's not thread-safe and should be called
+// before any tip is put.
+func (lvl *LevelDBBackedDB) SetCompactionChainLinkCheck(enabled bool) {
+	lvl.linkCheck = enabled
+}
+
 // PutCompactionChainTipInfo saves tip of compaction chain into the database.
 func (lvl *LevelDBBackedDB) PutCompactionChainTipInfo(
 	blockHash common.Hash, height uint64) error {
@@ -455,12 +590,90 @@ func (lvl *LevelDBBackedDB) PutCompactionChainTipInfo(
 	if info.Height+1 != height {
 		return ErrInvalidCompactionChainTipHeight
 	}
-	return lvl.db.Put(compactionChainTipInfoKey, marshaled, nil)
+	if lvl.linkCheck && info.Height > 0 {
+		if err = lvl.checkCompactionChainLink(blockHash, info.Hash); err != nil {
+			return err
+		}
+	}
+	return lvl.db.Put(lvl.getCompactionChainTipInfoKey(), marshaled, nil)
+}
+
+// ReorgCompactionChainTip moves the tip of compaction chain to a lower or
+// equal height when confirmed, and records the reorg.
+func (lvl *LevelDBBackedDB) ReorgCompactionChainTip(
+	blockHash common.Hash, height uint64, confirmed bool) error {
+	info, err := lvl.internalGetCompactionChainTipInfo()
+	if err != nil {
+		return err
+	}
+	if err = checkCompactionChainReorg(
+		info.Height, height, confirmed); err != nil {
+		return err
+	}
+	reorgs, err := lvl.GetCompactionChainReorgs()
+	if err != nil {
+		return err
+	}
+	reorgs = append(reorgs, CompactionChainReorg{
+		FromHash:   info.Hash,
+		FromHeight: info.Height,
+		ToHash:     blockHash,
+		ToHeight:   height,
+	})
+	marshaledReorgs, err := rlp.EncodeToBytes(&reorgs)
+	if err != nil {
+		return err
+	}
+	marshaled, err := rlp.EncodeToBytes(&compactionChainTipInfo{
+		Hash:   blockHash,
+		Height: height,
+	})
+	if err != nil {
+		return err
+	}
+	// The tip and the reorg record should be updated together.
+	batch := new(leveldb.Batch)
+	batch.Put(lvl.getCompactionChainReorgsKey(), marshaledReorgs)
+	batch.Put(lvl.getCompactionChainTipInfoKey(), marshaled)
+	return lvl.db.Write(batch, nil)
+}
+
+// GetCompactionChainReorgs returns the reorgs of the tip of compaction chain.
+func (lvl *LevelDBBackedDB) GetCompactionChainReorgs() (
+	reorgs []CompactionChainReorg, err error) {
+	reorgs = []CompactionChainReorg{}
+	queried, err := lvl.db.Get(lvl.getCompactionChainReorgsKey(), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			err = nil
+		}
+		return
+	}
+	err = rlp.DecodeBytes(queried, &reorgs)
+	return
+}
+
+func (lvl *LevelDBBackedDB) checkCompactionChainLink(
+	tipHash, prevHash common.Hash) error {
+	tip, err := lvl.GetBlock(tipHash)
+	if err != nil {
+		if err == ErrBlockDoesNotExist {
+			err = nil
+		}
+		return err
+	}
+	if !lvl.HasBlock(prevHash) {
+		return nil
+	}
+	if tip.ParentHash != prevHash {
+		return ErrCompactionChainBroken
+	}
+	return nil
 }
 
 func (lvl *LevelDBBackedDB) internalGetCompactionChainTipInfo() (
 	info compactionChainTipInfo, err error) {
-	queried, err := lvl.db.Get(compactionChainTipInfoKey, nil)
+	queried, err := lvl.db.Get(lvl.getCompactionChainTipInfoKey(), nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
 			err = nil
@@ -483,6 +696,20 @@ func (lvl *LevelDBBackedDB) GetCompactionChainTipInfo() (
 	return
 }
 
+// GetCompactionChainTipBlock returns the tip block of compaction chain.
+func (lvl *LevelDBBackedDB) GetCompactionChainTipBlock() (
+	*types.Block, error) {
+	info, err := lvl.internalGetCompactionChainTipInfo()
+	if err != nil {
+		return nil, err
+	}
+	block, err := lvl.GetBlock(info.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
 // GetDKGPrivateKey get DKG private key of one round.
 func (lvl *LevelDBBackedDB) GetDKGPrivateKey(round, reset uint64) (
 	prv dkg.PrivateKey, err error) {
@@ -493,14 +720,34 @@ func (lvl *LevelDBBackedDB) GetDKGPrivateKey(round, reset uint64) (
 		}
 		return
 	}
-	pk := dkgPrivateKey{}
-	err = rlp.DecodeBytes(queried, &pk)
-	if pk.Reset != reset {
-		err = ErrDKGPrivateKeyDoesNotExist
-		return
+	return decodeDKGPrivateKey(lvl.dkgCipher, round, reset, queried)
+}
+
+// HasDKGPrivateKeyInRange implements Reader.HasDKGPrivateKeyInRange method,
+// all saved DKG private keys are scanned once.
+func (lvl *LevelDBBackedDB) HasDKGPrivateKeyInRange(low, high uint64) (
+	map[uint64]bool, error) {
+	ret, err := newRoundPresence(low, high)
+	if err != nil {
+		return nil, err
+	}
+	prefix := lvl.withNamespace(dkgPrivateKeyKeyPrefix)
+	iter := lvl.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != len(prefix)+8 {
+			continue
+		}
+		round := binary.LittleEndian.Uint64(key[len(prefix):])
+		if round >= low && round <= high {
+			ret[round] = true
+		}
 	}
-	prv = pk.PK
-	return
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
 // PutDKGPrivateKey save DKG private key of one round.
@@ -514,11 +761,7 @@ func (lvl *LevelDBBackedDB) PutDKGPrivateKey(
 	if err != ErrDKGPrivateKeyDoesNotExist {
 		return err
 	}
-	pk := &dkgPrivateKey{
-		PK:    prv,
-		Reset: reset,
-	}
-	marshaled, err := rlp.EncodeToBytes(&pk)
+	marshaled, err := encodeDKGPrivateKey(lvl.dkgCipher, round, reset, prv)
 	if err != nil {
 		return err
 	}
@@ -536,25 +779,153 @@ func (lvl *LevelDBBackedDB) GetDKGProtocol() (
 		}
 		return
 	}
-
-	err = rlp.DecodeBytes(queried, &info)
-	return
+	return decodeDKGProtocolInfo(lvl.dkgCipher, queried)
 }
 
 // PutOrUpdateDKGProtocol save DKG protocol.
 func (lvl *LevelDBBackedDB) PutOrUpdateDKGProtocol(info DKGProtocolInfo) error {
-	marshaled, err := rlp.EncodeToBytes(&info)
+	marshaled, err := encodeDKGProtocolInfo(lvl.dkgCipher, &info)
 	if err != nil {
 		return err
 	}
 	return lvl.db.Put(lvl.getDKGProtocolInfoKey(), marshaled, nil)
 }
 
+// GetCRS get CRS of one round.
+func (lvl *LevelDBBackedDB) GetCRS(round uint64) (
+	crs common.Hash, err error) {
+	queried, err := lvl.db.Get(lvl.getCRSKey(round), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			err = ErrCRSDoesNotExist
+		}
+		return
+	}
+	err = rlp.DecodeBytes(queried, &crs)
+	return
+}
+
+// PutCRS save CRS of one round, the CRS of a round would be overwritten when
+// DKG of that round is reset.
+func (lvl *LevelDBBackedDB) PutCRS(round uint64, crs common.Hash) error {
+	marshaled, err := rlp.EncodeToBytes(&crs)
+	if err != nil {
+		return err
+	}
+	return lvl.db.Put(lvl.getCRSKey(round), marshaled, nil)
+}
+
+// GetPartialSignatures returns all partial signatures saved.
+func (lvl *LevelDBBackedDB) GetPartialSignatures() (
+	[]typesDKG.PartialSignature, error) {
+	iter := lvl.db.NewIterator(
+		util.BytesPrefix(lvl.withNamespace(psigsKeyPrefix)), nil)
+	defer iter.Release()
+	ret := []typesDKG.PartialSignature{}
+	for iter.Next() {
+		psigs := []typesDKG.PartialSignature{}
+		if err := rlp.DecodeBytes(iter.Value(), &psigs); err != nil {
+			return nil, err
+		}
+		ret = append(ret, psigs...)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// PutPartialSignatures replaces the partial signatures saved for the hash in
+// one round, including the ones added by AddPartialSignature.
+func (lvl *LevelDBBackedDB) PutPartialSignatures(round uint64,
+	hash common.Hash, psigs []typesDKG.PartialSignature) error {
+	key := lvl.getPartialSignaturesKey(round, hash)
+	batch := new(leveldb.Batch)
+	iter := lvl.db.NewIterator(util.BytesPrefix(key), nil)
+	for iter.Next() {
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	if len(psigs) > 0 {
+		marshaled, err := rlp.EncodeToBytes(&psigs)
+		if err != nil {
+			return err
+		}
+		batch.Put(key, marshaled)
+	}
+	return lvl.db.Write(batch, nil)
+}
+
+// AddPartialSignature saves one more partial signature, it's saved under its
+// own key so the ones saved are not rewritten.
+func (lvl *LevelDBBackedDB) AddPartialSignature(
+	psig typesDKG.PartialSignature) error {
+	psigs := []typesDKG.PartialSignature{psig}
+	marshaled, err := rlp.EncodeToBytes(&psigs)
+	if err != nil {
+		return err
+	}
+	return lvl.db.Put(lvl.getPartialSignatureKey(psig), marshaled, nil)
+}
+
+// GetBlockLabel returns the label of a block.
+func (lvl *LevelDBBackedDB) GetBlockLabel(
+	hash common.Hash) (string, bool, error) {
+	queried, err := lvl.db.Get(lvl.getBlockLabelKey(hash), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return string(queried), true, nil
+}
+
+// SetBlockLabel attaches a label to a stored block, or removes the label when
+// it's empty.
+func (lvl *LevelDBBackedDB) SetBlockLabel(
+	hash common.Hash, label string) error {
+	key := lvl.getBlockLabelKey(hash)
+	if len(label) == 0 {
+		return lvl.db.Delete(key, nil)
+	}
+	exists, err := lvl.internalHasBlock(lvl.getBlockKey(hash))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrBlockDoesNotExist
+	}
+	return lvl.db.Put(key, []byte(label), nil)
+}
+
+// withNamespace prefixes the key with the namespace of this DB.
+func (lvl *LevelDBBackedDB) withNamespace(key []byte) []byte {
+	if len(lvl.namespace) == 0 {
+		return key
+	}
+	ret := make([]byte, len(lvl.namespace)+len(key))
+	copy(ret, lvl.namespace)
+	copy(ret[len(lvl.namespace):], key)
+	return ret
+}
+
 func (lvl *LevelDBBackedDB) getBlockKey(hash common.Hash) (ret []byte) {
 	ret = make([]byte, len(blockKeyPrefix)+len(hash[:]))
 	copy(ret, blockKeyPrefix)
 	copy(ret[len(blockKeyPrefix):], hash[:])
-	return
+	return lvl.withNamespace(ret)
+}
+
+func (lvl *LevelDBBackedDB) getCompactionChainTipInfoKey() []byte {
+	return lvl.withNamespace(compactionChainTipInfoKey)
+}
+
+func (lvl *LevelDBBackedDB) getCompactionChainReorgsKey() []byte {
+	return lvl.withNamespace(compactionChainReorgsKey)
 }
 
 func (lvl *LevelDBBackedDB) getDKGPrivateKeyKey(
@@ -563,11 +934,43 @@ func (lvl *LevelDBBackedDB) getDKGPrivateKeyKey(
 	copy(ret, dkgPrivateKeyKeyPrefix)
 	binary.LittleEndian.PutUint64(
 		ret[len(dkgPrivateKeyKeyPrefix):], round)
-	return
+	return lvl.withNamespace(ret)
 }
 
 func (lvl *LevelDBBackedDB) getDKGProtocolInfoKey() (ret []byte) {
 	ret = make([]byte, len(dkgProtocolInfoKeyPrefix)+8)
 	copy(ret, dkgProtocolInfoKeyPrefix)
+	return lvl.withNamespace(ret)
+}
+
+func (lvl *LevelDBBackedDB) getPartialSignaturesKey(
+	round uint64, hash common.Hash) (ret []byte) {
+	ret = make([]byte, len(psigsKeyPrefix)+8+len(hash[:]))
+	copy(ret, psigsKeyPrefix)
+	binary.LittleEndian.PutUint64(ret[len(psigsKeyPrefix):], round)
+	copy(ret[len(psigsKeyPrefix)+8:], hash[:])
+	return lvl.withNamespace(ret)
+}
+
+func (lvl *LevelDBBackedDB) getPartialSignatureKey(
+	psig typesDKG.PartialSignature) (ret []byte) {
+	prefix := lvl.getPartialSignaturesKey(psig.Round, psig.Hash)
+	ret = make([]byte, len(prefix)+len(psig.ProposerID.Hash[:]))
+	copy(ret, prefix)
+	copy(ret[len(prefix):], psig.ProposerID.Hash[:])
 	return
 }
+
+func (lvl *LevelDBBackedDB) getBlockLabelKey(hash common.Hash) (ret []byte) {
+	ret = make([]byte, len(labelKeyPrefix)+len(hash[:]))
+	copy(ret, labelKeyPrefix)
+	copy(ret[len(labelKeyPrefix):], hash[:])
+	return lvl.withNamespace(ret)
+}
+
+func (lvl *LevelDBBackedDB) getCRSKey(round uint64) (ret []byte) {
+	ret = make([]byte, len(crsKeyPrefix)+8)
+	copy(ret, crsKeyPrefix)
+	binary.LittleEndian.PutUint64(ret[len(crsKeyPrefix):], round)
+	return lvl.withNamespace(ret)
+}
@@ -29,8 +29,11 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
 	"github.com/dexon-foundation/dexon/rlp"
 )
 
@@ -158,6 +161,250 @@ func (s *LevelDBTestSuite) TestCompactionChainTipInfo() {
 	s.Require().NoError(dbInst.PutCompactionChainTipInfo(hash, 2))
 }
 
+func (s *LevelDBTestSuite) TestReorgCompactionChainTip() {
+	dbName := fmt.Sprintf("test-db-%v-cc-reorg.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	hashes := common.Hashes{}
+	for i := 0; i < 3; i++ {
+		hashes = append(hashes, common.NewRandomHash())
+		s.Require().NoError(
+			dbInst.PutCompactionChainTipInfo(hashes[i], uint64(i+1)))
+	}
+	// Reorgs without confirmation are refused.
+	s.Require().Equal(ErrCompactionChainReorgNotConfirmed,
+		dbInst.ReorgCompactionChainTip(hashes[0], 1, false))
+	// Reorgs can't raise the height.
+	s.Require().Equal(ErrInvalidCompactionChainTipHeight,
+		dbInst.ReorgCompactionChainTip(hashes[0], 4, true))
+	reorgs, err := dbInst.GetCompactionChainReorgs()
+	s.Require().NoError(err)
+	s.Require().Empty(reorgs)
+	// Reorg to a lower height, and then replace the tip at the same height.
+	s.Require().NoError(dbInst.ReorgCompactionChainTip(hashes[0], 1, true))
+	hash, height := dbInst.GetCompactionChainTipInfo()
+	s.Require().Equal(hashes[0], hash)
+	s.Require().Equal(uint64(1), height)
+	newHash := common.NewRandomHash()
+	s.Require().NoError(dbInst.ReorgCompactionChainTip(newHash, 1, true))
+	reorgs, err = dbInst.GetCompactionChainReorgs()
+	s.Require().NoError(err)
+	s.Require().Equal([]CompactionChainReorg{
+		{FromHash: hashes[2], FromHeight: 3, ToHash: hashes[0], ToHeight: 1},
+		{FromHash: hashes[0], FromHeight: 1, ToHash: newHash, ToHeight: 1},
+	}, reorgs)
+	// Putting tips is still monotonic after reorgs.
+	s.Require().Equal(ErrInvalidCompactionChainTipHeight,
+		dbInst.PutCompactionChainTipInfo(hashes[1], 1))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(hashes[1], 2))
+}
+
+func (s *LevelDBTestSuite) TestGetCompactionChainTipBlock() {
+	dbName := fmt.Sprintf("test-db-%v-cc-tip-block.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	// No tip yet.
+	_, err = dbInst.GetCompactionChainTipBlock()
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	// The tip block is stored.
+	b := types.Block{Hash: common.NewRandomHash()}
+	s.Require().NoError(dbInst.PutBlock(b))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b.Hash, 1))
+	tip, err := dbInst.GetCompactionChainTipBlock()
+	s.Require().NoError(err)
+	s.Require().Equal(b.Hash, tip.Hash)
+	// The tip is dangling.
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(
+		common.NewRandomHash(), 2))
+	_, err = dbInst.GetCompactionChainTipBlock()
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+}
+
+func (s *LevelDBTestSuite) TestGetFinalizedBlocks() {
+	dbName := fmt.Sprintf("test-db-%v-finalized.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	blocks := make([]types.Block, 4)
+	for i := range blocks {
+		blocks[i] = types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Height: uint64(len(blocks) - i)},
+		}
+		if i%2 == 1 {
+			blocks[i].Randomness = common.NewRandomHash().Bytes()
+		}
+		s.Require().NoError(dbInst.PutBlock(blocks[i]))
+	}
+	iter, err := dbInst.GetFinalizedBlocks()
+	s.Require().NoError(err)
+	for _, i := range []int{3, 1} {
+		b, err := iter.NextBlock()
+		s.Require().NoError(err)
+		s.Require().Equal(blocks[i].Hash, b.Hash)
+	}
+	_, err = iter.NextBlock()
+	s.Require().Equal(ErrIterationFinished, err)
+}
+
+func (s *LevelDBTestSuite) TestCompactionChainLinkCheck() {
+	dbName := fmt.Sprintf("test-db-%v-cc-link.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	// Prepare a chain of blocks and an unrelated one.
+	b1 := types.Block{Hash: common.NewRandomHash()}
+	b2 := types.Block{Hash: common.NewRandomHash(), ParentHash: b1.Hash}
+	fork := types.Block{Hash: common.NewRandomHash()}
+	for _, b := range []types.Block{b1, b2, fork} {
+		s.Require().NoError(dbInst.PutBlock(b))
+	}
+	dbInst.SetCompactionChainLinkCheck(true)
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b1.Hash, 1))
+	// The tip can't jump to a fork.
+	s.Require().Equal(ErrCompactionChainBroken,
+		dbInst.PutCompactionChainTipInfo(fork.Hash, 2))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b2.Hash, 2))
+	// Blocks not stored are not checked.
+	s.Require().NoError(
+		dbInst.PutCompactionChainTipInfo(common.NewRandomHash(), 3))
+}
+
+func (s *LevelDBTestSuite) TestBlockHashCheck() {
+	dbName := fmt.Sprintf("test-db-%v-hash-check.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	block := types.Block{
+		ParentHash: common.NewRandomHash(),
+		Position:   types.Position{Height: 1},
+		Timestamp:  time.Now().UTC(),
+	}
+	hash, err := utils.HashBlock(&block)
+	s.Require().NoError(err)
+	// Blocks with mismatched hash are stored when the check is disabled.
+	block.Hash = common.NewRandomHash()
+	s.Require().NoError(dbInst.PutBlock(block))
+	dbInst.SetBlockHashCheck(true)
+	block.Hash = common.NewRandomHash()
+	s.Require().Equal(ErrBlockHashMismatch, dbInst.PutBlock(block))
+	s.Require().False(dbInst.HasBlock(block.Hash))
+	block.Hash = hash
+	s.Require().NoError(dbInst.PutBlock(block))
+	s.Require().True(dbInst.HasBlock(hash))
+}
+
+func (s *LevelDBTestSuite) TestMaxBlockSize() {
+	dbName := fmt.Sprintf("test-db-%v-max-block-size.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	dbInst.SetMaxBlockSize(16)
+	small := types.Block{
+		Hash:    common.NewRandomHash(),
+		Payload: make([]byte, 8),
+		Witness: types.Witness{Data: make([]byte, 8)},
+	}
+	s.Require().NoError(dbInst.PutBlock(small))
+	large := types.Block{
+		Hash:    common.NewRandomHash(),
+		Payload: make([]byte, 8),
+		Witness: types.Witness{Data: make([]byte, 9)},
+	}
+	s.Require().Equal(ErrBlockTooLarge, dbInst.PutBlock(large))
+	s.Require().False(dbInst.HasBlock(large.Hash))
+	// No limit when it's reset to zero.
+	dbInst.SetMaxBlockSize(0)
+	s.Require().NoError(dbInst.PutBlock(large))
+}
+
+func (s *LevelDBTestSuite) TestNamespaced() {
+	dbName := fmt.Sprintf("test-db-%v-namespaced.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	// Prefixes of namespaces might be prefixes of each other.
+	dbs := []*LevelDBBackedDB{
+		dbInst,
+		Namespaced(dbInst, "a"),
+		Namespaced(dbInst, "ab"),
+		Namespaced(Namespaced(dbInst, "a"), "b"),
+	}
+	blocks := make([]types.Block, len(dbs))
+	for i, d := range dbs {
+		blocks[i] = types.Block{Hash: common.NewRandomHash()}
+		s.Require().NoError(d.PutBlock(blocks[i]))
+		s.Require().NoError(d.PutCompactionChainTipInfo(blocks[i].Hash, 1))
+		s.Require().NoError(d.PutCRS(1, blocks[i].Hash))
+	}
+	for i, d := range dbs {
+		for j := range blocks {
+			s.Require().Equal(i == j, d.HasBlock(blocks[j].Hash))
+		}
+		iter, err := d.GetAllBlocks()
+		s.Require().NoError(err)
+		b, err := iter.NextBlock()
+		s.Require().NoError(err)
+		s.Require().Equal(blocks[i].Hash, b.Hash)
+		_, err = iter.NextBlock()
+		s.Require().Equal(ErrIterationFinished, err)
+		hash, height := d.GetCompactionChainTipInfo()
+		s.Require().Equal(blocks[i].Hash, hash)
+		s.Require().Equal(uint64(1), height)
+		crs, err := d.GetCRS(1)
+		s.Require().NoError(err)
+		s.Require().Equal(blocks[i].Hash, crs)
+	}
+	// DKG private keys are isolated as well.
+	p := dkg.NewPrivateKey()
+	s.Require().NoError(dbs[1].PutDKGPrivateKey(1, 0, *p))
+	for i, d := range dbs {
+		_, err := d.GetDKGPrivateKey(1, 0)
+		if i == 1 {
+			s.Require().NoError(err)
+		} else {
+			s.Require().Equal(ErrDKGPrivateKeyDoesNotExist, err)
+		}
+	}
+}
+
 func (s *LevelDBTestSuite) TestDKGPrivateKey() {
 	dbName := fmt.Sprintf("test-db-%v-dkg-prv.db", time.Now().UTC())
 	dbInst, err := NewLevelDBBackedDB(dbName)
@@ -196,6 +443,288 @@ func (s *LevelDBTestSuite) TestDKGPrivateKey() {
 	s.Require().NotEqual(bytes.Compare(p2.Bytes(), p.Bytes()), 0)
 }
 
+func (s *LevelDBTestSuite) TestHasDKGPrivateKeyInRange() {
+	dbName := fmt.Sprintf("test-db-%v-dkg-prv-range.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		s.NoError(dbInst.Close())
+		s.NoError(os.RemoveAll(dbName))
+	}(dbName)
+	s.Require().NoError(dbInst.PutDKGPrivateKey(2, 0, *dkg.NewPrivateKey()))
+	s.Require().NoError(dbInst.PutDKGPrivateKey(4, 1, *dkg.NewPrivateKey()))
+	s.Require().NoError(dbInst.PutDKGPrivateKey(300, 0, *dkg.NewPrivateKey()))
+	present, err := dbInst.HasDKGPrivateKeyInRange(1, 5)
+	s.Require().NoError(err)
+	s.Require().Equal(map[uint64]bool{
+		1: false, 2: true, 3: false, 4: true, 5: false}, present)
+	present, err = dbInst.HasDKGPrivateKeyInRange(299, 300)
+	s.Require().NoError(err)
+	s.Require().Equal(map[uint64]bool{299: false, 300: true}, present)
+	_, err = dbInst.HasDKGPrivateKeyInRange(5, 4)
+	s.Require().Equal(ErrInvalidRoundRange, err)
+	// Keys in other namespaces are not seen.
+	other := Namespaced(dbInst, "other")
+	s.Require().NoError(other.PutDKGPrivateKey(3, 0, *dkg.NewPrivateKey()))
+	present, err = other.HasDKGPrivateKeyInRange(1, 4)
+	s.Require().NoError(err)
+	s.Require().Equal(map[uint64]bool{
+		1: false, 2: false, 3: true, 4: false}, present)
+	present, err = dbInst.HasDKGPrivateKeyInRange(3, 3)
+	s.Require().NoError(err)
+	s.Require().Equal(map[uint64]bool{3: false}, present)
+}
+
+func (s *LevelDBTestSuite) TestEncryptedDKGPrivateKey() {
+	dbName := fmt.Sprintf("test-db-%v-dkg-prv-enc.db", time.Now().UTC())
+	key := []byte("0123456789abcdef0123456789abcdef")
+	dbInst, err := NewEncryptedLevelDBBackedDB(dbName, key)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	p := dkg.NewPrivateKey()
+	s.Require().NoError(dbInst.PutDKGPrivateKey(1, 0, *p))
+	s.Require().NoError(dbInst.Close())
+	// The private key is not stored in plaintext.
+	dbInst, err = NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	queried, err := dbInst.db.Get(dbInst.getDKGPrivateKeyKey(1), nil)
+	s.Require().NoError(err)
+	s.Require().False(bytes.Contains(queried, p.Bytes()))
+	s.Require().NoError(dbInst.Close())
+	// Reopen with the correct key.
+	dbInst, err = NewEncryptedLevelDBBackedDB(dbName, key)
+	s.Require().NoError(err)
+	tmpPrv, err := dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().NoError(err)
+	s.Require().Equal(bytes.Compare(p.Bytes(), tmpPrv.Bytes()), 0)
+	s.Require().NoError(dbInst.Close())
+	// Reopen with a wrong key.
+	dbInst, err = NewEncryptedLevelDBBackedDB(
+		dbName, []byte("fedcba9876543210fedcba9876543210"))
+	s.Require().NoError(err)
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().Equal(ErrDKGPrivateKeyDecryption, err)
+	s.Require().NoError(dbInst.Close())
+}
+
+func (s *LevelDBTestSuite) TestEncryptedDKGProtocol() {
+	dbName := fmt.Sprintf("test-db-%v-dkg-protocol-enc.db", time.Now().UTC())
+	key := []byte("0123456789abcdef0123456789abcdef")
+	dbInst, err := NewEncryptedLevelDBBackedDB(dbName, key)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	masterPrvShare, _ := dkg.NewPrivateKeyShares(3)
+	prvShares, _ := dkg.NewPrivateKeyShares(3)
+	info := DKGProtocolInfo{
+		ID:                 types.NodeID{Hash: common.Hash{0x11}},
+		Round:              5,
+		Reset:              1,
+		Threshold:          3,
+		MasterPrivateShare: *masterPrvShare,
+		PrvShares:          *prvShares,
+	}
+	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(info))
+	// None of the private shares is stored in plaintext.
+	queried, err := dbInst.db.Get(dbInst.getDKGProtocolInfoKey(), nil)
+	s.Require().NoError(err)
+	for _, shares := range []*dkg.PrivateKeyShares{masterPrvShare, prvShares} {
+		b, err := rlp.EncodeToBytes(shares)
+		s.Require().NoError(err)
+		s.Require().False(bytes.Contains(queried, b))
+	}
+	recovered, err := dbInst.GetDKGProtocol()
+	s.Require().NoError(err)
+	s.Require().True(info.Equal(&recovered))
+	// The round and reset are bound to the ciphertext.
+	sealed := dkgSealed{}
+	s.Require().NoError(rlp.DecodeBytes(queried, &sealed))
+	sealed.Reset++
+	tampered, err := rlp.EncodeToBytes(&sealed)
+	s.Require().NoError(err)
+	s.Require().NoError(
+		dbInst.db.Put(dbInst.getDKGProtocolInfoKey(), tampered, nil))
+	_, err = dbInst.GetDKGProtocol()
+	s.Require().Equal(ErrDKGProtocolDecryption, err)
+}
+
+func (s *LevelDBTestSuite) TestEncryptedDKGPrivateKeySwapped() {
+	dbName := fmt.Sprintf("test-db-%v-dkg-prv-swap.db", time.Now().UTC())
+	key := []byte("0123456789abcdef0123456789abcdef")
+	dbInst, err := NewEncryptedLevelDBBackedDB(dbName, key)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	s.Require().NoError(dbInst.PutDKGPrivateKey(1, 0, *dkg.NewPrivateKey()))
+	s.Require().NoError(dbInst.PutDKGPrivateKey(2, 0, *dkg.NewPrivateKey()))
+	// The private key of round 2 can't be opened as the one of round 1.
+	queried, err := dbInst.db.Get(dbInst.getDKGPrivateKeyKey(2), nil)
+	s.Require().NoError(err)
+	s.Require().NoError(
+		dbInst.db.Put(dbInst.getDKGPrivateKeyKey(1), queried, nil))
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().Equal(ErrDKGPrivateKeyDecryption, err)
+	// Nor as the one of another reset.
+	sealed := dkgSealed{}
+	s.Require().NoError(rlp.DecodeBytes(queried, &sealed))
+	sealed.Reset = 1
+	tampered, err := rlp.EncodeToBytes(&sealed)
+	s.Require().NoError(err)
+	s.Require().NoError(
+		dbInst.db.Put(dbInst.getDKGPrivateKeyKey(2), tampered, nil))
+	_, err = dbInst.GetDKGPrivateKey(2, 1)
+	s.Require().Equal(ErrDKGPrivateKeyDecryption, err)
+}
+
+func (s *LevelDBTestSuite) TestCRS() {
+	dbName := fmt.Sprintf("test-db-%v-crs.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	crs := common.NewRandomHash()
+	// We should be unable to get it.
+	_, err = dbInst.GetCRS(1)
+	s.Require().Equal(err.Error(), ErrCRSDoesNotExist.Error())
+	// Put it.
+	s.Require().NoError(dbInst.PutCRS(1, crs))
+	// Get it back.
+	tmpCRS, err := dbInst.GetCRS(1)
+	s.Require().NoError(err)
+	s.Require().Equal(crs, tmpCRS)
+	// Overwrite it, which happens when DKG is reset.
+	crs2 := common.NewRandomHash()
+	s.Require().NoError(dbInst.PutCRS(1, crs2))
+	tmpCRS, err = dbInst.GetCRS(1)
+	s.Require().NoError(err)
+	s.Require().Equal(crs2, tmpCRS)
+}
+
+func (s *LevelDBTestSuite) TestBlockLabel() {
+	dbName := fmt.Sprintf("test-db-%v-label.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	block := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: 1},
+	}
+	// Only stored blocks could be labeled.
+	s.Require().Equal(ErrBlockDoesNotExist,
+		dbInst.SetBlockLabel(block.Hash, "forked"))
+	s.Require().NoError(dbInst.PutBlock(block))
+	_, exists, err := dbInst.GetBlockLabel(block.Hash)
+	s.Require().NoError(err)
+	s.Require().False(exists)
+	s.Require().NoError(dbInst.SetBlockLabel(block.Hash, "forked"))
+	s.Require().NoError(dbInst.SetBlockLabel(block.Hash, "pruned-candidate"))
+	label, exists, err := dbInst.GetBlockLabel(block.Hash)
+	s.Require().NoError(err)
+	s.Require().True(exists)
+	s.Require().Equal("pruned-candidate", label)
+	// Labels are not iterated as blocks.
+	iter, err := dbInst.GetAllBlocks()
+	s.Require().NoError(err)
+	b, err := iter.NextBlock()
+	s.Require().NoError(err)
+	s.Require().Equal(block.Hash, b.Hash)
+	_, err = iter.NextBlock()
+	s.Require().Equal(ErrIterationFinished, err)
+	// Remove the label.
+	s.Require().NoError(dbInst.SetBlockLabel(block.Hash, ""))
+	_, exists, err = dbInst.GetBlockLabel(block.Hash)
+	s.Require().NoError(err)
+	s.Require().False(exists)
+}
+
+func (s *LevelDBTestSuite) TestPartialSignatures() {
+	dbName := fmt.Sprintf("test-db-%v-psigs.db", time.Now().UTC())
+	dbInst, err := NewLevelDBBackedDB(dbName)
+	s.Require().NoError(err)
+	defer func(dbName string) {
+		err = dbInst.Close()
+		s.NoError(err)
+		err = os.RemoveAll(dbName)
+		s.NoError(err)
+	}(dbName)
+	psig := func(round uint64, hash common.Hash) typesDKG.PartialSignature {
+		return typesDKG.PartialSignature{
+			ProposerID: types.NodeID{Hash: common.NewRandomHash()},
+			Round:      round,
+			Hash:       hash,
+			PartialSignature: dkg.PartialSignature{
+				Type:      "bls",
+				Signature: []byte{1, 2, 3},
+			},
+			Signature: crypto.Signature{
+				Type:      "ecdsa",
+				Signature: []byte{4, 5, 6},
+			},
+		}
+	}
+	psigs, err := dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Empty(psigs)
+	hash := common.NewRandomHash()
+	set1 := []typesDKG.PartialSignature{psig(1, hash), psig(1, hash)}
+	set2 := []typesDKG.PartialSignature{psig(2, hash)}
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, set1))
+	s.Require().NoError(dbInst.PutPartialSignatures(2, hash, set2))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().ElementsMatch(append(append(
+		[]typesDKG.PartialSignature{}, set1...), set2...), psigs)
+	// Replace them.
+	set1 = append(set1, psig(1, hash))
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, set1))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Len(psigs, 4)
+	// Remove them.
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, nil))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Equal(set2, psigs)
+	s.Require().NoError(dbInst.PutPartialSignatures(2, hash, nil))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Empty(psigs)
+	// Add them one by one, they are removed along with the ones put.
+	added := psig(1, hash)
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, set1))
+	s.Require().NoError(dbInst.AddPartialSignature(added))
+	s.Require().NoError(dbInst.AddPartialSignature(psig(2, hash)))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Len(psigs, len(set1)+2)
+	s.Require().Contains(psigs, added)
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, nil))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Len(psigs, 1)
+	s.Require().Equal(uint64(2), psigs[0].Round)
+}
+
 func (s *LevelDBTestSuite) TestDKGProtocol() {
 	dbName := fmt.Sprintf("test-db-%v-dkg-master-prv-shares.db", time.Now().UTC())
 	dbInst, err := NewLevelDBBackedDB(dbName)
@@ -18,54 +18,190 @@
 package db
 
 import (
-	"encoding/json"
+	"bytes"
 	"io/ioutil"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon/rlp"
 )
 
-type blockSeqIterator struct {
-	idx int
-	db  *MemBackedDB
+// blockSubscriberBufferSize is the size of the buffered channel returned by
+// MemBackedDB.SubscribeBlocks.
+const blockSubscriberBufferSize = 64
+
+type blockListIterator struct {
+	idx    int
+	blocks []types.Block
 }
 
 // NextBlock implemenets BlockIterator.NextBlock method.
-func (seq *blockSeqIterator) NextBlock() (types.Block, error) {
-	curIdx := seq.idx
-	seq.idx++
-	return seq.db.getBlockByIndex(curIdx)
+func (it *blockListIterator) NextBlock() (types.Block, error) {
+	if it.idx >= len(it.blocks) {
+		return types.Block{}, ErrIterationFinished
+	}
+	it.idx++
+	return it.blocks[it.idx-1], nil
+}
+
+// newFinalizedBlockIterator returns an iterator of finalized blocks among
+// blocks, ordered by height and then hash.
+func newFinalizedBlockIterator(blocks []types.Block) BlockIterator {
+	finalized := []types.Block{}
+	for _, b := range blocks {
+		if b.IsFinalized() {
+			finalized = append(finalized, b)
+		}
+	}
+	sort.Slice(finalized, func(i, j int) bool {
+		if finalized[i].Position.Height != finalized[j].Position.Height {
+			return finalized[i].Position.Height < finalized[j].Position.Height
+		}
+		return bytes.Compare(finalized[i].Hash[:], finalized[j].Hash[:]) < 0
+	})
+	return &blockListIterator{blocks: finalized}
+}
+
+// maxBlockShards is the maximum count of shards of blocks in MemBackedDB,
+// blocks are distributed by the first two bytes of their hashes.
+const maxBlockShards = 1 << 16
+
+// blockShard is a part of blocks in MemBackedDB with its own lock.
+type blockShard struct {
+	lock   sync.RWMutex
+	blocks map[common.Hash]*types.Block
 }
 
 // MemBackedDB is a memory backed DB implementation.
 type MemBackedDB struct {
+	// blocksLock guards blockHashSequence only, blocks are guarded by the
+	// lock of the shard they belong to.
 	blocksLock               sync.RWMutex
 	blockHashSequence        common.Hashes
-	blocksByHash             map[common.Hash]*types.Block
+	blockShards              []*blockShard
 	compactionChainTipLock   sync.RWMutex
 	compactionChainTipHash   common.Hash
 	compactionChainTipHeight uint64
+	compactionChainLinkCheck bool
+	compactionChainReorgs    []CompactionChainReorg
 	dkgPrivateKeysLock       sync.RWMutex
-	dkgPrivateKeys           map[uint64]*dkgPrivateKey
+	dkgPrivateKeys           map[uint64][]byte
 	dkgProtocolLock          sync.RWMutex
-	dkgProtocolInfo          *DKGProtocolInfo
+	dkgProtocolInfo          []byte
+	dkgCipher                *dkgCipher
+	crsLock                  sync.RWMutex
+	crs                      map[uint64]common.Hash
 	persistantFilePath       string
+	persistFormat            PersistFormat
+	validator                BlockValidator
+	blockHashCheck           bool
+	subscribersLock          sync.RWMutex
+	subscribers              map[uint64]chan types.Block
+	subscriberSeq            uint64
+	droppedNotifications     uint64
+	maxBlockSize             int
+	fileSlot                 *openFileSlot
+	readOnly                 int32
+	psigsLock                sync.RWMutex
+	psigs                    map[psigKey][]typesDKG.PartialSignature
+	approxSize               uint64
+	labelsLock               sync.RWMutex
+	labels                   map[common.Hash]string
+}
+
+type psigKey struct {
+	round uint64
+	hash  common.Hash
 }
 
-// NewMemBackedDB initialize a memory-backed database.
+// memBackedDBDump is the content of MemBackedDB persisted into file, it's a
+// temporary way to export those private fields for encoding.
+type memBackedDBDump struct {
+	Sequence common.Hashes
+	ByHash   map[common.Hash]*types.Block
+	CRS      map[uint64]common.Hash
+	Labels   map[common.Hash]string
+}
+
+// NewMemBackedDB initialize a memory-backed database, the content would be
+// persisted in JSON.
 func NewMemBackedDB(persistantFilePath ...string) (
 	dbInst *MemBackedDB, err error) {
+	return NewMemBackedDBWithFormat(PersistFormatJSON, persistantFilePath...)
+}
+
+// NewEncryptedMemBackedDB initialize a memory-backed database like
+// NewMemBackedDB, the DKG private keys and DKG protocol are kept encrypted
+// in the same way as NewEncryptedLevelDBBackedDB.
+func NewEncryptedMemBackedDB(key []byte, persistantFilePath ...string) (
+	dbInst *MemBackedDB, err error) {
+	c, err := newDKGCipher(key)
+	if err != nil {
+		return
+	}
+	if dbInst, err = NewMemBackedDB(persistantFilePath...); err != nil {
+		return
+	}
+	dbInst.dkgCipher = c
+	return
+}
+
+// NewMemBackedDBWithFormat initialize a memory-backed database persisting its
+// content in the provided format. Files in any supported format could be
+// loaded.
+func NewMemBackedDBWithFormat(
+	format PersistFormat, persistantFilePath ...string) (
+	dbInst *MemBackedDB, err error) {
+	return NewMemBackedDBWithShards(format, 1, persistantFilePath...)
+}
+
+// NewMemBackedDBWithShards initialize a memory-backed database like
+// NewMemBackedDBWithFormat, and distributes blocks into shards by their
+// hashes, so accessing blocks in different shards doesn't contend for the same
+// lock. The count of shards should be in [1, 65536].
+func NewMemBackedDBWithShards(
+	format PersistFormat, shards int, persistantFilePath ...string) (
+	dbInst *MemBackedDB, err error) {
+	if _, err = newSerializer(format); err != nil {
+		return
+	}
+	if shards < 1 || shards > maxBlockShards {
+		err = ErrInvalidShardCount
+		return
+	}
 	dbInst = &MemBackedDB{
+		persistFormat:     format,
 		blockHashSequence: common.Hashes{},
-		blocksByHash:      make(map[common.Hash]*types.Block),
-		dkgPrivateKeys:    make(map[uint64]*dkgPrivateKey),
+		blockShards:       make([]*blockShard, shards),
+		dkgPrivateKeys:    make(map[uint64][]byte),
+		crs:               make(map[uint64]common.Hash),
+		subscribers:       make(map[uint64]chan types.Block),
+		psigs:             make(map[psigKey][]typesDKG.PartialSignature),
+		labels:            make(map[common.Hash]string),
+	}
+	for i := range dbInst.blockShards {
+		dbInst.blockShards[i] = &blockShard{
+			blocks: make(map[common.Hash]*types.Block),
+		}
 	}
 	if len(persistantFilePath) == 0 || len(persistantFilePath[0]) == 0 {
 		return
 	}
+	if dbInst.fileSlot, err = acquireOpenFileSlot(); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			dbInst.fileSlot.release()
+		}
+	}()
 	dbInst.persistantFilePath = persistantFilePath[0]
 	buf, err := ioutil.ReadFile(dbInst.persistantFilePath)
 	if err != nil {
@@ -79,86 +215,288 @@ func NewMemBackedDB(persistantFilePath ...string) (
 		return
 	}
 
-	// Init this instance by file content, it's a temporary way
-	// to export those private field for JSON encoding.
-	toLoad := struct {
-		Sequence common.Hashes
-		ByHash   map[common.Hash]*types.Block
-	}{}
-	err = json.Unmarshal(buf, &toLoad)
-	if err != nil {
+	// Init this instance by file content.
+	toLoad := memBackedDBDump{}
+	if err = decodePersisted(buf, &toLoad); err != nil {
 		return
 	}
 	dbInst.blockHashSequence = toLoad.Sequence
-	dbInst.blocksByHash = toLoad.ByHash
+	for hash, b := range toLoad.ByHash {
+		dbInst.shardOf(hash).blocks[hash] = b
+		dbInst.approxSize += encodedSize(b)
+	}
+	if toLoad.CRS != nil {
+		dbInst.crs = toLoad.CRS
+		for _, crs := range dbInst.crs {
+			dbInst.approxSize += encodedSize(&crs)
+		}
+	}
+	if toLoad.Labels != nil {
+		dbInst.labels = toLoad.Labels
+	}
 	return
 }
 
+// encodedSize returns the length of v encoded in RLP, which is how
+// LevelDBBackedDB stores it, values failed to be encoded are counted as empty.
+func encodedSize(v interface{}) uint64 {
+	b, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		return 0
+	}
+	return uint64(len(b))
+}
+
+// resize replaces the size of a stored value from "from" to "to" in the
+// approximated size of the database.
+func (m *MemBackedDB) resize(from, to uint64) {
+	// Adding (to - from) in two's complement also works when shrinking.
+	atomic.AddUint64(&m.approxSize, to-from)
+}
+
+// ApproxSize returns the approximated count of bytes taken by blocks, DKG
+// private keys, DKG protocol, CRS and partial signatures stored, based on
+// their serialized length. It's maintained when they are put, updated or
+// deleted, so it's cheap to call.
+func (m *MemBackedDB) ApproxSize() (uint64, error) {
+	return atomic.LoadUint64(&m.approxSize), nil
+}
+
+func (m *MemBackedDB) shardOf(hash common.Hash) *blockShard {
+	if len(m.blockShards) == 1 {
+		return m.blockShards[0]
+	}
+	idx := (int(hash[0])<<8 | int(hash[1])) % len(m.blockShards)
+	return m.blockShards[idx]
+}
+
+// lookupBlock returns the stored block identified with the hash, the returned
+// block should not be modified.
+func (m *MemBackedDB) lookupBlock(hash common.Hash) (*types.Block, bool) {
+	shard := m.shardOf(hash)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+	b, ok := shard.blocks[hash]
+	return b, ok
+}
+
 // HasBlock returns wheter or not the DB has a block identified with the hash.
 func (m *MemBackedDB) HasBlock(hash common.Hash) bool {
-	m.blocksLock.RLock()
-	defer m.blocksLock.RUnlock()
-
-	_, ok := m.blocksByHash[hash]
+	_, ok := m.lookupBlock(hash)
 	return ok
 }
 
 // GetBlock returns a block given a hash.
 func (m *MemBackedDB) GetBlock(hash common.Hash) (types.Block, error) {
-	m.blocksLock.RLock()
-	defer m.blocksLock.RUnlock()
-
-	return m.internalGetBlock(hash)
-}
-
-func (m *MemBackedDB) internalGetBlock(hash common.Hash) (types.Block, error) {
-	b, ok := m.blocksByHash[hash]
+	b, ok := m.lookupBlock(hash)
 	if !ok {
 		return types.Block{}, ErrBlockDoesNotExist
 	}
 	return *b, nil
 }
 
+// SetBlockValidator sets the validator to check blocks before PutBlock, it's
+// not thread-safe and should be called before any block is put.
+func (m *MemBackedDB) SetBlockValidator(v BlockValidator) {
+	m.validator = v
+}
+
+// SetBlockHashCheck enables or disables the check that the hash of a block
+// matches its content before PutBlock, it's not thread-safe and should be
+// called before any block is put.
+func (m *MemBackedDB) SetBlockHashCheck(enabled bool) {
+	m.blockHashCheck = enabled
+}
+
+// SetMaxBlockSize sets the limit in bytes of payload and witness data of
+// blocks accepted by PutBlock, zero means unlimited. It's not thread-safe and
+// should be called before any block is put.
+func (m *MemBackedDB) SetMaxBlockSize(bytes int) {
+	m.maxBlockSize = bytes
+}
+
+// SetReadOnly makes writes to the database fail with ErrDBReadOnly, while
+// reads are served normally. It's safe to toggle it at any time.
+func (m *MemBackedDB) SetReadOnly(ro bool) {
+	var v int32
+	if ro {
+		v = 1
+	}
+	atomic.StoreInt32(&m.readOnly, v)
+}
+
+func (m *MemBackedDB) checkWritable() error {
+	if atomic.LoadInt32(&m.readOnly) != 0 {
+		return ErrDBReadOnly
+	}
+	return nil
+}
+
 // PutBlock inserts a new block into the database.
 func (m *MemBackedDB) PutBlock(block types.Block) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	if err := checkBlockSize(&block, m.maxBlockSize); err != nil {
+		return err
+	}
+	if m.blockHashCheck {
+		if err := checkBlockHash(&block); err != nil {
+			return err
+		}
+	}
 	if m.HasBlock(block.Hash) {
 		return ErrBlockExists
 	}
+	if m.validator != nil {
+		if err := m.validator(&block); err != nil {
+			return err
+		}
+	}
 
-	m.blocksLock.Lock()
-	defer m.blocksLock.Unlock()
-
-	m.blockHashSequence = append(m.blockHashSequence, block.Hash)
-	m.blocksByHash[block.Hash] = &block
+	err := func() error {
+		shard := m.shardOf(block.Hash)
+		shard.lock.Lock()
+		defer shard.lock.Unlock()
+		if _, exists := shard.blocks[block.Hash]; exists {
+			return ErrBlockExists
+		}
+		shard.blocks[block.Hash] = &block
+		return nil
+	}()
+	if err != nil {
+		return err
+	}
+	m.resize(0, encodedSize(&block))
+	func() {
+		m.blocksLock.Lock()
+		defer m.blocksLock.Unlock()
+		m.blockHashSequence = append(m.blockHashSequence, block.Hash)
+	}()
+	m.notifySubscribers(block)
 	return nil
 }
 
+// SubscribeBlocks returns a channel to receive blocks newly put into the
+// database, and a function to cancel this subscription. Blocks would be
+// dropped when the subscriber is too slow to receive them.
+func (m *MemBackedDB) SubscribeBlocks() (<-chan types.Block, func()) {
+	m.subscribersLock.Lock()
+	defer m.subscribersLock.Unlock()
+	id := m.subscriberSeq
+	m.subscriberSeq++
+	ch := make(chan types.Block, blockSubscriberBufferSize)
+	m.subscribers[id] = ch
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			m.subscribersLock.Lock()
+			defer m.subscribersLock.Unlock()
+			delete(m.subscribers, id)
+			close(ch)
+		})
+	}
+}
+
+// DroppedBlockNotifications returns the count of blocks not sent to
+// subscribers because they are too slow.
+func (m *MemBackedDB) DroppedBlockNotifications() uint64 {
+	return atomic.LoadUint64(&m.droppedNotifications)
+}
+
+func (m *MemBackedDB) notifySubscribers(block types.Block) {
+	m.subscribersLock.RLock()
+	defer m.subscribersLock.RUnlock()
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- block:
+		default:
+			atomic.AddUint64(&m.droppedNotifications, 1)
+		}
+	}
+}
+
 // UpdateBlock updates a block in the database.
 func (m *MemBackedDB) UpdateBlock(block types.Block) error {
-	if !m.HasBlock(block.Hash) {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	shard := m.shardOf(block.Hash)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	old, exists := shard.blocks[block.Hash]
+	if !exists {
 		return ErrBlockDoesNotExist
 	}
-
-	m.blocksLock.Lock()
-	defer m.blocksLock.Unlock()
-
-	m.blocksByHash[block.Hash] = &block
+	shard.blocks[block.Hash] = &block
+	m.resize(encodedSize(old), encodedSize(&block))
 	return nil
 }
 
+// SetCompactionChainLinkCheck enables or disables the check that the parent
+// of the new tip of compaction chain is the current tip, it's only applied
+// when both blocks are stored. It's not thread-safe and should be called
+// before any tip is put.
+func (m *MemBackedDB) SetCompactionChainLinkCheck(enabled bool) {
+	m.compactionChainLinkCheck = enabled
+}
+
 // PutCompactionChainTipInfo saves tip of compaction chain into the database.
 func (m *MemBackedDB) PutCompactionChainTipInfo(
 	blockHash common.Hash, height uint64) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 	m.compactionChainTipLock.Lock()
 	defer m.compactionChainTipLock.Unlock()
 	if m.compactionChainTipHeight+1 != height {
 		return ErrInvalidCompactionChainTipHeight
 	}
+	if m.compactionChainLinkCheck && m.compactionChainTipHeight > 0 {
+		tip, err := m.GetBlock(blockHash)
+		if err == nil && m.HasBlock(m.compactionChainTipHash) &&
+			tip.ParentHash != m.compactionChainTipHash {
+			return ErrCompactionChainBroken
+		}
+	}
+	m.compactionChainTipHeight = height
+	m.compactionChainTipHash = blockHash
+	return nil
+}
+
+// ReorgCompactionChainTip moves the tip of compaction chain to a lower or
+// equal height when confirmed, and records the reorg.
+func (m *MemBackedDB) ReorgCompactionChainTip(
+	blockHash common.Hash, height uint64, confirmed bool) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	m.compactionChainTipLock.Lock()
+	defer m.compactionChainTipLock.Unlock()
+	if err := checkCompactionChainReorg(
+		m.compactionChainTipHeight, height, confirmed); err != nil {
+		return err
+	}
+	m.compactionChainReorgs = append(m.compactionChainReorgs,
+		CompactionChainReorg{
+			FromHash:   m.compactionChainTipHash,
+			FromHeight: m.compactionChainTipHeight,
+			ToHash:     blockHash,
+			ToHeight:   height,
+		})
 	m.compactionChainTipHeight = height
 	m.compactionChainTipHash = blockHash
 	return nil
 }
 
+// GetCompactionChainReorgs returns the reorgs of the tip of compaction chain.
+func (m *MemBackedDB) GetCompactionChainReorgs() (
+	[]CompactionChainReorg, error) {
+	m.compactionChainTipLock.RLock()
+	defer m.compactionChainTipLock.RUnlock()
+	return append([]CompactionChainReorg{}, m.compactionChainReorgs...), nil
+}
+
 // GetCompactionChainTipInfo get the tip info of compaction chain into the
 // database.
 func (m *MemBackedDB) GetCompactionChainTipInfo() (
@@ -168,29 +506,127 @@ func (m *MemBackedDB) GetCompactionChainTipInfo() (
 	return m.compactionChainTipHash, m.compactionChainTipHeight
 }
 
+// GetCompactionChainTipBlock returns the tip block of compaction chain.
+func (m *MemBackedDB) GetCompactionChainTipBlock() (*types.Block, error) {
+	hash, _ := m.GetCompactionChainTipInfo()
+	block, err := m.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// OrphanBlocks returns hashes of stored blocks not on the compaction chain
+// from its tip to genesis, in the order they are put. All blocks are returned
+// when the compaction chain is empty.
+func (m *MemBackedDB) OrphanBlocks() (common.Hashes, error) {
+	tipHash, _ := m.GetCompactionChainTipInfo()
+	m.blocksLock.RLock()
+	defer m.blocksLock.RUnlock()
+	mainChain := make(map[common.Hash]struct{})
+	for hash := tipHash; hash != (common.Hash{}); {
+		b, exists := m.lookupBlock(hash)
+		if !exists {
+			break
+		}
+		if _, marked := mainChain[hash]; marked {
+			break
+		}
+		mainChain[hash] = struct{}{}
+		hash = b.ParentHash
+	}
+	orphans := common.Hashes{}
+	for _, hash := range m.blockHashSequence {
+		if _, exists := mainChain[hash]; !exists {
+			orphans = append(orphans, hash)
+		}
+	}
+	return orphans, nil
+}
+
+// GenesisBlocks returns hashes of stored genesis blocks, which are at genesis
+// height without parent, in the order they are put.
+func (m *MemBackedDB) GenesisBlocks() (common.Hashes, error) {
+	m.blocksLock.RLock()
+	defer m.blocksLock.RUnlock()
+	genesis := common.Hashes{}
+	for _, hash := range m.blockHashSequence {
+		if b, _ := m.lookupBlock(hash); b.IsGenesis() {
+			genesis = append(genesis, hash)
+		}
+	}
+	return genesis, nil
+}
+
+// GetGenesisBlock returns the only genesis block stored, ErrBlockDoesNotExist
+// is returned when there is none, and ErrMultipleGenesisBlocks is returned
+// when there are more than one.
+func (m *MemBackedDB) GetGenesisBlock() (types.Block, error) {
+	genesis, err := m.GenesisBlocks()
+	if err != nil {
+		return types.Block{}, err
+	}
+	switch len(genesis) {
+	case 0:
+		return types.Block{}, ErrBlockDoesNotExist
+	case 1:
+		return m.GetBlock(genesis[0])
+	default:
+		return types.Block{}, ErrMultipleGenesisBlocks
+	}
+}
+
 // GetDKGPrivateKey get DKG private key of one round.
 func (m *MemBackedDB) GetDKGPrivateKey(round, reset uint64) (
 	dkg.PrivateKey, error) {
 	m.dkgPrivateKeysLock.RLock()
 	defer m.dkgPrivateKeysLock.RUnlock()
-	if prv, exists := m.dkgPrivateKeys[round]; exists && prv.Reset == reset {
-		return prv.PK, nil
+	encoded, exists := m.dkgPrivateKeys[round]
+	if !exists {
+		return dkg.PrivateKey{}, ErrDKGPrivateKeyDoesNotExist
+	}
+	return decodeDKGPrivateKey(m.dkgCipher, round, reset, encoded)
+}
+
+// HasDKGPrivateKeyInRange implements Reader.HasDKGPrivateKeyInRange method.
+func (m *MemBackedDB) HasDKGPrivateKeyInRange(low, high uint64) (
+	map[uint64]bool, error) {
+	ret, err := newRoundPresence(low, high)
+	if err != nil {
+		return nil, err
+	}
+	m.dkgPrivateKeysLock.RLock()
+	defer m.dkgPrivateKeysLock.RUnlock()
+	for round := range ret {
+		_, ret[round] = m.dkgPrivateKeys[round]
 	}
-	return dkg.PrivateKey{}, ErrDKGPrivateKeyDoesNotExist
+	return ret, nil
 }
 
 // PutDKGPrivateKey save DKG private key of one round.
 func (m *MemBackedDB) PutDKGPrivateKey(
 	round, reset uint64, prv dkg.PrivateKey) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 	m.dkgPrivateKeysLock.Lock()
 	defer m.dkgPrivateKeysLock.Unlock()
-	if prv, exists := m.dkgPrivateKeys[round]; exists && prv.Reset == reset {
-		return ErrDKGPrivateKeyExists
+	old, exists := m.dkgPrivateKeys[round]
+	if exists {
+		_, err := decodeDKGPrivateKey(m.dkgCipher, round, reset, old)
+		if err == nil {
+			return ErrDKGPrivateKeyExists
+		}
+		if err != ErrDKGPrivateKeyDoesNotExist {
+			return err
+		}
 	}
-	m.dkgPrivateKeys[round] = &dkgPrivateKey{
-		PK:    prv,
-		Reset: reset,
+	encoded, err := encodeDKGPrivateKey(m.dkgCipher, round, reset, prv)
+	if err != nil {
+		return err
 	}
+	m.dkgPrivateKeys[round] = encoded
+	m.resize(uint64(len(old)), uint64(len(encoded)))
 	return nil
 }
 
@@ -203,38 +639,162 @@ func (m *MemBackedDB) GetDKGProtocol() (
 		return DKGProtocolInfo{}, ErrDKGProtocolDoesNotExist
 	}
 
-	return *m.dkgProtocolInfo, nil
+	return decodeDKGProtocolInfo(m.dkgCipher, m.dkgProtocolInfo)
 }
 
 // PutOrUpdateDKGProtocol save DKG protocol.
 func (m *MemBackedDB) PutOrUpdateDKGProtocol(dkgProtocol DKGProtocolInfo) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
 	m.dkgProtocolLock.Lock()
 	defer m.dkgProtocolLock.Unlock()
-	m.dkgProtocolInfo = &dkgProtocol
+	encoded, err := encodeDKGProtocolInfo(m.dkgCipher, &dkgProtocol)
+	if err != nil {
+		return err
+	}
+	m.resize(uint64(len(m.dkgProtocolInfo)), uint64(len(encoded)))
+	m.dkgProtocolInfo = encoded
+	return nil
+}
+
+// GetCRS get CRS of one round.
+func (m *MemBackedDB) GetCRS(round uint64) (common.Hash, error) {
+	m.crsLock.RLock()
+	defer m.crsLock.RUnlock()
+	crs, exists := m.crs[round]
+	if !exists {
+		return common.Hash{}, ErrCRSDoesNotExist
+	}
+	return crs, nil
+}
+
+// PutCRS save CRS of one round, the CRS of a round would be overwritten when
+// DKG of that round is reset.
+func (m *MemBackedDB) PutCRS(round uint64, crs common.Hash) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	m.crsLock.Lock()
+	defer m.crsLock.Unlock()
+	if _, exists := m.crs[round]; !exists {
+		m.resize(0, encodedSize(&crs))
+	}
+	m.crs[round] = crs
+	return nil
+}
+
+// GetPartialSignatures returns all partial signatures saved.
+func (m *MemBackedDB) GetPartialSignatures() (
+	[]typesDKG.PartialSignature, error) {
+	m.psigsLock.RLock()
+	defer m.psigsLock.RUnlock()
+	ret := []typesDKG.PartialSignature{}
+	for _, psigs := range m.psigs {
+		ret = append(ret, psigs...)
+	}
+	return ret, nil
+}
+
+// PutPartialSignatures replaces the partial signatures saved for the hash in
+// one round.
+func (m *MemBackedDB) PutPartialSignatures(round uint64, hash common.Hash,
+	psigs []typesDKG.PartialSignature) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	m.psigsLock.Lock()
+	defer m.psigsLock.Unlock()
+	key := psigKey{round: round, hash: hash}
+	oldSize := psigsSize(m.psigs[key])
+	if len(psigs) == 0 {
+		delete(m.psigs, key)
+		m.resize(oldSize, 0)
+		return nil
+	}
+	m.psigs[key] = append([]typesDKG.PartialSignature(nil), psigs...)
+	m.resize(oldSize, psigsSize(psigs))
+	return nil
+}
+
+// psigsSize returns the size of partial signatures, they are sized one by one
+// as they might be added by AddPartialSignature.
+func psigsSize(psigs []typesDKG.PartialSignature) (size uint64) {
+	for i := range psigs {
+		size += encodedSize(&psigs[i])
+	}
+	return
+}
+
+// AddPartialSignature saves one more partial signature for its hash in its
+// round.
+func (m *MemBackedDB) AddPartialSignature(
+	psig typesDKG.PartialSignature) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	m.psigsLock.Lock()
+	defer m.psigsLock.Unlock()
+	key := psigKey{round: psig.Round, hash: psig.Hash}
+	m.psigs[key] = append(m.psigs[key], psig)
+	m.resize(0, encodedSize(&psig))
+	return nil
+}
+
+// GetBlockLabel returns the label of a block.
+func (m *MemBackedDB) GetBlockLabel(hash common.Hash) (string, bool, error) {
+	m.labelsLock.RLock()
+	defer m.labelsLock.RUnlock()
+	label, exists := m.labels[hash]
+	return label, exists, nil
+}
+
+// SetBlockLabel attaches a label to a stored block, or removes the label when
+// it's empty.
+func (m *MemBackedDB) SetBlockLabel(hash common.Hash, label string) error {
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+	m.labelsLock.Lock()
+	defer m.labelsLock.Unlock()
+	if len(label) == 0 {
+		delete(m.labels, hash)
+		return nil
+	}
+	if !m.HasBlock(hash) {
+		return ErrBlockDoesNotExist
+	}
+	m.labels[hash] = label
 	return nil
 }
 
 // Close implement Closer interface, which would release allocated resource.
 func (m *MemBackedDB) Close() (err error) {
-	// Save internal state to a pretty-print json file. It's a temporary way
-	// to dump private file via JSON encoding.
+	defer m.fileSlot.release()
+	// Save internal state to file in the format specified when constructing.
 	if len(m.persistantFilePath) == 0 {
 		return
 	}
 
 	m.blocksLock.RLock()
 	defer m.blocksLock.RUnlock()
-
-	toDump := struct {
-		Sequence common.Hashes
-		ByHash   map[common.Hash]*types.Block
-	}{
+	m.crsLock.RLock()
+	defer m.crsLock.RUnlock()
+	m.labelsLock.RLock()
+	defer m.labelsLock.RUnlock()
+
+	byHash := make(map[common.Hash]*types.Block, len(m.blockHashSequence))
+	for _, hash := range m.blockHashSequence {
+		byHash[hash], _ = m.lookupBlock(hash)
+	}
+	toDump := memBackedDBDump{
 		Sequence: m.blockHashSequence,
-		ByHash:   m.blocksByHash,
+		ByHash:   byHash,
+		CRS:      m.crs,
+		Labels:   m.labels,
 	}
 
-	// Dump to JSON with 2-space indent.
-	buf, err := json.Marshal(&toDump)
+	buf, err := encodePersisted(m.persistFormat, &toDump)
 	if err != nil {
 		return
 	}
@@ -243,20 +803,57 @@ func (m *MemBackedDB) Close() (err error) {
 	return
 }
 
-func (m *MemBackedDB) getBlockByIndex(idx int) (types.Block, error) {
+// GetAllBlocks implement Reader.GetAllBlocks method, which allows caller
+// to retrieve all blocks in DB. The iterator iterates a snapshot of blocks
+// when it's created, blocks put or updated afterward are not seen.
+func (m *MemBackedDB) GetAllBlocks() (BlockIterator, error) {
 	m.blocksLock.RLock()
 	defer m.blocksLock.RUnlock()
 
-	if idx >= len(m.blockHashSequence) {
-		return types.Block{}, ErrIterationFinished
+	blocks := make([]types.Block, 0, len(m.blockHashSequence))
+	for _, hash := range m.blockHashSequence {
+		b, _ := m.lookupBlock(hash)
+		blocks = append(blocks, *b)
 	}
+	return &blockListIterator{blocks: blocks}, nil
+}
 
-	hash := m.blockHashSequence[idx]
-	return m.internalGetBlock(hash)
+// GetFinalizedBlocks implement Reader.GetFinalizedBlocks method.
+func (m *MemBackedDB) GetFinalizedBlocks() (BlockIterator, error) {
+	blocks := []types.Block{}
+	for _, shard := range m.blockShards {
+		func() {
+			shard.lock.RLock()
+			defer shard.lock.RUnlock()
+			for _, b := range shard.blocks {
+				blocks = append(blocks, *b)
+			}
+		}()
+	}
+	return newFinalizedBlockIterator(blocks), nil
 }
 
-// GetAllBlocks implement Reader.GetAllBlocks method, which allows caller
-// to retrieve all blocks in DB.
-func (m *MemBackedDB) GetAllBlocks() (BlockIterator, error) {
-	return &blockSeqIterator{db: m}, nil
+// GetBlocksByTimeRange implement Reader.GetBlocksByTimeRange method.
+func (m *MemBackedDB) GetBlocksByTimeRange(
+	start, end time.Time) (BlockIterator, error) {
+	blocks := []types.Block{}
+	for _, shard := range m.blockShards {
+		func() {
+			shard.lock.RLock()
+			defer shard.lock.RUnlock()
+			for _, b := range shard.blocks {
+				if b.Timestamp.Before(start) || b.Timestamp.After(end) {
+					continue
+				}
+				blocks = append(blocks, *b)
+			}
+		}()
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if !blocks[i].Timestamp.Equal(blocks[j].Timestamp) {
+			return blocks[i].Timestamp.Before(blocks[j].Timestamp)
+		}
+		return bytes.Compare(blocks[i].Hash[:], blocks[j].Hash[:]) < 0
+	})
+	return &blockListIterator{blocks: blocks}, nil
 }
@@ -19,12 +19,23 @@ package db
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+	"github.com/dexon-foundation/dexon/rlp"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -86,6 +97,8 @@ func (s *MemBackedDBTestSuite) TestSaveAndLoad() {
 	s.NoError(dbInst.PutBlock(*s.b00))
 	s.NoError(dbInst.PutBlock(*s.b01))
 	s.NoError(dbInst.PutBlock(*s.b02))
+	crs := common.NewRandomHash()
+	s.NoError(dbInst.PutCRS(1, crs))
 	s.NoError(dbInst.Close())
 
 	// Load the json file back to check if all inserted blocks
@@ -96,9 +109,140 @@ func (s *MemBackedDBTestSuite) TestSaveAndLoad() {
 	s.True(dbInst.HasBlock(s.b00.Hash))
 	s.True(dbInst.HasBlock(s.b01.Hash))
 	s.True(dbInst.HasBlock(s.b02.Hash))
+	crsBack, err := dbInst.GetCRS(1)
+	s.Require().NoError(err)
+	s.Equal(crs, crsBack)
 	s.NoError(dbInst.Close())
 }
 
+func (s *MemBackedDBTestSuite) TestShards() {
+	_, err := NewMemBackedDBWithShards(PersistFormatJSON, 0)
+	s.Require().Equal(ErrInvalidShardCount, err)
+	_, err = NewMemBackedDBWithShards(PersistFormatJSON, maxBlockShards+1)
+	s.Require().Equal(ErrInvalidShardCount, err)
+	dbPath := "test-shards.db"
+	defer os.Remove(dbPath)
+	dbInst, err := NewMemBackedDBWithShards(PersistFormatJSON, 16, dbPath)
+	s.Require().NoError(err)
+	now := time.Now().UTC()
+	hashes := common.Hashes{}
+	for i := 0; i < 64; i++ {
+		b := types.Block{
+			Hash:      common.NewRandomHash(),
+			Position:  types.Position{Height: uint64(i)},
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		}
+		s.Require().NoError(dbInst.PutBlock(b))
+		s.Require().Equal(ErrBlockExists, dbInst.PutBlock(b))
+		hashes = append(hashes, b.Hash)
+	}
+	b, err := dbInst.GetBlock(hashes[3])
+	s.Require().NoError(err)
+	b.Payload = []byte("updated")
+	s.Require().NoError(dbInst.UpdateBlock(b))
+	checkBlocks := func(dbInst *MemBackedDB) {
+		// Blocks are iterated in the order they are put.
+		iter, err := dbInst.GetAllBlocks()
+		s.Require().NoError(err)
+		for _, hash := range hashes {
+			b, err := iter.NextBlock()
+			s.Require().NoError(err)
+			s.Require().Equal(hash, b.Hash)
+		}
+		_, err = iter.NextBlock()
+		s.Require().Equal(ErrIterationFinished, err)
+		b, err := dbInst.GetBlock(hashes[3])
+		s.Require().NoError(err)
+		s.Require().Equal([]byte("updated"), b.Payload)
+		iter, err = dbInst.GetBlocksByTimeRange(
+			now.Add(10*time.Second), now.Add(19*time.Second))
+		s.Require().NoError(err)
+		for _, hash := range hashes[10:20] {
+			b, err := iter.NextBlock()
+			s.Require().NoError(err)
+			s.Require().Equal(hash, b.Hash)
+		}
+		_, err = iter.NextBlock()
+		s.Require().Equal(ErrIterationFinished, err)
+	}
+	checkBlocks(dbInst)
+	s.Require().NoError(dbInst.Close())
+	// Blocks are loaded into a different count of shards.
+	dbInst, err = NewMemBackedDBWithShards(PersistFormatJSON, 3, dbPath)
+	s.Require().NoError(err)
+	checkBlocks(dbInst)
+	s.Require().NoError(dbInst.Close())
+}
+
+func (s *MemBackedDBTestSuite) TestPersistFormat() {
+	dbPath := "test-persist-format.db"
+	for _, format := range []PersistFormat{
+		PersistFormatJSON, PersistFormatBinary} {
+		dbInst, err := NewMemBackedDBWithFormat(format, dbPath)
+		s.Require().NoError(err)
+		s.NoError(dbInst.PutBlock(*s.b00))
+		s.NoError(dbInst.PutBlock(*s.b01))
+		crs := common.NewRandomHash()
+		s.NoError(dbInst.PutCRS(1, crs))
+		s.NoError(dbInst.Close())
+		// Files are loaded by the format in header, no matter which format
+		// is used to construct the DB.
+		for _, loadFormat := range []PersistFormat{
+			PersistFormatJSON, PersistFormatBinary} {
+			dbInst, err = NewMemBackedDBWithFormat(loadFormat, dbPath)
+			s.Require().NoError(err)
+			b, err := dbInst.GetBlock(s.b01.Hash)
+			s.Require().NoError(err)
+			s.Require().Equal(s.b01.ParentHash, b.ParentHash)
+			s.Require().Equal(s.b01.Position, b.Position)
+			s.True(dbInst.HasBlock(s.b00.Hash))
+			crsBack, err := dbInst.GetCRS(1)
+			s.Require().NoError(err)
+			s.Equal(crs, crsBack)
+		}
+		s.Require().NoError(os.Remove(dbPath))
+	}
+	// Unknown formats.
+	_, err := NewMemBackedDBWithFormat(PersistFormat(100), dbPath)
+	s.Require().Equal(ErrUnknownPersistFormat, err)
+	s.Require().NoError(ioutil.WriteFile(
+		dbPath, append(persistMagic, persistVersion, 100), 0644))
+	defer os.Remove(dbPath)
+	_, err = NewMemBackedDB(dbPath)
+	s.Require().Equal(ErrUnknownPersistFormat, err)
+}
+
+func (s *MemBackedDBTestSuite) TestMaxOpenFiles() {
+	SetMaxOpenFiles(2)
+	defer SetMaxOpenFiles(0)
+	dbPath := fmt.Sprintf("test-db-%v-max-open-files", time.Now().UTC())
+	defer os.Remove(dbPath + "-0")
+	defer os.Remove(dbPath + "-1")
+	defer os.RemoveAll(dbPath + "-2")
+	db0, err := NewMemBackedDB(dbPath + "-0")
+	s.Require().NoError(err)
+	db1, err := NewMemBackedDB(dbPath + "-1")
+	s.Require().NoError(err)
+	// Memory-backed DBs without files are not limited.
+	_, err = NewMemBackedDB()
+	s.Require().NoError(err)
+	// Both memory-backed and leveldb-backed DBs are limited.
+	_, err = NewMemBackedDB(dbPath + "-2")
+	s.Require().Equal(ErrTooManyOpenFiles, err)
+	_, err = NewLevelDBBackedDB(dbPath + "-2")
+	s.Require().Equal(ErrTooManyOpenFiles, err)
+	// Closing a DB releases its slot, and closing it again doesn't release
+	// one more.
+	s.Require().NoError(db0.Close())
+	s.Require().NoError(db0.Close())
+	db2, err := NewLevelDBBackedDB(dbPath + "-2")
+	s.Require().NoError(err)
+	_, err = NewMemBackedDB(dbPath + "-0")
+	s.Require().Equal(ErrTooManyOpenFiles, err)
+	s.Require().NoError(db2.Close())
+	s.Require().NoError(db1.Close())
+}
+
 func (s *MemBackedDBTestSuite) TestIteration() {
 	// Make sure the file pointed by 'dbPath' doesn't exist.
 	dbInst, err := NewMemBackedDB()
@@ -128,6 +272,136 @@ func (s *MemBackedDBTestSuite) TestIteration() {
 	s.Contains(touched, s.b02.Hash)
 }
 
+func (s *MemBackedDBTestSuite) TestIterationWithConcurrentPut() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	for i := 0; i < 100; i++ {
+		s.Require().NoError(
+			dbInst.PutBlock(types.Block{Hash: common.NewRandomHash()}))
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := dbInst.PutBlock(
+				types.Block{Hash: common.NewRandomHash()}); err != nil {
+				panic(err)
+			}
+		}
+	}()
+	// Each iteration sees a snapshot, which never shrinks.
+	prevCount := 100
+	for i := 0; i < 10; i++ {
+		iter, err := dbInst.GetAllBlocks()
+		s.Require().NoError(err)
+		count := 0
+		for {
+			_, err := iter.NextBlock()
+			if err == ErrIterationFinished {
+				break
+			}
+			s.Require().NoError(err)
+			count++
+		}
+		s.Require().True(count >= prevCount)
+		prevCount = count
+	}
+	<-done
+	// Blocks put after the iterator created are not seen.
+	iter, err := dbInst.GetAllBlocks()
+	s.Require().NoError(err)
+	s.Require().NoError(
+		dbInst.PutBlock(types.Block{Hash: common.NewRandomHash()}))
+	count := 0
+	for {
+		if _, err := iter.NextBlock(); err == ErrIterationFinished {
+			break
+		}
+		count++
+	}
+	s.Require().Equal(200, count)
+}
+
+func (s *MemBackedDBTestSuite) TestGetFinalizedBlocks() {
+	dbInst, err := NewMemBackedDBWithShards(PersistFormatJSON, 4)
+	s.Require().NoError(err)
+	collect := func() []uint64 {
+		iter, err := dbInst.GetFinalizedBlocks()
+		s.Require().NoError(err)
+		heights := []uint64{}
+		for {
+			b, err := iter.NextBlock()
+			if err == ErrIterationFinished {
+				break
+			}
+			s.Require().NoError(err)
+			s.Require().True(b.IsFinalized())
+			heights = append(heights, b.Position.Height)
+		}
+		return heights
+	}
+	// No finalized block yet.
+	s.Require().Empty(collect())
+	blocks := make([]types.Block, 6)
+	for i := range blocks {
+		blocks[i] = types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Height: uint64(len(blocks) - i)},
+		}
+		s.Require().NoError(dbInst.PutBlock(blocks[i]))
+	}
+	s.Require().Empty(collect())
+	// Store finalization proofs for some of them.
+	for _, i := range []int{0, 2, 3} {
+		blocks[i].Randomness = common.NewRandomHash().Bytes()
+		s.Require().NoError(dbInst.UpdateBlock(blocks[i]))
+	}
+	s.Require().Equal([]uint64{3, 4, 6}, collect())
+}
+
+func (s *MemBackedDBTestSuite) TestGetBlocksByTimeRange() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	now := time.Now().UTC()
+	blocks := []types.Block{
+		{Hash: common.NewRandomHash(), Timestamp: now},
+		{Hash: common.NewRandomHash(), Timestamp: now.Add(time.Second)},
+		{Hash: common.NewRandomHash(), Timestamp: now.Add(time.Second)},
+		{Hash: common.NewRandomHash(), Timestamp: now.Add(2 * time.Second)},
+		{Hash: common.NewRandomHash(), Timestamp: now.Add(3 * time.Second)},
+	}
+	for _, b := range blocks {
+		s.Require().NoError(dbInst.PutBlock(b))
+	}
+	collect := func(start, end time.Time) common.Hashes {
+		iter, err := dbInst.GetBlocksByTimeRange(start, end)
+		s.Require().NoError(err)
+		hashes := common.Hashes{}
+		for {
+			b, err := iter.NextBlock()
+			if err == ErrIterationFinished {
+				break
+			}
+			s.Require().NoError(err)
+			hashes = append(hashes, b.Hash)
+		}
+		return hashes
+	}
+	// Both boundaries are inclusive, blocks with equal timestamp are ordered
+	// by hash.
+	middle := common.Hashes{blocks[1].Hash, blocks[2].Hash}
+	if bytes.Compare(middle[0][:], middle[1][:]) > 0 {
+		middle[0], middle[1] = middle[1], middle[0]
+	}
+	s.Require().Equal(
+		common.Hashes{middle[0], middle[1], blocks[3].Hash},
+		collect(now.Add(time.Second), now.Add(2*time.Second)))
+	s.Require().Len(collect(now, now.Add(3*time.Second)), len(blocks))
+	// Empty ranges.
+	s.Require().Empty(collect(now.Add(time.Minute), now.Add(time.Hour)))
+	s.Require().Empty(collect(now.Add(time.Second), now))
+}
+
 func (s *MemBackedDBTestSuite) TestCompactionChainTipInfo() {
 	dbInst, err := NewMemBackedDB()
 	s.Require().NoError(err)
@@ -149,6 +423,315 @@ func (s *MemBackedDBTestSuite) TestCompactionChainTipInfo() {
 	s.Require().NoError(dbInst.PutCompactionChainTipInfo(hash, 2))
 }
 
+func (s *MemBackedDBTestSuite) TestReorgCompactionChainTip() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	hashes := common.Hashes{}
+	for i := 0; i < 3; i++ {
+		hashes = append(hashes, common.NewRandomHash())
+		s.Require().NoError(
+			dbInst.PutCompactionChainTipInfo(hashes[i], uint64(i+1)))
+	}
+	// Reorgs without confirmation are refused.
+	s.Require().Equal(ErrCompactionChainReorgNotConfirmed,
+		dbInst.ReorgCompactionChainTip(hashes[0], 1, false))
+	// Reorgs can't raise the height.
+	s.Require().Equal(ErrInvalidCompactionChainTipHeight,
+		dbInst.ReorgCompactionChainTip(hashes[0], 4, true))
+	reorgs, err := dbInst.GetCompactionChainReorgs()
+	s.Require().NoError(err)
+	s.Require().Empty(reorgs)
+	// Reorg to a lower height, and then replace the tip at the same height.
+	s.Require().NoError(dbInst.ReorgCompactionChainTip(hashes[0], 1, true))
+	hash, height := dbInst.GetCompactionChainTipInfo()
+	s.Require().Equal(hashes[0], hash)
+	s.Require().Equal(uint64(1), height)
+	newHash := common.NewRandomHash()
+	s.Require().NoError(dbInst.ReorgCompactionChainTip(newHash, 1, true))
+	reorgs, err = dbInst.GetCompactionChainReorgs()
+	s.Require().NoError(err)
+	s.Require().Equal([]CompactionChainReorg{
+		{FromHash: hashes[2], FromHeight: 3, ToHash: hashes[0], ToHeight: 1},
+		{FromHash: hashes[0], FromHeight: 1, ToHash: newHash, ToHeight: 1},
+	}, reorgs)
+	// Putting tips is still monotonic after reorgs.
+	s.Require().Equal(ErrInvalidCompactionChainTipHeight,
+		dbInst.PutCompactionChainTipInfo(hashes[1], 1))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(hashes[1], 2))
+}
+
+func (s *MemBackedDBTestSuite) TestGetCompactionChainTipBlock() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	// No tip yet.
+	_, err = dbInst.GetCompactionChainTipBlock()
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	// The tip block is stored.
+	b := types.Block{Hash: common.NewRandomHash()}
+	s.Require().NoError(dbInst.PutBlock(b))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b.Hash, 1))
+	tip, err := dbInst.GetCompactionChainTipBlock()
+	s.Require().NoError(err)
+	s.Require().Equal(b.Hash, tip.Hash)
+	// The tip is dangling.
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(
+		common.NewRandomHash(), 2))
+	_, err = dbInst.GetCompactionChainTipBlock()
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+}
+
+func (s *MemBackedDBTestSuite) TestOrphanBlocks() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	// Prepare a chain of blocks and a fork from the first one.
+	b1 := types.Block{Hash: common.NewRandomHash()}
+	b2 := types.Block{Hash: common.NewRandomHash(), ParentHash: b1.Hash}
+	b3 := types.Block{Hash: common.NewRandomHash(), ParentHash: b2.Hash}
+	fork := types.Block{Hash: common.NewRandomHash(), ParentHash: b1.Hash}
+	for _, b := range []types.Block{b1, b2, fork, b3} {
+		s.Require().NoError(dbInst.PutBlock(b))
+	}
+	// All blocks are orphans without tip.
+	orphans, err := dbInst.OrphanBlocks()
+	s.Require().NoError(err)
+	s.Require().Equal(common.Hashes{b1.Hash, b2.Hash, fork.Hash, b3.Hash},
+		orphans)
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b1.Hash, 1))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b2.Hash, 2))
+	orphans, err = dbInst.OrphanBlocks()
+	s.Require().NoError(err)
+	s.Require().Equal(common.Hashes{fork.Hash, b3.Hash}, orphans)
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b3.Hash, 3))
+	orphans, err = dbInst.OrphanBlocks()
+	s.Require().NoError(err)
+	s.Require().Equal(common.Hashes{fork.Hash}, orphans)
+}
+
+func (s *MemBackedDBTestSuite) TestGetFinalizedBlock() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	prvKey := dkg.NewPrivateKey()
+	errBadProof := errors.New("bad proof")
+	verify := func(b *types.Block, proof crypto.Signature) error {
+		if !prvKey.PublicKey().VerifySignature(b.Hash, proof) {
+			return errBadProof
+		}
+		return nil
+	}
+	finalized := types.Block{Hash: common.NewRandomHash()}
+	sig, err := prvKey.Sign(finalized.Hash)
+	s.Require().NoError(err)
+	finalized.Randomness = sig.Signature
+	s.Require().NoError(dbInst.PutBlock(finalized))
+	forged := types.Block{
+		Hash:       common.NewRandomHash(),
+		Randomness: sig.Signature,
+	}
+	s.Require().NoError(dbInst.PutBlock(forged))
+	notFinalized := types.Block{Hash: common.NewRandomHash()}
+	s.Require().NoError(dbInst.PutBlock(notFinalized))
+	// The verified block is returned.
+	b, err := GetFinalizedBlock(dbInst, finalized.Hash, verify)
+	s.Require().NoError(err)
+	s.Require().Equal(finalized.Hash, b.Hash)
+	// The errors from verifier are returned.
+	_, err = GetFinalizedBlock(dbInst, forged.Hash, verify)
+	s.Require().Equal(errBadProof, err)
+	_, err = GetFinalizedBlock(dbInst, notFinalized.Hash, verify)
+	s.Require().Equal(ErrFinalizationProofDoesNotExist, err)
+	_, err = GetFinalizedBlock(dbInst, common.NewRandomHash(), verify)
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+}
+
+func (s *MemBackedDBTestSuite) TestVerifyCompactionChain() {
+	newDB := func(blocks ...types.Block) *MemBackedDB {
+		dbInst, err := NewMemBackedDB()
+		s.Require().NoError(err)
+		for i, b := range blocks {
+			s.Require().NoError(dbInst.PutBlock(b))
+			s.Require().NoError(dbInst.PutCompactionChainTipInfo(
+				b.Hash, types.GenesisHeight+uint64(i)))
+		}
+		return dbInst
+	}
+	chain := make([]types.Block, 4)
+	for i := range chain {
+		chain[i] = types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Height: types.GenesisHeight + uint64(i)},
+		}
+		if i > 0 {
+			chain[i].ParentHash = chain[i-1].Hash
+		}
+	}
+	// An empty compaction chain.
+	s.Require().NoError(VerifyCompactionChain(newDB()))
+	s.Require().NoError(VerifyCompactionChain(newDB(chain...)))
+	checkBroken := func(err error, hash common.Hash, reason error) {
+		s.Require().IsType(&ErrInvalidCompactionChain{}, err)
+		s.Require().Equal(hash, err.(*ErrInvalidCompactionChain).Hash)
+		s.Require().Equal(reason, err.(*ErrInvalidCompactionChain).Reason)
+	}
+	// The parent of the tip is not stored.
+	dbInst := newDB(chain[:2]...)
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(chain[2].Hash, 3))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(chain[3].Hash, 4))
+	s.Require().NoError(dbInst.PutBlock(chain[3]))
+	checkBroken(VerifyCompactionChain(dbInst), chain[2].Hash,
+		ErrBlockDoesNotExist)
+	// A block links to a parent with unexpected height.
+	broken := append([]types.Block{}, chain...)
+	broken[2].ParentHash = chain[0].Hash
+	checkBroken(VerifyCompactionChain(newDB(broken...)), chain[0].Hash,
+		ErrCompactionChainHeightMismatch)
+	// The chain doesn't end at genesis.
+	broken = append([]types.Block{}, chain...)
+	broken[0].ParentHash = common.NewRandomHash()
+	checkBroken(VerifyCompactionChain(newDB(broken...)), chain[0].Hash,
+		ErrCompactionChainNotGenesis)
+}
+
+func (s *MemBackedDBTestSuite) TestGenesisBlocks() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	// b00 refers to itself as parent and is not a genesis block.
+	s.Require().NoError(dbInst.PutBlock(*s.b00))
+	genesis, err := dbInst.GenesisBlocks()
+	s.Require().NoError(err)
+	s.Require().Empty(genesis)
+	_, err = dbInst.GetGenesisBlock()
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	b1 := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: types.GenesisHeight},
+	}
+	b2 := types.Block{
+		Hash:       common.NewRandomHash(),
+		ParentHash: b1.Hash,
+		Position:   types.Position{Height: types.GenesisHeight + 1},
+	}
+	s.Require().NoError(dbInst.PutBlock(b1))
+	s.Require().NoError(dbInst.PutBlock(b2))
+	s.Require().NoError(dbInst.PutBlock(*s.b01))
+	genesis, err = dbInst.GenesisBlocks()
+	s.Require().NoError(err)
+	s.Require().Equal(common.Hashes{b1.Hash}, genesis)
+	b, err := dbInst.GetGenesisBlock()
+	s.Require().NoError(err)
+	s.Require().Equal(b1.Hash, b.Hash)
+	// Another genesis block is an anomaly.
+	anotherGenesis := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: types.GenesisHeight},
+	}
+	s.Require().NoError(dbInst.PutBlock(anotherGenesis))
+	genesis, err = dbInst.GenesisBlocks()
+	s.Require().NoError(err)
+	s.Require().Equal(common.Hashes{b1.Hash, anotherGenesis.Hash}, genesis)
+	_, err = dbInst.GetGenesisBlock()
+	s.Require().Equal(ErrMultipleGenesisBlocks, err)
+}
+
+func (s *MemBackedDBTestSuite) TestCompactionChainLinkCheck() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	// Prepare a chain of blocks and an unrelated one.
+	b1 := types.Block{Hash: common.NewRandomHash()}
+	b2 := types.Block{Hash: common.NewRandomHash(), ParentHash: b1.Hash}
+	fork := types.Block{Hash: common.NewRandomHash()}
+	for _, b := range []types.Block{b1, b2, fork} {
+		s.Require().NoError(dbInst.PutBlock(b))
+	}
+	dbInst.SetCompactionChainLinkCheck(true)
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b1.Hash, 1))
+	// The tip can't jump to a fork.
+	s.Require().Equal(ErrCompactionChainBroken,
+		dbInst.PutCompactionChainTipInfo(fork.Hash, 2))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b2.Hash, 2))
+	// Blocks not stored are not checked.
+	s.Require().NoError(
+		dbInst.PutCompactionChainTipInfo(common.NewRandomHash(), 3))
+}
+
+func (s *MemBackedDBTestSuite) TestBlockHashCheck() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	block := types.Block{
+		ParentHash: common.NewRandomHash(),
+		Position:   types.Position{Height: 1},
+		Timestamp:  time.Now().UTC(),
+	}
+	hash, err := utils.HashBlock(&block)
+	s.Require().NoError(err)
+	// Blocks with mismatched hash are stored when the check is disabled.
+	block.Hash = common.NewRandomHash()
+	s.Require().NoError(dbInst.PutBlock(block))
+	dbInst.SetBlockHashCheck(true)
+	block.Hash = common.NewRandomHash()
+	s.Require().Equal(ErrBlockHashMismatch, dbInst.PutBlock(block))
+	s.Require().False(dbInst.HasBlock(block.Hash))
+	block.Hash = hash
+	s.Require().NoError(dbInst.PutBlock(block))
+	s.Require().True(dbInst.HasBlock(hash))
+}
+
+func (s *MemBackedDBTestSuite) TestMaxBlockSize() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	dbInst.SetMaxBlockSize(16)
+	small := types.Block{
+		Hash:    common.NewRandomHash(),
+		Payload: make([]byte, 8),
+		Witness: types.Witness{Data: make([]byte, 8)},
+	}
+	s.Require().NoError(dbInst.PutBlock(small))
+	large := types.Block{
+		Hash:    common.NewRandomHash(),
+		Payload: make([]byte, 8),
+		Witness: types.Witness{Data: make([]byte, 9)},
+	}
+	s.Require().Equal(ErrBlockTooLarge, dbInst.PutBlock(large))
+	s.Require().False(dbInst.HasBlock(large.Hash))
+	// No limit when it's reset to zero.
+	dbInst.SetMaxBlockSize(0)
+	s.Require().NoError(dbInst.PutBlock(large))
+}
+
+func (s *MemBackedDBTestSuite) TestReadOnly() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	b0 := types.Block{Hash: common.NewRandomHash()}
+	s.Require().NoError(dbInst.PutBlock(b0))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b0.Hash, 1))
+	dbInst.SetReadOnly(true)
+	// Writes are rejected.
+	b1 := types.Block{Hash: common.NewRandomHash(), ParentHash: b0.Hash}
+	s.Require().Equal(ErrDBReadOnly, dbInst.PutBlock(b1))
+	s.Require().Equal(ErrDBReadOnly, dbInst.UpdateBlock(b0))
+	s.Require().Equal(ErrDBReadOnly,
+		dbInst.PutCompactionChainTipInfo(b1.Hash, 2))
+	s.Require().Equal(ErrDBReadOnly,
+		dbInst.PutDKGPrivateKey(1, 0, *dkg.NewPrivateKey()))
+	s.Require().Equal(ErrDBReadOnly,
+		dbInst.PutOrUpdateDKGProtocol(DKGProtocolInfo{}))
+	s.Require().Equal(ErrDBReadOnly, dbInst.PutCRS(1, common.NewRandomHash()))
+	// Reads are served.
+	s.Require().True(dbInst.HasBlock(b0.Hash))
+	s.Require().False(dbInst.HasBlock(b1.Hash))
+	_, err = dbInst.GetBlock(b0.Hash)
+	s.Require().NoError(err)
+	hash, height := dbInst.GetCompactionChainTipInfo()
+	s.Require().Equal(b0.Hash, hash)
+	s.Require().Equal(uint64(1), height)
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().Equal(ErrDKGPrivateKeyDoesNotExist, err)
+	// Writes are resumed.
+	dbInst.SetReadOnly(false)
+	s.Require().NoError(dbInst.PutBlock(b1))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b1.Hash, 2))
+	s.Require().NoError(dbInst.PutDKGPrivateKey(1, 0, *dkg.NewPrivateKey()))
+}
+
 func (s *MemBackedDBTestSuite) TestDKGPrivateKey() {
 	dbInst, err := NewMemBackedDB()
 	s.Require().NoError(err)
@@ -181,6 +764,461 @@ func (s *MemBackedDBTestSuite) TestDKGPrivateKey() {
 	s.Require().NotEqual(bytes.Compare(p2.Bytes(), p.Bytes()), 0)
 }
 
+func (s *MemBackedDBTestSuite) TestHasDKGPrivateKeyInRange() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NoError(dbInst.PutDKGPrivateKey(2, 0, *dkg.NewPrivateKey()))
+	s.Require().NoError(dbInst.PutDKGPrivateKey(4, 1, *dkg.NewPrivateKey()))
+	s.Require().NoError(dbInst.PutDKGPrivateKey(7, 0, *dkg.NewPrivateKey()))
+	present, err := dbInst.HasDKGPrivateKeyInRange(1, 5)
+	s.Require().NoError(err)
+	s.Require().Equal(map[uint64]bool{
+		1: false, 2: true, 3: false, 4: true, 5: false}, present)
+	present, err = dbInst.HasDKGPrivateKeyInRange(7, 7)
+	s.Require().NoError(err)
+	s.Require().Equal(map[uint64]bool{7: true}, present)
+	_, err = dbInst.HasDKGPrivateKeyInRange(5, 4)
+	s.Require().Equal(ErrInvalidRoundRange, err)
+}
+
+func (s *MemBackedDBTestSuite) TestEncryptedDKGSecrets() {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	dbInst, err := NewEncryptedMemBackedDB(key)
+	s.Require().NoError(err)
+	p := dkg.NewPrivateKey()
+	s.Require().NoError(dbInst.PutDKGPrivateKey(1, 0, *p))
+	s.Require().Equal(ErrDKGPrivateKeyExists, dbInst.PutDKGPrivateKey(1, 0, *p))
+	_, err = dbInst.GetDKGPrivateKey(1, 1)
+	s.Require().Equal(ErrDKGPrivateKeyDoesNotExist, err)
+	tmpPrv, err := dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().NoError(err)
+	s.Require().Equal(p.Bytes(), tmpPrv.Bytes())
+	s.Require().False(bytes.Contains(dbInst.dkgPrivateKeys[1], p.Bytes()))
+	// The private key of round 2 can't be opened as the one of round 1.
+	s.Require().NoError(dbInst.PutDKGPrivateKey(2, 0, *dkg.NewPrivateKey()))
+	dbInst.dkgPrivateKeys[1] = dbInst.dkgPrivateKeys[2]
+	_, err = dbInst.GetDKGPrivateKey(1, 0)
+	s.Require().Equal(ErrDKGPrivateKeyDecryption, err)
+	// The private shares in DKG protocol are encrypted, too.
+	prvShares, _ := dkg.NewPrivateKeyShares(3)
+	info := DKGProtocolInfo{
+		ID:        types.NodeID{Hash: common.Hash{0x11}},
+		Round:     5,
+		Threshold: 3,
+		PrvShares: *prvShares,
+	}
+	s.Require().NoError(dbInst.PutOrUpdateDKGProtocol(info))
+	b, err := rlp.EncodeToBytes(prvShares)
+	s.Require().NoError(err)
+	s.Require().False(bytes.Contains(dbInst.dkgProtocolInfo, b))
+	recovered, err := dbInst.GetDKGProtocol()
+	s.Require().NoError(err)
+	s.Require().True(info.Equal(&recovered))
+	// A database encrypted by another key can't open them.
+	other, err := NewEncryptedMemBackedDB(
+		[]byte("fedcba9876543210fedcba9876543210"))
+	s.Require().NoError(err)
+	other.dkgPrivateKeys[2] = dbInst.dkgPrivateKeys[2]
+	other.dkgProtocolInfo = dbInst.dkgProtocolInfo
+	_, err = other.GetDKGPrivateKey(2, 0)
+	s.Require().Equal(ErrDKGPrivateKeyDecryption, err)
+	_, err = other.GetDKGProtocol()
+	s.Require().Equal(ErrDKGProtocolDecryption, err)
+}
+
+func (s *MemBackedDBTestSuite) TestCRS() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NotNil(dbInst)
+	crs := common.NewRandomHash()
+	// We should be unable to get it.
+	_, err = dbInst.GetCRS(1)
+	s.Require().Equal(err.Error(), ErrCRSDoesNotExist.Error())
+	// Put it.
+	s.Require().NoError(dbInst.PutCRS(1, crs))
+	// We should be unable to get it because round is different.
+	_, err = dbInst.GetCRS(2)
+	s.Require().Equal(err.Error(), ErrCRSDoesNotExist.Error())
+	// Get it back.
+	tmpCRS, err := dbInst.GetCRS(1)
+	s.Require().NoError(err)
+	s.Require().Equal(crs, tmpCRS)
+	// Overwrite it, which happens when DKG is reset.
+	crs2 := common.NewRandomHash()
+	s.Require().NoError(dbInst.PutCRS(1, crs2))
+	tmpCRS, err = dbInst.GetCRS(1)
+	s.Require().NoError(err)
+	s.Require().Equal(crs2, tmpCRS)
+	s.Require().NotEqual(crs, crs2)
+}
+
+func (s *MemBackedDBTestSuite) TestPartialSignatures() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	psig := func(round uint64, hash common.Hash) typesDKG.PartialSignature {
+		return typesDKG.PartialSignature{
+			ProposerID: types.NodeID{Hash: common.NewRandomHash()},
+			Round:      round,
+			Hash:       hash,
+			PartialSignature: dkg.PartialSignature{
+				Type:      "bls",
+				Signature: []byte{1, 2, 3},
+			},
+			Signature: crypto.Signature{
+				Type:      "ecdsa",
+				Signature: []byte{4, 5, 6},
+			},
+		}
+	}
+	psigs, err := dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Empty(psigs)
+	hash := common.NewRandomHash()
+	set1 := []typesDKG.PartialSignature{psig(1, hash), psig(1, hash)}
+	set2 := []typesDKG.PartialSignature{psig(2, hash)}
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, set1))
+	s.Require().NoError(dbInst.PutPartialSignatures(2, hash, set2))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().ElementsMatch(append(append(
+		[]typesDKG.PartialSignature{}, set1...), set2...), psigs)
+	// Replace them.
+	set1 = append(set1, psig(1, hash))
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, set1))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Len(psigs, 4)
+	// Remove them.
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, nil))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Equal(set2, psigs)
+	s.Require().NoError(dbInst.PutPartialSignatures(2, hash, nil))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Empty(psigs)
+	// Add them one by one, they are removed along with the ones put.
+	added := psig(1, hash)
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, set1))
+	s.Require().NoError(dbInst.AddPartialSignature(added))
+	s.Require().NoError(dbInst.AddPartialSignature(psig(2, hash)))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Len(psigs, len(set1)+2)
+	s.Require().Contains(psigs, added)
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, nil))
+	psigs, err = dbInst.GetPartialSignatures()
+	s.Require().NoError(err)
+	s.Require().Len(psigs, 1)
+	s.Require().Equal(uint64(2), psigs[0].Round)
+}
+
+func (s *MemBackedDBTestSuite) TestBlockLabel() {
+	dbPath := "test-block-label.db"
+	dbInst, err := NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(os.Remove(dbPath))
+	}()
+	// Only stored blocks could be labeled.
+	s.Require().Equal(ErrBlockDoesNotExist,
+		dbInst.SetBlockLabel(s.b00.Hash, "forked"))
+	s.Require().NoError(dbInst.PutBlock(*s.b00))
+	s.Require().NoError(dbInst.PutBlock(*s.b01))
+	_, exists, err := dbInst.GetBlockLabel(s.b00.Hash)
+	s.Require().NoError(err)
+	s.Require().False(exists)
+	s.Require().NoError(dbInst.SetBlockLabel(s.b00.Hash, "forked"))
+	s.Require().NoError(dbInst.SetBlockLabel(s.b01.Hash, "forked"))
+	s.Require().NoError(dbInst.SetBlockLabel(s.b01.Hash, "pruned-candidate"))
+	label, exists, err := dbInst.GetBlockLabel(s.b01.Hash)
+	s.Require().NoError(err)
+	s.Require().True(exists)
+	s.Require().Equal("pruned-candidate", label)
+	// The block is not modified.
+	b, err := dbInst.GetBlock(s.b01.Hash)
+	s.Require().NoError(err)
+	s.Require().Equal(*s.b01, b)
+	// Remove the label.
+	s.Require().NoError(dbInst.SetBlockLabel(s.b00.Hash, ""))
+	_, exists, err = dbInst.GetBlockLabel(s.b00.Hash)
+	s.Require().NoError(err)
+	s.Require().False(exists)
+	// Labels are persisted.
+	s.Require().NoError(dbInst.Close())
+	dbInst, err = NewMemBackedDB(dbPath)
+	s.Require().NoError(err)
+	label, exists, err = dbInst.GetBlockLabel(s.b01.Hash)
+	s.Require().NoError(err)
+	s.Require().True(exists)
+	s.Require().Equal("pruned-candidate", label)
+	_, exists, err = dbInst.GetBlockLabel(s.b00.Hash)
+	s.Require().NoError(err)
+	s.Require().False(exists)
+	s.Require().NoError(dbInst.Close())
+}
+
+func (s *MemBackedDBTestSuite) TestApproxSize() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	size, err := dbInst.ApproxSize()
+	s.Require().NoError(err)
+	s.Require().Zero(size)
+	// The size should cover the payload of blocks, with some overhead from
+	// other fields.
+	inRange := func(low, high uint64) uint64 {
+		size, err := dbInst.ApproxSize()
+		s.Require().NoError(err)
+		s.Require().True(size >= low && size <= high,
+			"size %d not in [%d, %d]", size, low, high)
+		return size
+	}
+	block := types.Block{
+		Hash:    common.NewRandomHash(),
+		Payload: make([]byte, 1000),
+	}
+	s.Require().NoError(dbInst.PutBlock(block))
+	afterBlock := inRange(1000, 1500)
+	// Update the block with a larger payload.
+	block.Payload = make([]byte, 3000)
+	s.Require().NoError(dbInst.UpdateBlock(block))
+	afterUpdate := inRange(afterBlock+2000, afterBlock+2100)
+	// Put partial signatures and delete them.
+	hash := common.NewRandomHash()
+	psigs := []typesDKG.PartialSignature{{
+		Round: 1,
+		Hash:  hash,
+		PartialSignature: dkg.PartialSignature{
+			Type:      "bls",
+			Signature: make([]byte, 500),
+		},
+	}}
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, psigs))
+	inRange(afterUpdate+500, afterUpdate+700)
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, nil))
+	inRange(afterUpdate, afterUpdate)
+	s.Require().NoError(dbInst.AddPartialSignature(psigs[0]))
+	s.Require().NoError(dbInst.AddPartialSignature(psigs[0]))
+	inRange(afterUpdate+1000, afterUpdate+1400)
+	s.Require().NoError(dbInst.PutPartialSignatures(1, hash, nil))
+	inRange(afterUpdate, afterUpdate)
+	// Put CRS, overwriting it when DKG is reset doesn't change the size.
+	s.Require().NoError(dbInst.PutCRS(1, common.NewRandomHash()))
+	afterCRS := inRange(afterUpdate+common.HashLength,
+		afterUpdate+common.HashLength+8)
+	s.Require().NoError(dbInst.PutCRS(1, common.NewRandomHash()))
+	inRange(afterCRS, afterCRS)
+	// Shrink the block.
+	block.Payload = nil
+	s.Require().NoError(dbInst.UpdateBlock(block))
+	inRange(afterCRS-3100, afterCRS-2900)
+}
+
+func (s *MemBackedDBTestSuite) TestTimestampMonotonicValidator() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NotNil(dbInst)
+	errAlwaysFail := errors.New("always fail")
+	failNext := false
+	dbInst.SetBlockValidator(ComposeBlockValidators(
+		TimestampMonotonicValidator(
+			func(h common.Hash) (*types.Block, bool) {
+				b, err := dbInst.GetBlock(h)
+				if err != nil {
+					return nil, false
+				}
+				return &b, true
+			}),
+		func(*types.Block) error {
+			if failNext {
+				return errAlwaysFail
+			}
+			return nil
+		},
+	))
+	now := time.Now().UTC()
+	b00 := s.b00.Clone()
+	b00.Timestamp = now
+	b01 := s.b01.Clone()
+	b01.Timestamp = now.Add(time.Second)
+	b02 := s.b02.Clone()
+	b02.Timestamp = now
+	// The parent of b00 is itself, which has the same timestamp.
+	s.Require().NoError(dbInst.PutBlock(*b00))
+	s.Require().NoError(dbInst.PutBlock(*b01))
+	// b02 is earlier than its parent.
+	s.Require().Equal(ErrTimestampNotMonotonic, dbInst.PutBlock(*b02))
+	s.Require().False(dbInst.HasBlock(b02.Hash))
+	b02.Timestamp = b01.Timestamp
+	// Other validators are also checked.
+	failNext = true
+	s.Require().Equal(errAlwaysFail, dbInst.PutBlock(*b02))
+	failNext = false
+	s.Require().NoError(dbInst.PutBlock(*b02))
+}
+
+func (s *MemBackedDBTestSuite) TestSignatureValidator() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	prvKey, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	proposerID := types.NewNodeID(prvKey.PublicKey())
+	dbInst.SetBlockValidator(SignatureValidator(ProposerSignatureVerifier(
+		func(nID types.NodeID) (crypto.PublicKey, bool) {
+			if nID != proposerID {
+				return nil, false
+			}
+			return prvKey.PublicKey(), true
+		})))
+	signer := utils.NewSigner(prvKey)
+	newBlock := func(height uint64) *types.Block {
+		b := &types.Block{
+			ParentHash: common.NewRandomHash(),
+			Position:   types.Position{Height: height},
+			Timestamp:  time.Now().UTC(),
+			Payload:    []byte{1, 2, 3},
+		}
+		s.Require().NoError(signer.SignBlock(b))
+		return b
+	}
+	// A valid block.
+	valid := newBlock(1)
+	s.Require().NoError(dbInst.PutBlock(*valid))
+	// A block whose content is tampered after signed.
+	tampered := newBlock(2)
+	tampered.Position.Height = 3
+	tampered.Hash, err = utils.HashBlock(tampered)
+	s.Require().NoError(err)
+	s.Require().Equal(ErrInvalidBlockSignature, dbInst.PutBlock(*tampered))
+	s.Require().False(dbInst.HasBlock(tampered.Hash))
+	// A block signed by someone else.
+	otherKey, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	forged := newBlock(4)
+	forged.Signature, err = otherKey.Sign(forged.Hash)
+	s.Require().NoError(err)
+	s.Require().Equal(ErrInvalidBlockSignature, dbInst.PutBlock(*forged))
+	// A block from an unknown proposer.
+	unknown := newBlock(5)
+	s.Require().NoError(utils.NewSigner(otherKey).SignBlock(unknown))
+	s.Require().Equal(ErrInvalidBlockSignature, dbInst.PutBlock(*unknown))
+	// Custom verifiers are also supported.
+	errAlwaysFail := errors.New("always fail")
+	dbInst.SetBlockValidator(SignatureValidator(func(types.Block) error {
+		return errAlwaysFail
+	}))
+	s.Require().Equal(ErrInvalidBlockSignature, dbInst.PutBlock(*newBlock(6)))
+}
+
+func (s *MemBackedDBTestSuite) TestSubscribeBlocks() {
+	dbInst, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	s.Require().NotNil(dbInst)
+	ch1, unsubscribe1 := dbInst.SubscribeBlocks()
+	ch2, unsubscribe2 := dbInst.SubscribeBlocks()
+	s.Require().NoError(dbInst.PutBlock(*s.b00))
+	s.Require().Equal(s.b00.Hash, (<-ch1).Hash)
+	s.Require().Equal(s.b00.Hash, (<-ch2).Hash)
+	// Blocks failed to be put are not notified.
+	s.Require().Equal(ErrBlockExists, dbInst.PutBlock(*s.b00))
+	unsubscribe2()
+	unsubscribe2()
+	_, ok := <-ch2
+	s.Require().False(ok)
+	s.Require().NoError(dbInst.PutBlock(*s.b01))
+	s.Require().Equal(s.b01.Hash, (<-ch1).Hash)
+	// Blocks are dropped for slow subscribers.
+	for i := 0; i < blockSubscriberBufferSize+1; i++ {
+		s.Require().NoError(dbInst.PutBlock(types.Block{
+			Hash: common.NewRandomHash(),
+		}))
+	}
+	s.Require().Len(ch1, blockSubscriberBufferSize)
+	s.Require().Equal(uint64(1), dbInst.DroppedBlockNotifications())
+	unsubscribe1()
+}
+
 func TestMemBackedDB(t *testing.T) {
 	suite.Run(t, new(MemBackedDBTestSuite))
 }
+
+func BenchmarkMemBackedDBPersistJSON(b *testing.B) {
+	benchmarkMemBackedDBPersist(b, PersistFormatJSON)
+}
+
+func BenchmarkMemBackedDBPersistBinary(b *testing.B) {
+	benchmarkMemBackedDBPersist(b, PersistFormatBinary)
+}
+
+func benchmarkMemBackedDBPersist(b *testing.B, format PersistFormat) {
+	dbPath := fmt.Sprintf("test-bench-persist-%d.db", format)
+	defer os.Remove(dbPath)
+	dbInst, err := NewMemBackedDBWithFormat(format, dbPath)
+	if err != nil {
+		panic(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if err = dbInst.PutBlock(types.Block{
+			Hash:      common.NewRandomHash(),
+			Position:  types.Position{Height: uint64(i)},
+			Timestamp: time.Now().UTC(),
+			Payload:   make([]byte, 256),
+		}); err != nil {
+			panic(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = dbInst.Close(); err != nil {
+			panic(err)
+		}
+		if _, err = NewMemBackedDBWithFormat(format, dbPath); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func BenchmarkMemBackedDBConcurrentAccess(b *testing.B) {
+	benchmarkMemBackedDBConcurrentAccess(b, 1)
+}
+
+func BenchmarkMemBackedDBConcurrentAccessSharded(b *testing.B) {
+	benchmarkMemBackedDBConcurrentAccess(b, 64)
+}
+
+// benchmarkMemBackedDBConcurrentAccess puts one block and gets 4 blocks in
+// each iteration from all goroutines.
+func benchmarkMemBackedDBConcurrentAccess(b *testing.B, shards int) {
+	dbInst, err := NewMemBackedDBWithShards(PersistFormatJSON, shards)
+	if err != nil {
+		panic(err)
+	}
+	hashes := make(common.Hashes, 1024)
+	for i := range hashes {
+		hashes[i] = common.NewRandomHash()
+		if err = dbInst.PutBlock(types.Block{Hash: hashes[i]}); err != nil {
+			panic(err)
+		}
+	}
+	// Hashes of new blocks are derived from a counter, instead of the random
+	// source guarded by a global lock.
+	var seq uint64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			var hash common.Hash
+			binary.LittleEndian.PutUint64(hash[:], atomic.AddUint64(&seq, 1))
+			if err := dbInst.PutBlock(types.Block{Hash: hash}); err != nil {
+				panic(err)
+			}
+			for j := 0; j < 4; j++ {
+				if _, err := dbInst.GetBlock(
+					hashes[(i+j)%len(hashes)]); err != nil {
+					panic(err)
+				}
+			}
+			i += 4
+		}
+	})
+}
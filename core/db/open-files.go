@@ -0,0 +1,64 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"sync"
+)
+
+var (
+	openFilesLock sync.Mutex
+	openFiles     int
+	maxOpenFiles  int
+)
+
+// SetMaxOpenFiles limits the count of file-backed databases opened at the
+// same time, opening more of them fails with ErrTooManyOpenFiles until some
+// of them are closed. 0 means unlimited, which is the default.
+func SetMaxOpenFiles(n int) {
+	openFilesLock.Lock()
+	defer openFilesLock.Unlock()
+	maxOpenFiles = n
+}
+
+// openFileSlot is taken by a file-backed database until it's closed.
+type openFileSlot struct {
+	once sync.Once
+}
+
+func acquireOpenFileSlot() (*openFileSlot, error) {
+	openFilesLock.Lock()
+	defer openFilesLock.Unlock()
+	if maxOpenFiles > 0 && openFiles >= maxOpenFiles {
+		return nil, ErrTooManyOpenFiles
+	}
+	openFiles++
+	return &openFileSlot{}, nil
+}
+
+// release returns the slot, it's safe to call it more than once or on nil.
+func (s *openFileSlot) release() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		openFilesLock.Lock()
+		defer openFilesLock.Unlock()
+		openFiles--
+	})
+}
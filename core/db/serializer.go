@@ -0,0 +1,113 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// persistMagic is the header of files persisted by MemBackedDB, followed by
+// one byte of version and one byte of PersistFormat.
+var persistMagic = []byte("DEXDB")
+
+const persistVersion byte = 1
+
+// PersistFormat is the format MemBackedDB persists its content into file.
+type PersistFormat byte
+
+// Supported persist formats.
+const (
+	PersistFormatJSON PersistFormat = iota
+	PersistFormatBinary
+)
+
+// serializer encodes and decodes the content of MemBackedDB.
+type serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v interface{}) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func newSerializer(format PersistFormat) (serializer, error) {
+	switch format {
+	case PersistFormatJSON:
+		return jsonSerializer{}, nil
+	case PersistFormatBinary:
+		return gobSerializer{}, nil
+	}
+	return nil, ErrUnknownPersistFormat
+}
+
+// encodePersisted encodes v in format with the header prepended.
+func encodePersisted(format PersistFormat, v interface{}) ([]byte, error) {
+	s, err := newSerializer(format)
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(persistMagic)+2+len(body))
+	buf = append(buf, persistMagic...)
+	buf = append(buf, persistVersion, byte(format))
+	return append(buf, body...), nil
+}
+
+// decodePersisted decodes data into v by the format recorded in the header,
+// data without the header is treated as JSON for files persisted before the
+// header is introduced.
+func decodePersisted(data []byte, v interface{}) error {
+	if !bytes.HasPrefix(data, persistMagic) {
+		return jsonSerializer{}.Unmarshal(data, v)
+	}
+	data = data[len(persistMagic):]
+	if len(data) < 2 || data[0] != persistVersion {
+		return ErrUnknownPersistFormat
+	}
+	s, err := newSerializer(PersistFormat(data[1]))
+	if err != nil {
+		return err
+	}
+	return s.Unmarshal(data[2:], v)
+}
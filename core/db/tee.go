@@ -0,0 +1,297 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+// TeeWriteError is the error when writing to any backend of TeeDB fails, the
+// error of the backend which succeeded is nil.
+type TeeWriteError struct {
+	Primary   error
+	Secondary error
+}
+
+func (e *TeeWriteError) Error() string {
+	return fmt.Sprintf("tee write failed, primary: %v, secondary: %v",
+		e.Primary, e.Secondary)
+}
+
+// newTeeWriteError aggregates errors from both backends, nil is returned when
+// both succeeded.
+func newTeeWriteError(primary, secondary error) error {
+	if primary == nil && secondary == nil {
+		return nil
+	}
+	return &TeeWriteError{Primary: primary, Secondary: secondary}
+}
+
+// TeeDB is a database mirroring writes to two backends. Reads are served by
+// the primary one, and by the secondary one when the primary one fails, ex.
+// the block is not found in the primary one.
+type TeeDB struct {
+	primary   Database
+	secondary Database
+}
+
+// NewTeeDB constructs a TeeDB on two backends. A failed write to either of
+// them is reported as TeeWriteError, and TeeDB is still usable after that.
+func NewTeeDB(primary, secondary Database) *TeeDB {
+	return &TeeDB{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+// HasBlock implements the Reader.HasBlock method.
+func (t *TeeDB) HasBlock(hash common.Hash) bool {
+	return t.primary.HasBlock(hash) || t.secondary.HasBlock(hash)
+}
+
+// GetBlock implements the Reader.GetBlock method.
+func (t *TeeDB) GetBlock(hash common.Hash) (types.Block, error) {
+	block, err := t.primary.GetBlock(hash)
+	if err == nil {
+		return block, nil
+	}
+	if block, sErr := t.secondary.GetBlock(hash); sErr == nil {
+		return block, nil
+	}
+	return types.Block{}, err
+}
+
+// GetAllBlocks implements Reader.GetAllBlocks method, blocks are iterated
+// from the primary backend only.
+func (t *TeeDB) GetAllBlocks() (BlockIterator, error) {
+	return t.primary.GetAllBlocks()
+}
+
+// GetBlocksByTimeRange implements Reader.GetBlocksByTimeRange method, blocks
+// are iterated from the primary backend only.
+func (t *TeeDB) GetBlocksByTimeRange(
+	start, end time.Time) (BlockIterator, error) {
+	return t.primary.GetBlocksByTimeRange(start, end)
+}
+
+// GetFinalizedBlocks implements Reader.GetFinalizedBlocks method, blocks are
+// iterated from the primary backend only.
+func (t *TeeDB) GetFinalizedBlocks() (BlockIterator, error) {
+	return t.primary.GetFinalizedBlocks()
+}
+
+// GetCompactionChainTipInfo implements Reader.GetCompactionChainTipInfo
+// method, the secondary backend is used when the compaction chain in the
+// primary one is empty.
+func (t *TeeDB) GetCompactionChainTipInfo() (common.Hash, uint64) {
+	hash, height := t.primary.GetCompactionChainTipInfo()
+	if (hash == common.Hash{}) {
+		return t.secondary.GetCompactionChainTipInfo()
+	}
+	return hash, height
+}
+
+// GetCompactionChainTipBlock implements Reader.GetCompactionChainTipBlock
+// method.
+func (t *TeeDB) GetCompactionChainTipBlock() (*types.Block, error) {
+	block, err := t.primary.GetCompactionChainTipBlock()
+	if err == nil {
+		return block, nil
+	}
+	if block, sErr := t.secondary.GetCompactionChainTipBlock(); sErr == nil {
+		return block, nil
+	}
+	return nil, err
+}
+
+// GetCompactionChainReorgs implements Reader.GetCompactionChainReorgs method.
+func (t *TeeDB) GetCompactionChainReorgs() ([]CompactionChainReorg, error) {
+	reorgs, err := t.primary.GetCompactionChainReorgs()
+	if err == nil {
+		return reorgs, nil
+	}
+	if reorgs, sErr := t.secondary.GetCompactionChainReorgs(); sErr == nil {
+		return reorgs, nil
+	}
+	return nil, err
+}
+
+// GetDKGPrivateKey implements the Reader.GetDKGPrivateKey method.
+func (t *TeeDB) GetDKGPrivateKey(round, reset uint64) (
+	dkg.PrivateKey, error) {
+	prv, err := t.primary.GetDKGPrivateKey(round, reset)
+	if err == nil {
+		return prv, nil
+	}
+	if prv, sErr := t.secondary.GetDKGPrivateKey(round, reset); sErr == nil {
+		return prv, nil
+	}
+	return dkg.PrivateKey{}, err
+}
+
+// HasDKGPrivateKeyInRange implements the Reader.HasDKGPrivateKeyInRange
+// method, a round is present when it's present in either backend.
+func (t *TeeDB) HasDKGPrivateKeyInRange(
+	low, high uint64) (map[uint64]bool, error) {
+	ret, err := t.primary.HasDKGPrivateKeyInRange(low, high)
+	if err != nil {
+		return t.secondary.HasDKGPrivateKeyInRange(low, high)
+	}
+	secondary, err := t.secondary.HasDKGPrivateKeyInRange(low, high)
+	if err != nil {
+		return ret, nil
+	}
+	for round, exists := range secondary {
+		if exists {
+			ret[round] = true
+		}
+	}
+	return ret, nil
+}
+
+// GetDKGProtocol implements the Reader.GetDKGProtocol method.
+func (t *TeeDB) GetDKGProtocol() (DKGProtocolInfo, error) {
+	info, err := t.primary.GetDKGProtocol()
+	if err == nil {
+		return info, nil
+	}
+	if info, sErr := t.secondary.GetDKGProtocol(); sErr == nil {
+		return info, nil
+	}
+	return DKGProtocolInfo{}, err
+}
+
+// GetCRS implements the Reader.GetCRS method.
+func (t *TeeDB) GetCRS(round uint64) (common.Hash, error) {
+	crs, err := t.primary.GetCRS(round)
+	if err == nil {
+		return crs, nil
+	}
+	if crs, sErr := t.secondary.GetCRS(round); sErr == nil {
+		return crs, nil
+	}
+	return common.Hash{}, err
+}
+
+// GetPartialSignatures implements the Reader.GetPartialSignatures method.
+func (t *TeeDB) GetPartialSignatures() ([]typesDKG.PartialSignature, error) {
+	psigs, err := t.primary.GetPartialSignatures()
+	if err == nil {
+		return psigs, nil
+	}
+	if psigs, sErr := t.secondary.GetPartialSignatures(); sErr == nil {
+		return psigs, nil
+	}
+	return nil, err
+}
+
+// GetBlockLabel implements the Reader.GetBlockLabel method.
+func (t *TeeDB) GetBlockLabel(hash common.Hash) (string, bool, error) {
+	label, exists, err := t.primary.GetBlockLabel(hash)
+	if err == nil {
+		return label, exists, nil
+	}
+	if label, exists, sErr := t.secondary.GetBlockLabel(hash); sErr == nil {
+		return label, exists, nil
+	}
+	return "", false, err
+}
+
+// UpdateBlock implements the Writer.UpdateBlock method.
+func (t *TeeDB) UpdateBlock(block types.Block) error {
+	return newTeeWriteError(
+		t.primary.UpdateBlock(block), t.secondary.UpdateBlock(block))
+}
+
+// PutBlock implements the Writer.PutBlock method.
+func (t *TeeDB) PutBlock(block types.Block) error {
+	return newTeeWriteError(
+		t.primary.PutBlock(block), t.secondary.PutBlock(block))
+}
+
+// PutCompactionChainTipInfo implements the Writer.PutCompactionChainTipInfo
+// method.
+func (t *TeeDB) PutCompactionChainTipInfo(
+	blockHash common.Hash, height uint64) error {
+	return newTeeWriteError(
+		t.primary.PutCompactionChainTipInfo(blockHash, height),
+		t.secondary.PutCompactionChainTipInfo(blockHash, height))
+}
+
+// ReorgCompactionChainTip implements the Writer.ReorgCompactionChainTip
+// method.
+func (t *TeeDB) ReorgCompactionChainTip(
+	hash common.Hash, height uint64, confirmed bool) error {
+	return newTeeWriteError(
+		t.primary.ReorgCompactionChainTip(hash, height, confirmed),
+		t.secondary.ReorgCompactionChainTip(hash, height, confirmed))
+}
+
+// PutDKGPrivateKey implements the Writer.PutDKGPrivateKey method.
+func (t *TeeDB) PutDKGPrivateKey(
+	round, reset uint64, prv dkg.PrivateKey) error {
+	return newTeeWriteError(
+		t.primary.PutDKGPrivateKey(round, reset, prv),
+		t.secondary.PutDKGPrivateKey(round, reset, prv))
+}
+
+// PutOrUpdateDKGProtocol implements the Writer.PutOrUpdateDKGProtocol method.
+func (t *TeeDB) PutOrUpdateDKGProtocol(info DKGProtocolInfo) error {
+	return newTeeWriteError(
+		t.primary.PutOrUpdateDKGProtocol(info),
+		t.secondary.PutOrUpdateDKGProtocol(info))
+}
+
+// PutCRS implements the Writer.PutCRS method.
+func (t *TeeDB) PutCRS(round uint64, crs common.Hash) error {
+	return newTeeWriteError(
+		t.primary.PutCRS(round, crs), t.secondary.PutCRS(round, crs))
+}
+
+// PutPartialSignatures implements the Writer.PutPartialSignatures method.
+func (t *TeeDB) PutPartialSignatures(round uint64, hash common.Hash,
+	psigs []typesDKG.PartialSignature) error {
+	return newTeeWriteError(
+		t.primary.PutPartialSignatures(round, hash, psigs),
+		t.secondary.PutPartialSignatures(round, hash, psigs))
+}
+
+// AddPartialSignature implements the Writer.AddPartialSignature method.
+func (t *TeeDB) AddPartialSignature(psig typesDKG.PartialSignature) error {
+	return newTeeWriteError(
+		t.primary.AddPartialSignature(psig),
+		t.secondary.AddPartialSignature(psig))
+}
+
+// SetBlockLabel implements the Writer.SetBlockLabel method.
+func (t *TeeDB) SetBlockLabel(hash common.Hash, label string) error {
+	return newTeeWriteError(
+		t.primary.SetBlockLabel(hash, label),
+		t.secondary.SetBlockLabel(hash, label))
+}
+
+// Close implements Database.Close method, both backends are closed.
+func (t *TeeDB) Close() error {
+	return newTeeWriteError(t.primary.Close(), t.secondary.Close())
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"testing"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type TeeDBTestSuite struct {
+	suite.Suite
+}
+
+func (s *TeeDBTestSuite) newTeeDB() (Database, *MemBackedDB, *MemBackedDB) {
+	primary, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	secondary, err := NewMemBackedDB()
+	s.Require().NoError(err)
+	return NewTeeDB(primary, secondary), primary, secondary
+}
+
+func (s *TeeDBTestSuite) TestWriteFanOut() {
+	dbInst, primary, secondary := s.newTeeDB()
+	b0 := types.Block{Hash: common.NewRandomHash()}
+	s.Require().NoError(dbInst.PutBlock(b0))
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(b0.Hash, 1))
+	crs := common.NewRandomHash()
+	s.Require().NoError(dbInst.PutCRS(0, crs))
+	s.Require().NoError(dbInst.SetBlockLabel(b0.Hash, "b0"))
+	for _, backend := range []*MemBackedDB{primary, secondary} {
+		s.Require().True(backend.HasBlock(b0.Hash))
+		hash, height := backend.GetCompactionChainTipInfo()
+		s.Require().Equal(b0.Hash, hash)
+		s.Require().Equal(uint64(1), height)
+		backendCRS, err := backend.GetCRS(0)
+		s.Require().NoError(err)
+		s.Require().Equal(crs, backendCRS)
+		label, exists, err := backend.GetBlockLabel(b0.Hash)
+		s.Require().NoError(err)
+		s.Require().True(exists)
+		s.Require().Equal("b0", label)
+	}
+	// Updates are mirrored too.
+	b0.Payload = []byte("updated")
+	s.Require().NoError(dbInst.UpdateBlock(b0))
+	for _, backend := range []*MemBackedDB{primary, secondary} {
+		b, err := backend.GetBlock(b0.Hash)
+		s.Require().NoError(err)
+		s.Require().Equal(b0.Payload, b.Payload)
+	}
+}
+
+func (s *TeeDBTestSuite) TestReadFallback() {
+	dbInst, primary, secondary := s.newTeeDB()
+	// Only the secondary backend has the block and CRS, ex. it's the old
+	// database in a live migration.
+	b0 := types.Block{Hash: common.NewRandomHash(), Payload: []byte("b0")}
+	s.Require().NoError(secondary.PutBlock(b0))
+	crs := common.NewRandomHash()
+	s.Require().NoError(secondary.PutCRS(0, crs))
+	s.Require().True(dbInst.HasBlock(b0.Hash))
+	b, err := dbInst.GetBlock(b0.Hash)
+	s.Require().NoError(err)
+	s.Require().Equal(b0.Payload, b.Payload)
+	dbCRS, err := dbInst.GetCRS(0)
+	s.Require().NoError(err)
+	s.Require().Equal(crs, dbCRS)
+	// The primary backend is preferred when both have it.
+	b0.Payload = []byte("primary")
+	s.Require().NoError(primary.PutBlock(b0))
+	b, err = dbInst.GetBlock(b0.Hash)
+	s.Require().NoError(err)
+	s.Require().Equal(b0.Payload, b.Payload)
+	// The error of the primary backend is returned on a miss of both.
+	_, err = dbInst.GetBlock(common.NewRandomHash())
+	s.Require().Equal(ErrBlockDoesNotExist, err)
+	s.Require().False(dbInst.HasBlock(common.NewRandomHash()))
+	_, err = dbInst.GetCRS(1)
+	s.Require().Equal(ErrCRSDoesNotExist, err)
+}
+
+func (s *TeeDBTestSuite) TestPartialWriteError() {
+	dbInst, primary, secondary := s.newTeeDB()
+	secondary.SetReadOnly(true)
+	b0 := types.Block{Hash: common.NewRandomHash()}
+	err := dbInst.PutBlock(b0)
+	s.Require().Error(err)
+	teeErr, ok := err.(*TeeWriteError)
+	s.Require().True(ok)
+	s.Require().NoError(teeErr.Primary)
+	s.Require().Equal(ErrDBReadOnly, teeErr.Secondary)
+	// The write to the primary backend is kept.
+	s.Require().True(primary.HasBlock(b0.Hash))
+	s.Require().False(secondary.HasBlock(b0.Hash))
+	s.Require().True(dbInst.HasBlock(b0.Hash))
+	// It's still usable once the secondary backend is writable again.
+	secondary.SetReadOnly(false)
+	b1 := types.Block{Hash: common.NewRandomHash()}
+	s.Require().NoError(dbInst.PutBlock(b1))
+	s.Require().True(secondary.HasBlock(b1.Hash))
+	// Errors from both backends are aggregated.
+	err = dbInst.PutBlock(b1)
+	teeErr, ok = err.(*TeeWriteError)
+	s.Require().True(ok)
+	s.Require().Equal(ErrBlockExists, teeErr.Primary)
+	s.Require().Equal(ErrBlockExists, teeErr.Secondary)
+	s.Require().NoError(dbInst.Close())
+}
+
+func TestTeeDB(t *testing.T) {
+	suite.Run(t, new(TeeDBTestSuite))
+}
@@ -0,0 +1,134 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"errors"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+)
+
+// Errors for block validators.
+var (
+	// ErrTimestampNotMonotonic means the timestamp of a block is earlier than
+	// its parent's.
+	ErrTimestampNotMonotonic = errors.New("timestamp not monotonic")
+	// ErrInvalidBlockSignature means the signature of a block is not signed by
+	// its proposer.
+	ErrInvalidBlockSignature = errors.New("invalid block signature")
+)
+
+// BlockValidator checks a block before it's put into the database.
+type BlockValidator func(b *types.Block) error
+
+// checkBlockHash makes sure the hash of a block is the one computed from its
+// content.
+func checkBlockHash(b *types.Block) error {
+	hash, err := utils.HashBlock(b)
+	if err != nil {
+		return err
+	}
+	if hash != b.Hash {
+		return ErrBlockHashMismatch
+	}
+	return nil
+}
+
+// checkBlockSize makes sure the size of payload and witness data of a block
+// doesn't exceed the limit, no limit is applied when it's not positive.
+func checkBlockSize(b *types.Block, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	if len(b.Payload)+len(b.Witness.Data) > limit {
+		return ErrBlockTooLarge
+	}
+	return nil
+}
+
+// ComposeBlockValidators combines validators into one, which returns the
+// error from the first failed validator.
+func ComposeBlockValidators(validators ...BlockValidator) BlockValidator {
+	return func(b *types.Block) error {
+		for _, v := range validators {
+			if v == nil {
+				continue
+			}
+			if err := v(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// TimestampMonotonicValidator returns a validator which makes sure the
+// timestamp of a block is not earlier than its parent's. The check is skipped
+// when the parent could not be found by getParent.
+func TimestampMonotonicValidator(
+	getParent func(common.Hash) (*types.Block, bool)) BlockValidator {
+	return func(b *types.Block) error {
+		parent, exist := getParent(b.ParentHash)
+		if !exist {
+			return nil
+		}
+		if b.Timestamp.Before(parent.Timestamp) {
+			return ErrTimestampNotMonotonic
+		}
+		return nil
+	}
+}
+
+// SignatureValidator returns a validator which rejects blocks failed to be
+// verified by verify with ErrInvalidBlockSignature.
+func SignatureValidator(verify func(types.Block) error) BlockValidator {
+	return func(b *types.Block) error {
+		if err := verify(*b); err != nil {
+			return ErrInvalidBlockSignature
+		}
+		return nil
+	}
+}
+
+// ProposerSignatureVerifier returns a verifier for SignatureValidator, which
+// checks the hash of a block is computed from its content and signed by the
+// public key of its proposer. The public key of a proposer is provided by
+// resolve, blocks from unknown proposers are rejected.
+func ProposerSignatureVerifier(
+	resolve func(types.NodeID) (crypto.PublicKey, bool)) func(
+	types.Block) error {
+	return func(b types.Block) error {
+		if err := checkBlockHash(&b); err != nil {
+			return err
+		}
+		pubKey, exist := resolve(b.ProposerID)
+		if !exist {
+			return ErrInvalidBlockSignature
+		}
+		if !b.ProposerID.Equal(types.NewNodeID(pubKey)) {
+			return ErrInvalidBlockSignature
+		}
+		if !pubKey.VerifySignature(b.Hash, b.Signature) {
+			return ErrInvalidBlockSignature
+		}
+		return nil
+	}
+}
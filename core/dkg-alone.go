@@ -0,0 +1,108 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+// isLoneDKGParticipant checks if this node is the only member of the notary
+// set of the registered DKG, it should be called with cc.dkgLock held.
+func (cc *configurationChain) isLoneDKGParticipant() bool {
+	_, exist := cc.notarySet[cc.ID]
+	return exist && len(cc.notarySet) == 1
+}
+
+// runDKGAlone runs the remaining DKG phases one after another without
+// waiting for block heights, as a lone participant has nobody to wait for.
+// The artifacts are produced by the same phases as runDKG, so they verify
+// the same way. It should be called with cc.dkgLock held.
+func (cc *configurationChain) runDKGAlone(round, reset uint64) error {
+	cc.dkg.proposeMPKReady()
+	for cc.dkg.step < len(cc.dkgRunPhases) {
+		select {
+		case <-cc.dkgCtx.Done():
+			return ErrDKGAborted
+		default:
+		}
+		err := cc.dkgRunPhases[cc.dkg.step](round, reset)
+		if err != nil && err != ErrSkipButNoError {
+			return err
+		}
+		if cc.mpkReady {
+			// The private share to self is sent through dkgReceiver in
+			// background, don't let nack complaints be proposed before it
+			// arrives.
+			if err = cc.dkg.receiveSelfPrivateShare(); err != nil {
+				return err
+			}
+		}
+		cc.dkg.step++
+		if err = cc.db.PutOrUpdateDKGProtocol(
+			cc.dkg.toDKGProtocolInfo()); err != nil {
+			cc.logger.Error("Failed to save DKG Protocol",
+				"step", cc.dkg.step,
+				"error", err)
+		}
+	}
+	return nil
+}
+
+// receiveSelfPrivateShare receives the private share to self in place, it's
+// trusted and already verified against the master public key of self.
+func (d *dkgProtocol) receiveSelfPrivateShare() error {
+	if _, exist := d.prvSharesReceived[d.ID]; exist {
+		return nil
+	}
+	id := d.idMap[d.ID]
+	share, ok := d.masterPrivateShare.Share(id)
+	if !ok {
+		return ErrUnableGetSelfPrvShare
+	}
+	if err := d.prvShares.AddShare(id, share); err != nil {
+		return err
+	}
+	d.prvSharesReceived[d.ID] = struct{}{}
+	return nil
+}
+
+// runTSigAlone signs the hash when this node is the only qualified node of
+// the round, the threshold signature is recovered from its own partial
+// signature without collecting others. ok is false when it's not the case.
+func (cc *configurationChain) runTSigAlone(
+	npks *typesDKG.NodePublicKeys, round uint64, hash common.Hash) (
+	signature crypto.Signature, ok bool, err error) {
+	id, exist := npks.IDMap[cc.ID]
+	if !exist || len(npks.QualifyIDs) != 1 || npks.Threshold != 1 {
+		return
+	}
+	psig, err := cc.preparePartialSignature(round, hash)
+	if err != nil {
+		return
+	}
+	ok = true
+	signature, err = dkg.RecoverSignature(
+		[]dkg.PartialSignature{psig.PartialSignature}, dkg.IDs{id})
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	cc.purgePendingPsig(hash)
+	return
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+// VerificationBundle carries the result of a finished DKG, enough for parties
+// not participating in that DKG to verify partial and threshold signatures.
+type VerificationBundle struct {
+	Round          uint64
+	Threshold      int
+	GroupPublicKey *dkg.PublicKey
+	// PublicKeyShares is keyed by the qualified nodes.
+	PublicKeyShares map[types.NodeID]*dkg.PublicKey
+}
+
+type verificationBundleJSON struct {
+	Round           uint64                  `json:"round"`
+	Threshold       int                     `json:"threshold"`
+	GroupPublicKey  []byte                  `json:"group_public_key"`
+	PublicKeyShares map[types.NodeID][]byte `json:"public_key_shares"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b *VerificationBundle) MarshalJSON() ([]byte, error) {
+	enc := verificationBundleJSON{
+		Round:           b.Round,
+		Threshold:       b.Threshold,
+		GroupPublicKey:  b.GroupPublicKey.Serialize(),
+		PublicKeyShares: make(map[types.NodeID][]byte, len(b.PublicKeyShares)),
+	}
+	for nID, pubKey := range b.PublicKeyShares {
+		enc.PublicKeyShares[nID] = pubKey.Serialize()
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *VerificationBundle) UnmarshalJSON(data []byte) error {
+	var dec verificationBundleJSON
+	if err := json.Unmarshal(data, &dec); err != nil {
+		return err
+	}
+	groupPubKey := &dkg.PublicKey{}
+	if err := groupPubKey.Deserialize(dec.GroupPublicKey); err != nil {
+		return err
+	}
+	pubKeys := make(map[types.NodeID]*dkg.PublicKey, len(dec.PublicKeyShares))
+	for nID, raw := range dec.PublicKeyShares {
+		pubKey := &dkg.PublicKey{}
+		if err := pubKey.Deserialize(raw); err != nil {
+			return err
+		}
+		pubKeys[nID] = pubKey
+	}
+	*b = VerificationBundle{
+		Round:           dec.Round,
+		Threshold:       dec.Threshold,
+		GroupPublicKey:  groupPubKey,
+		PublicKeyShares: pubKeys,
+	}
+	return nil
+}
+
+// VerifyPartialSignature checks that the partial signature is signed by a
+// qualified node of this bundle.
+func (b *VerificationBundle) VerifyPartialSignature(
+	psig *typesDKG.PartialSignature) bool {
+	if psig.Round != b.Round {
+		return false
+	}
+	pubKey, exist := b.PublicKeyShares[psig.ProposerID]
+	if !exist {
+		return false
+	}
+	return pubKey.VerifySignature(
+		psig.Hash, crypto.Signature(psig.PartialSignature))
+}
+
+// VerifyThresholdSignature checks that the signature is recovered from
+// partial signatures of this group.
+func (b *VerificationBundle) VerifyThresholdSignature(
+	hash common.Hash, sig crypto.Signature) bool {
+	return b.GroupPublicKey.VerifySignature(hash, sig)
+}
+
+// ExportVerificationBundle exports the qualified group of a finished DKG as a
+// VerificationBundle.
+func (cc *configurationChain) ExportVerificationBundle(
+	round uint64) (*VerificationBundle, error) {
+	npks, _, err := cc.getDKGInfo(round, true)
+	if err != nil {
+		return nil, err
+	}
+	shares := make(map[types.NodeID]*dkg.PublicKey, len(npks.IDMap))
+	for nID := range npks.IDMap {
+		shares[nID] = npks.PublicKeys[nID]
+	}
+	groupPubKey, err := cc.groupPublicKey(round, npks)
+	if err != nil {
+		return nil, err
+	}
+	return &VerificationBundle{
+		Round:           round,
+		Threshold:       npks.Threshold,
+		GroupPublicKey:  groupPubKey,
+		PublicKeyShares: shares,
+	}, nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+)
+
+// DisqualificationPolicy decides the disqualified participants of the DKG of
+// one round. All nodes should apply the same policy, or they would end up
+// with different qualified sets.
+type DisqualificationPolicy interface {
+	// Disqualify returns the disqualified participants from the complaints
+	// and the equivocations of master public keys in governance. Only
+	// evidences every node reads the same from governance are provided,
+	// equivocations of private shares detected locally are not.
+	Disqualify(round uint64, complaints []*typesDKG.Complaint,
+		equivocations []Equivocation, threshold int) map[types.NodeID]struct{}
+}
+
+// DefaultDisqualificationPolicy disqualifies a participant complained with a
+// revealed private share, or complained by at least threshold participants.
+// Equivocations are ignored.
+type DefaultDisqualificationPolicy struct{}
+
+// Disqualify implements DisqualificationPolicy interface.
+func (DefaultDisqualificationPolicy) Disqualify(_ uint64,
+	complaints []*typesDKG.Complaint, _ []Equivocation,
+	threshold int) map[types.NodeID]struct{} {
+	return typesDKG.CalcDisqualifyNodes(complaints, threshold)
+}
+
+// SetDisqualificationPolicy replaces the policy to decide the disqualified
+// participants of DKG, it's not thread-safe and should be called before any
+// DKG is registered.
+func (cc *configurationChain) SetDisqualificationPolicy(
+	policy DisqualificationPolicy) {
+	cc.disqualifyPolicy = policy
+}
+
+// disqualified returns the participants disqualified by the policy from the
+// complaints and master public keys in governance.
+func (cc *configurationChain) disqualified(
+	round uint64, threshold int) map[types.NodeID]struct{} {
+	cc.logger.Debug("Calling Governance.DKGComplaints", "round", round)
+	return cc.disqualifyPolicy.Disqualify(round,
+		cc.gov.DKGComplaints(round), cc.governanceEquivocations(round),
+		threshold)
+}
+
+// governanceEquivocations returns the equivocations of master public keys in
+// governance of round, which are the same for all nodes.
+func (cc *configurationChain) governanceEquivocations(
+	round uint64) []Equivocation {
+	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys", "round", round)
+	return masterPublicKeysEquivocations(cc.gov.DKGMasterPublicKeys(round))
+}
+
+// SimulateQualification returns the participants qualified and disqualified
+// in the DKG of round, as if hypotheticalComplaints were added to the
+// complaints in governance. The qualification is calculated like the real one
+// with the policy, so participants excluded for another threshold are
+// disqualified as well. The master public keys in governance and their
+// equivocations are used as they are, and nothing is changed. Both results
+// are sorted, typesDKG.ErrNotReachThreshold is returned when too few
+// participants would be qualified.
+func (cc *configurationChain) SimulateQualification(
+	round uint64, hypotheticalComplaints []*typesDKG.Complaint) (
+	qualified, disqualified types.NodeIDs, err error) {
+	threshold, ok := cc.dkgThreshold(round)
+	if !ok {
+		err = utils.ErrConfigurationNotReady
+		return
+	}
+	cc.logger.Debug("Calling Governance.DKGComplaints", "round", round)
+	// Copy the complaints in governance so appending never writes into them.
+	complaints := append(append([]*typesDKG.Complaint(nil),
+		cc.gov.DKGComplaints(round)...), hypotheticalComplaints...)
+	disqualifyIDs := cc.disqualifyPolicy.Disqualify(round, complaints,
+		cc.governanceEquivocations(round), threshold)
+	mpks := cc.gov.DKGMasterPublicKeys(round)
+	_, qualifyNodeIDs, err := typesDKG.CalcQualifyNodesWithDisqualified(
+		mpks, disqualifyIDs, threshold)
+	if err != nil {
+		return
+	}
+	if cfg := cc.gov.Configuration(round); cfg != nil &&
+		len(qualifyNodeIDs) < utils.GetDKGValidThreshold(cfg) {
+		err = typesDKG.ErrNotReachThreshold
+		return
+	}
+	proposers := make(map[types.NodeID]struct{})
+	for _, mpk := range mpks {
+		if _, exist := proposers[mpk.ProposerID]; exist {
+			continue
+		}
+		proposers[mpk.ProposerID] = struct{}{}
+		if _, exist := qualifyNodeIDs[mpk.ProposerID]; exist {
+			qualified = append(qualified, mpk.ProposerID)
+		} else {
+			disqualified = append(disqualified, mpk.ProposerID)
+		}
+	}
+	qualified.Sort()
+	disqualified.Sort()
+	return
+}
+
+// dkgThreshold returns the threshold of the DKG of round, from the DKG result
+// or the registered DKG of that round if any, or from the configuration.
+func (cc *configurationChain) dkgThreshold(round uint64) (int, bool) {
+	if npks := func() *typesDKG.NodePublicKeys {
+		cc.dkgResult.RLock()
+		defer cc.dkgResult.RUnlock()
+		return cc.npks[round]
+	}(); npks != nil {
+		return npks.Threshold, true
+	}
+	if threshold := func() int {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		if cc.dkg == nil || cc.dkg.round != round {
+			return 0
+		}
+		return cc.dkg.threshold
+	}(); threshold > 0 {
+		return threshold, true
+	}
+	cfg := cc.gov.Configuration(round)
+	if cfg == nil {
+		return 0, false
+	}
+	return utils.GetDKGThreshold(cfg), true
+}
+
+// DiffQualification returns the participants qualified in the DKG of roundB
+// but not roundA as added, and the ones qualified in roundA but not roundB as
+// removed. Both results are sorted. The DKG results of both rounds are
+// recovered from governance when not cached, and an error is returned if
+// either of them is not available.
+func (cc *configurationChain) DiffQualification(roundA, roundB uint64) (
+	added, removed types.NodeIDs, err error) {
+	npksA, _, err := cc.getDKGInfo(roundA, true)
+	if err != nil {
+		return
+	}
+	npksB, _, err := cc.getDKGInfo(roundB, true)
+	if err != nil {
+		return
+	}
+	for nID := range npksB.QualifyNodeIDs {
+		if _, exist := npksA.QualifyNodeIDs[nID]; !exist {
+			added = append(added, nID)
+		}
+	}
+	for nID := range npksA.QualifyNodeIDs {
+		if _, exist := npksB.QualifyNodeIDs[nID]; !exist {
+			removed = append(removed, nID)
+		}
+	}
+	added.Sort()
+	removed.Sort()
+	return
+}
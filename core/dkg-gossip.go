@@ -0,0 +1,111 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+type antiNackKey struct {
+	proposerID types.NodeID
+	receiverID types.NodeID
+	reset      uint64
+	// Conflicting complaints of the same share are relayed separately, so
+	// every node could find the equivocation.
+	shareHash common.Hash
+}
+
+// antiNackGossip propagates anti nack complaints to a bounded count of peers
+// instead of all nodes. Nodes are sorted by ID into a ring, and peers are
+// those at offsets 1, 2, 4, ... from this node. The next node is always
+// chosen, so a complaint reaches all nodes when each node forwards it once,
+// which takes at most n * fanout messages for n nodes.
+type antiNackGossip struct {
+	ID     types.NodeID
+	fanout int
+	lock   sync.Mutex
+	seen   map[uint64]map[antiNackKey]struct{}
+}
+
+func newAntiNackGossip(ID types.NodeID, fanout int) *antiNackGossip {
+	return &antiNackGossip{
+		ID:     ID,
+		fanout: fanout,
+		seen:   make(map[uint64]map[antiNackKey]struct{}),
+	}
+}
+
+// markSeen returns false if the complaint is already seen. Complaints of
+// rounds older than the previous one of the newest round are dropped.
+func (g *antiNackGossip) markSeen(prv *typesDKG.PrivateShare) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	seen, exist := g.seen[prv.Round]
+	if !exist {
+		seen = make(map[antiNackKey]struct{})
+		g.seen[prv.Round] = seen
+		for round := range g.seen {
+			if round+1 < prv.Round {
+				delete(g.seen, round)
+			}
+		}
+	}
+	key := antiNackKey{
+		proposerID: prv.ProposerID,
+		receiverID: prv.ReceiverID,
+		reset:      prv.Reset,
+		shareHash:  crypto.Keccak256Hash(prv.PrivateShare.Bytes()),
+	}
+	if _, exist := seen[key]; exist {
+		return false
+	}
+	seen[key] = struct{}{}
+	return true
+}
+
+// peers returns nodes to forward the complaint to, nothing is returned when
+// the complaint is already seen.
+func (g *antiNackGossip) peers(prv *typesDKG.PrivateShare,
+	nodes map[types.NodeID]struct{}) []types.NodeID {
+	if !g.markSeen(prv) {
+		return nil
+	}
+	ring := make(types.NodeIDs, 0, len(nodes))
+	for nID := range nodes {
+		if nID != g.ID {
+			ring = append(ring, nID)
+		}
+	}
+	ring.Sort()
+	// The position of this node in the ring, the node at it is the next one.
+	self := sort.Search(len(ring), func(i int) bool {
+		return bytes.Compare(ring[i].Hash[:], g.ID.Hash[:]) > 0
+	})
+	peers := []types.NodeID{}
+	for offset := 1; offset <= len(ring) && len(peers) < g.fanout; offset *= 2 {
+		peers = append(peers, ring[(self+offset-1)%len(ring)])
+	}
+	return peers
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/dexon-foundation/dexon/rlp"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/db"
+)
+
+// Errors for DKG material.
+var (
+	ErrDKGMaterialDecryption = fmt.Errorf(
+		"DKG material decryption failed")
+)
+
+// dkgMaterialKDFIterations is the iteration count of PBKDF2 to derive the
+// encryption key of DKG material from the password.
+const dkgMaterialKDFIterations = 100000
+
+// dkgMaterialEntry is the DKG private key of one round in DKG material.
+type dkgMaterialEntry struct {
+	Round      uint64
+	Reset      uint64
+	PrivateKey dkg.PrivateKey
+}
+
+// dkgMaterial is the exported DKG material, Data is the RLP encoded entries,
+// which is sealed by AES-GCM when Salt is not empty.
+type dkgMaterial struct {
+	Salt  []byte
+	Nonce []byte
+	Data  []byte
+}
+
+// deriveDKGMaterialKey derives a 32 bytes key from the password by PBKDF2
+// with HMAC-SHA256.
+func deriveDKGMaterialKey(password string, salt []byte) []byte {
+	return pbkdf2.Key(
+		[]byte(password), salt, dkgMaterialKDFIterations, 32, sha256.New)
+}
+
+func newDKGMaterialCipher(password string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveDKGMaterialKey(password, salt))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ExportDKGMaterial exports DKG private keys of all finalized rounds in DB into
+// one blob, which is encrypted by the password unless it's empty.
+func (cc *configurationChain) ExportDKGMaterial(
+	password string) ([]byte, error) {
+	entries := []dkgMaterialEntry{}
+	for round := DKGDelayRound; cc.gov.IsDKGFinal(round); round++ {
+		reset := cc.gov.DKGResetCount(round)
+		prvKey, err := cc.db.GetDKGPrivateKey(round, reset)
+		if err != nil {
+			if err == db.ErrDKGPrivateKeyDoesNotExist {
+				// Not a participant of this round.
+				continue
+			}
+			return nil, err
+		}
+		entries = append(entries, dkgMaterialEntry{
+			Round:      round,
+			Reset:      reset,
+			PrivateKey: prvKey,
+		})
+	}
+	data, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return nil, err
+	}
+	material := dkgMaterial{Data: data}
+	if len(password) > 0 {
+		material.Salt = make([]byte, 16)
+		if _, err = io.ReadFull(rand.Reader, material.Salt); err != nil {
+			return nil, err
+		}
+		aead, err := newDKGMaterialCipher(password, material.Salt)
+		if err != nil {
+			return nil, err
+		}
+		material.Nonce = make([]byte, aead.NonceSize())
+		if _, err = io.ReadFull(rand.Reader, material.Nonce); err != nil {
+			return nil, err
+		}
+		material.Data = aead.Seal(nil, material.Nonce, data, nil)
+	}
+	return rlp.EncodeToBytes(&material)
+}
+
+// ImportDKGMaterial writes DKG private keys in the blob exported by
+// ExportDKGMaterial into DB, keys already in DB are left untouched.
+func (cc *configurationChain) ImportDKGMaterial(
+	blob []byte, password string) error {
+	material := dkgMaterial{}
+	if err := rlp.DecodeBytes(blob, &material); err != nil {
+		return err
+	}
+	data := material.Data
+	if len(material.Salt) > 0 || len(password) > 0 {
+		if len(material.Salt) == 0 || len(password) == 0 {
+			return ErrDKGMaterialDecryption
+		}
+		aead, err := newDKGMaterialCipher(password, material.Salt)
+		if err != nil {
+			return err
+		}
+		if len(material.Nonce) != aead.NonceSize() {
+			return ErrDKGMaterialDecryption
+		}
+		if data, err = aead.Open(
+			nil, material.Nonce, material.Data, nil); err != nil {
+			return ErrDKGMaterialDecryption
+		}
+	}
+	entries := []dkgMaterialEntry{}
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		err := cc.db.PutDKGPrivateKey(e.Round, e.Reset, e.PrivateKey)
+		if err != nil && err != db.ErrDKGPrivateKeyExists {
+			return err
+		}
+	}
+	return nil
+}
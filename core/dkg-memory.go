@@ -0,0 +1,126 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"unsafe"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+// MemoryStats is the approximate memory in bytes held by a
+// configurationChain, only the data of fixed-size elements are counted.
+type MemoryStats struct {
+	// PrivateShares is held by private shares buffered for the running DKG.
+	PrivateShares uint64
+	// PartialSignatures is held by partial signatures buffered or collected
+	// by runTSig.
+	PartialSignatures uint64
+	// Signers is held by cached DKG signers of all rounds.
+	Signers uint64
+	// NodePublicKeys is held by cached node public keys, indexed by round.
+	NodePublicKeys map[uint64]uint64
+}
+
+// Total returns the sum of all estimates.
+func (s MemoryStats) Total() (total uint64) {
+	total = s.PrivateShares + s.PartialSignatures + s.Signers
+	for _, size := range s.NodePublicKeys {
+		total += size
+	}
+	return
+}
+
+var (
+	sizeOfNodeID       = uint64(unsafe.Sizeof(types.NodeID{}))
+	sizeOfDKGID        = uint64(unsafe.Sizeof(dkg.ID{}))
+	sizeOfPointer      = uint64(unsafe.Sizeof(uintptr(0)))
+	sizeOfPrivateShare = uint64(unsafe.Sizeof(typesDKG.PrivateShare{}))
+	sizeOfPsig         = uint64(unsafe.Sizeof(typesDKG.PartialSignature{}))
+	sizeOfDKGPsig      = uint64(unsafe.Sizeof(dkg.PartialSignature{}))
+	sizeOfSigner       = uint64(unsafe.Sizeof(dkgShareSecret{}) +
+		unsafe.Sizeof(dkg.PrivateKey{}))
+	sizeOfNodePublicKeys = uint64(unsafe.Sizeof(typesDKG.NodePublicKeys{}))
+	sizeOfPublicKey      = uint64(unsafe.Sizeof(dkg.PublicKey{}))
+)
+
+func privateShareSize(prvShare *typesDKG.PrivateShare) uint64 {
+	return sizeOfPrivateShare + uint64(len(prvShare.Signature.Signature))
+}
+
+func nodePublicKeysSize(npks *typesDKG.NodePublicKeys) uint64 {
+	return sizeOfNodePublicKeys +
+		uint64(len(npks.QualifyIDs))*sizeOfDKGID +
+		uint64(len(npks.QualifyNodeIDs))*sizeOfNodeID +
+		uint64(len(npks.IDMap))*(sizeOfNodeID+sizeOfDKGID) +
+		uint64(len(npks.PublicKeys))*(sizeOfNodeID+sizeOfPointer+sizeOfPublicKey)
+}
+
+// MemoryEstimate returns the approximate memory held by this
+// configurationChain, for sizing nodes and tuning the TTL of buffered partial
+// signatures.
+func (cc *configurationChain) MemoryEstimate() MemoryStats {
+	stats := MemoryStats{NodePublicKeys: make(map[uint64]uint64)}
+	func() {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		for _, prvShare := range cc.pendingPrvShare {
+			stats.PrivateShares += sizeOfNodeID + privateShareSize(prvShare)
+		}
+		for _, shares := range cc.receivedPrvShare {
+			for _, prvShare := range shares {
+				stats.PrivateShares += 2*sizeOfNodeID + privateShareSize(prvShare)
+			}
+		}
+	}()
+	func() {
+		cc.dkgResult.RLock()
+		defer cc.dkgResult.RUnlock()
+		stats.Signers = uint64(len(cc.dkgSigner)) * (8 + sizeOfSigner)
+		for round, npks := range cc.npks {
+			stats.NodePublicKeys[round] = nodePublicKeysSize(npks)
+		}
+	}()
+	cc.tsigReady.L.Lock()
+	defer cc.tsigReady.L.Unlock()
+	bufs := make([]*psigBuffer, 0, len(cc.pendingPsig)+len(cc.persistedPsig))
+	for _, buf := range cc.pendingPsig {
+		bufs = append(bufs, buf)
+	}
+	for _, buf := range cc.persistedPsig {
+		bufs = append(bufs, buf)
+	}
+	for _, buf := range bufs {
+		stats.PartialSignatures += common.HashLength
+		for _, psig := range buf.psigs {
+			stats.PartialSignatures += sizeOfPointer + sizeOfPsig +
+				uint64(len(psig.Signature.Signature))
+		}
+	}
+	for _, tsig := range cc.tsig {
+		stats.PartialSignatures += common.HashLength
+		for _, sig := range tsig.sigs {
+			stats.PartialSignatures += sizeOfDKGID + sizeOfDKGPsig +
+				uint64(len(sig.Signature))
+		}
+	}
+	return stats
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+)
+
+// PauseShareProcessing makes incoming private shares buffered instead of
+// processed, until ResumeShareProcessing is called. Shares beyond
+// pausedPrvShareLimit are rejected with ErrPausedPrivateShareFull, and shares
+// beyond the limit of their round are rejected with ErrShareBufferFull.
+func (cc *configurationChain) PauseShareProcessing() {
+	cc.pausedPrvShareLock.Lock()
+	defer cc.pausedPrvShareLock.Unlock()
+	cc.prvSharePaused = true
+}
+
+// ResumeShareProcessing processes private shares buffered while paused in the
+// order they are received, and stops buffering incoming ones. Errors of each
+// share are logged, as they would be when processed on arrival.
+func (cc *configurationChain) ResumeShareProcessing() {
+	cc.pausedPrvShareLock.Lock()
+	defer cc.pausedPrvShareLock.Unlock()
+	// Shares received during draining wait for the lock, so they are
+	// processed after the buffered ones.
+	for _, prvShare := range cc.pausedPrvShare {
+		if err := cc.processPrivateShareNoPause(prvShare); err != nil {
+			cc.logger.Error("Failed to process paused private share",
+				"share", prvShare,
+				"error", err)
+		}
+	}
+	cc.pausedPrvShare = nil
+	cc.pausedPrvShareCount = make(map[uint64]int)
+	cc.prvSharePaused = false
+}
+
+// SetPausedPrivateShareLimit sets the count of private shares buffered in
+// total while share processing is paused, the default is
+// defaultPausedPrvShareLimit. Shares already buffered are kept.
+func (cc *configurationChain) SetPausedPrivateShareLimit(limit int) {
+	cc.pausedPrvShareLock.Lock()
+	defer cc.pausedPrvShareLock.Unlock()
+	cc.pausedPrvShareLimit = limit
+}
+
+// SetPrivateShareBufferLimit sets the count of private shares buffered for
+// one round while share processing is paused, so flooding one round doesn't
+// exhaust the buffer for others. It's twice the size of the notary set of
+// that round when limit is not positive, which is the default. It's not
+// thread-safe and should be called before PauseShareProcessing.
+func (cc *configurationChain) SetPrivateShareBufferLimit(limit int) {
+	cc.prvShareRoundLimit = limit
+}
+
+// prvShareBufferLimit returns the count of private shares buffered for round.
+func (cc *configurationChain) prvShareBufferLimit(round uint64) (int, error) {
+	if cc.prvShareRoundLimit > 0 {
+		return cc.prvShareRoundLimit, nil
+	}
+	notarySet, err := cc.cache.GetNotarySet(round)
+	if err != nil {
+		return 0, err
+	}
+	return 2 * len(notarySet), nil
+}
+
+// bufferPausedPrivateShare buffers the private share when share processing is
+// paused, and returns false otherwise. The share is verified before it's
+// counted against the limits, so junk shares don't take the buffer.
+func (cc *configurationChain) bufferPausedPrivateShare(
+	prvShare *typesDKG.PrivateShare) (bool, error) {
+	if paused := func() bool {
+		cc.pausedPrvShareLock.Lock()
+		defer cc.pausedPrvShareLock.Unlock()
+		return cc.prvSharePaused
+	}(); !paused {
+		return false, nil
+	}
+	if err := cc.verifyPausedPrivateShare(prvShare); err != nil {
+		return true, err
+	}
+	cc.pausedPrvShareLock.Lock()
+	defer cc.pausedPrvShareLock.Unlock()
+	// It might be resumed during verification.
+	if !cc.prvSharePaused {
+		return false, nil
+	}
+	if len(cc.pausedPrvShare) >= cc.pausedPrvShareLimit {
+		return true, ErrPausedPrivateShareFull
+	}
+	limit, err := cc.prvShareBufferLimit(prvShare.Round)
+	if err != nil {
+		return true, err
+	}
+	if cc.pausedPrvShareCount[prvShare.Round] >= limit {
+		return true, ErrShareBufferFull
+	}
+	cc.pausedPrvShare = append(cc.pausedPrvShare, prvShare)
+	cc.pausedPrvShareCount[prvShare.Round]++
+	return true, nil
+}
+
+// verifyPausedPrivateShare checks a private share to be buffered while share
+// processing is paused. It's verified by VerifyPrivateShare when master public
+// keys of its DKG are processed, otherwise only its proposer and signature
+// are checked.
+func (cc *configurationChain) verifyPausedPrivateShare(
+	prvShare *typesDKG.PrivateShare) error {
+	if mpkReady := func() bool {
+		cc.dkgLock.RLock()
+		defer cc.dkgLock.RUnlock()
+		return cc.dkg != nil && cc.mpkReady &&
+			cc.dkg.round == prvShare.Round && cc.dkg.reset == prvShare.Reset
+	}(); mpkReady {
+		return cc.VerifyPrivateShare(prvShare)
+	}
+	notarySet, err := cc.cache.GetNotarySet(prvShare.Round)
+	if err != nil {
+		return err
+	}
+	if _, exist := notarySet[prvShare.ProposerID]; !exist {
+		return ErrNotDKGParticipant
+	}
+	ok, err := utils.VerifyDKGPrivateShareSignature(prvShare)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIncorrectPrivateShareSignature
+	}
+	return nil
+}
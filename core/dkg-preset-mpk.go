@@ -0,0 +1,143 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+)
+
+// registerDKGWithMPKs registers a DKG like registerDKG, but with master public
+// keys already known, ex. read from a durable copy of the ones in governance
+// for resharing or restarting. The DKG protocol of this node must be
+// recoverable from db and match its master public key in mpks, otherwise
+// nothing is registered or proposed. The DKG would skip waiting for master
+// public keys and propose MPK-ready then exchange private shares right after
+// runDKG is called, so all participants should be registered by then. The
+// master public keys should be the same as the ones in governance, which
+// decides the DKG result.
+func (cc *configurationChain) registerDKGWithMPKs(
+	parentCtx context.Context,
+	round, reset uint64,
+	threshold int,
+	mpks []*typesDKG.MasterPublicKey) error {
+	selfExists := false
+	for _, mpk := range mpks {
+		if mpk.Round != round || mpk.Reset != reset {
+			return ErrIncorrectPresetMasterPublicKey
+		}
+		ok, err := utils.VerifyDKGMasterPublicKeySignature(mpk)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrIncorrectPresetMasterPublicKey
+		}
+		if mpk.ProposerID == cc.ID {
+			selfExists = true
+		}
+	}
+	if !selfExists {
+		return ErrSelfMPKNotRegister
+	}
+	// Check the preset master public keys before registerDKG gets a chance to
+	// deal and propose a new one.
+	recovered, err := recoverDKGProtocol(cc.ID, cc.recv, round, reset, cc.db)
+	if err != nil {
+		return err
+	}
+	if recovered == nil {
+		return ErrDKGProtocolNotRecovered
+	}
+	if err := recovered.verifyPresetMPKs(mpks); err != nil {
+		return err
+	}
+	if err := cc.registerDKG(parentCtx, round, reset, threshold); err != nil {
+		return err
+	}
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	if cc.dkg == nil || cc.dkg.round != round || cc.dkg.reset != reset {
+		return ErrDKGNotRegistered
+	}
+	cc.presetMPKs = append([]*typesDKG.MasterPublicKey(nil), mpks...)
+	return nil
+}
+
+// hasPresetMPKs checks if the registered DKG of round and reset comes with
+// preset master public keys.
+func (cc *configurationChain) hasPresetMPKs(round, reset uint64) bool {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	return cc.presetMPKs != nil && cc.dkg != nil &&
+		cc.dkg.round == round && cc.dkg.reset == reset
+}
+
+// dkgMasterPublicKeys returns the master public keys of the running DKG of
+// round, the preset ones are preferred to the ones in governance. It should
+// be called with cc.dkgLock held.
+func (cc *configurationChain) dkgMasterPublicKeys(
+	round uint64) []*typesDKG.MasterPublicKey {
+	if cc.presetMPKs != nil && cc.dkg != nil && cc.dkg.round == round {
+		return cc.presetMPKs
+	}
+	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys", "round", round)
+	return cc.gov.DKGMasterPublicKeys(round)
+}
+
+// skipMPKPhaseOffsets returns the offsets of DKG phases with the phase waiting
+// for master public keys taking no time.
+func skipMPKPhaseOffsets(offsets []uint64) []uint64 {
+	skipped := make([]uint64, len(offsets))
+	for i := 1; i < len(offsets); i++ {
+		skipped[i] = offsets[i] - offsets[DKGPhaseExchangePrivateShares]
+	}
+	return skipped
+}
+
+// verifyPresetMPKs makes sure the master public key of this node in mpks
+// matches its private shares.
+func (d *dkgProtocol) verifyPresetMPKs(mpks []*typesDKG.MasterPublicKey) error {
+	var selfMPK *typesDKG.MasterPublicKey
+	ids := make(dkg.IDs, 0, len(mpks))
+	for _, mpk := range mpks {
+		ids = append(ids, mpk.DKGID)
+		if mpk.ProposerID == d.ID {
+			selfMPK = mpk
+		}
+	}
+	if selfMPK == nil {
+		return ErrSelfMPKNotRegister
+	}
+	d.masterPrivateShare.SetParticipants(ids)
+	share, ok := d.masterPrivateShare.Share(selfMPK.DKGID)
+	if !ok {
+		return ErrUnableGetSelfPrvShare
+	}
+	ok, err := selfMPK.PublicKeyShares.VerifyPrvShare(selfMPK.DKGID, share)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSelfPrvShareMismatch
+	}
+	return nil
+}
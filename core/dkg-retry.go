@@ -0,0 +1,164 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+)
+
+// GovernanceRetryPolicy decides if a governance read failed during DKG should
+// be retried, and how long to wait before that.
+type GovernanceRetryPolicy interface {
+	// Backoff returns the duration to wait before the attempt-th retry, which
+	// starts from 1, of a read failed with err. retry is false when err is
+	// permanent or there is no retry left.
+	Backoff(attempt int, err error) (delay time.Duration, retry bool)
+}
+
+// IsTransientGovernanceError checks if the error of a governance read might
+// go away later, like the node set or configuration of a round not ready yet.
+func IsTransientGovernanceError(err error) bool {
+	switch err {
+	case utils.ErrNodeSetNotReady,
+		utils.ErrCRSNotReady,
+		utils.ErrConfigurationNotReady:
+		return true
+	}
+	return false
+}
+
+// ExponentialBackoffPolicy retries reads failed with transient errors at most
+// MaxRetries times, the delay starts from InitialDelay and is doubled for each
+// retry up to MaxDelay.
+type ExponentialBackoffPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	MaxRetries   int
+	// IsTransient decides if an error is transient, IsTransientGovernanceError
+	// is used when it's nil.
+	IsTransient func(error) bool
+}
+
+// Backoff implements GovernanceRetryPolicy interface.
+func (p ExponentialBackoffPolicy) Backoff(attempt int, err error) (
+	time.Duration, bool) {
+	isTransient := p.IsTransient
+	if isTransient == nil {
+		isTransient = IsTransientGovernanceError
+	}
+	if attempt > p.MaxRetries || !isTransient(err) {
+		return 0, false
+	}
+	delay := p.InitialDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay, true
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, true
+}
+
+// SetGovernanceRetryPolicy sets the policy to retry governance reads failed
+// during DKG, they are not retried by default. It's not thread-safe and should
+// be called before any DKG is registered.
+func (cc *configurationChain) SetGovernanceRetryPolicy(
+	policy GovernanceRetryPolicy) {
+	cc.govRetryPolicy = policy
+}
+
+// retryGovernanceRead calls read until it succeeds, or the retry policy gives
+// up and the last error is returned. ErrDKGAborted is returned when ctx is
+// done while waiting for a retry.
+func (cc *configurationChain) retryGovernanceRead(
+	ctx context.Context, read func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := read()
+		if err == nil || cc.govRetryPolicy == nil {
+			return err
+		}
+		delay, retry := cc.govRetryPolicy.Backoff(attempt, err)
+		if !retry {
+			return err
+		}
+		cc.logger.Warn("Retry governance read",
+			"attempt", attempt,
+			"delay", delay,
+			"error", err)
+		select {
+		case <-ctx.Done():
+			return ErrDKGAborted
+		case <-time.After(delay):
+		}
+	}
+}
+
+// getNotarySet gets the notary set of round, with retries. ErrDuplicateNodeID
+// is returned without retries when the node set of round contains the same
+// node more than once, the DKG of that round should not be run on it.
+func (cc *configurationChain) getNotarySet(
+	ctx context.Context, round uint64) (
+	notarySet map[types.NodeID]struct{}, err error) {
+	err = cc.retryGovernanceRead(ctx, func() (err error) {
+		notarySet, err = cc.cache.GetNotarySet(round)
+		return
+	})
+	if err != nil {
+		return
+	}
+	nodes := make(map[types.NodeID]struct{})
+	for _, key := range cc.gov.NodeSet(round) {
+		nID := types.NewNodeID(key)
+		if _, exist := nodes[nID]; exist {
+			return nil, ErrDuplicateNodeID
+		}
+		nodes[nID] = struct{}{}
+	}
+	return
+}
+
+// getConfiguration gets the configuration of round, with retries.
+func (cc *configurationChain) getConfiguration(
+	ctx context.Context, round uint64) (cfg *types.Config, err error) {
+	err = cc.retryGovernanceRead(ctx, func() error {
+		cc.logger.Debug("Calling Governance.Configuration", "round", round)
+		if cfg = cc.gov.Configuration(round); cfg == nil {
+			return utils.ErrConfigurationNotReady
+		}
+		return nil
+	})
+	return
+}
+
+// dkgContext returns the context of the registered DKG, or a background
+// context when there is none.
+func (cc *configurationChain) dkgContext() context.Context {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	if cc.dkgCtx == nil {
+		return context.Background()
+	}
+	return cc.dkgCtx
+}
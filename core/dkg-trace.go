@@ -0,0 +1,163 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+)
+
+// DKGTrace is the time of key events observed by one node in the DKG of a
+// round, events not observed yet are zero.
+type DKGTrace struct {
+	Round uint64
+	Reset uint64
+	// FirstMPKSeen is the time the first master public key is found in
+	// governance.
+	FirstMPKSeen time.Time
+	// AllMPKsSeen is the time master public keys of the whole notary set are
+	// found in governance, or the time MPKs are ready when some are missing.
+	AllMPKsSeen time.Time
+	// FirstShareSent is the time private shares are begun to be proposed.
+	FirstShareSent time.Time
+	// AllSharesProcessed is the time private shares from all participants are
+	// processed.
+	AllSharesProcessed time.Time
+	// ComplaintWindowClosed is the time nack complaints are collected from
+	// governance.
+	ComplaintWindowClosed time.Time
+	// Finalized is the time the DKG is found final in governance.
+	Finalized time.Time
+}
+
+type dkgTraceEvent int
+
+const (
+	dkgTraceFirstMPKSeen dkgTraceEvent = iota
+	dkgTraceAllMPKsSeen
+	dkgTraceFirstShareSent
+	dkgTraceAllSharesProcessed
+	dkgTraceComplaintWindowClosed
+	dkgTraceFinalized
+)
+
+// dkgTraceRecorder records the time of DKG events by round when enabled, only
+// the first occurrence of each event is kept. It's guarded by dkgLock of
+// configurationChain.
+type dkgTraceRecorder struct {
+	enabled bool
+	traces  map[uint64]*DKGTrace
+}
+
+// begin starts a new trace for the DKG of round, the trace of the previous
+// reset is dropped.
+func (r *dkgTraceRecorder) begin(round, reset uint64) {
+	if !r.enabled {
+		return
+	}
+	if r.traces == nil {
+		r.traces = make(map[uint64]*DKGTrace)
+	}
+	r.traces[round] = &DKGTrace{Round: round, Reset: reset}
+}
+
+func (r *dkgTraceRecorder) record(
+	round, reset uint64, event dkgTraceEvent, at time.Time) {
+	if !r.enabled {
+		return
+	}
+	trace, exist := r.traces[round]
+	if !exist || trace.Reset != reset {
+		return
+	}
+	var t *time.Time
+	switch event {
+	case dkgTraceFirstMPKSeen:
+		t = &trace.FirstMPKSeen
+	case dkgTraceAllMPKsSeen:
+		t = &trace.AllMPKsSeen
+	case dkgTraceFirstShareSent:
+		t = &trace.FirstShareSent
+	case dkgTraceAllSharesProcessed:
+		t = &trace.AllSharesProcessed
+	case dkgTraceComplaintWindowClosed:
+		t = &trace.ComplaintWindowClosed
+	case dkgTraceFinalized:
+		t = &trace.Finalized
+	default:
+		return
+	}
+	if t.IsZero() {
+		*t = at
+	}
+}
+
+// SetDKGTrace enables recording DKG traces, which are available through
+// DKGTrace. It's not thread-safe and should be called before any DKG is
+// registered.
+func (cc *configurationChain) SetDKGTrace(enabled bool) {
+	cc.dkgTrace.enabled = enabled
+}
+
+// DKGTrace returns a copy of the trace of the latest DKG of round, nil is
+// returned when there is none or tracing is disabled.
+func (cc *configurationChain) DKGTrace(round uint64) *DKGTrace {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	trace, exist := cc.dkgTrace.traces[round]
+	if !exist {
+		return nil
+	}
+	ret := *trace
+	return &ret
+}
+
+// traceDKG records an event of the registered DKG, it should be called with
+// dkgLock held.
+func (cc *configurationChain) traceDKG(event dkgTraceEvent) {
+	if !cc.dkgTrace.enabled || cc.dkg == nil {
+		return
+	}
+	cc.dkgTrace.record(cc.dkg.round, cc.dkg.reset, event, time.Now())
+}
+
+// traceMPKs records the MPKs found in governance, it should be called with
+// dkgLock held.
+func (cc *configurationChain) traceMPKs(round uint64) {
+	if !cc.dkgTrace.enabled {
+		return
+	}
+	cc.logger.Debug("Calling Governance.DKGMasterPublicKeys", "round", round)
+	mpks := cc.gov.DKGMasterPublicKeys(round)
+	if len(mpks) > 0 {
+		cc.traceDKG(dkgTraceFirstMPKSeen)
+	}
+	if len(missingMPKProposers(cc.notarySet, mpks)) == 0 {
+		cc.traceDKG(dkgTraceAllMPKsSeen)
+	}
+}
+
+// traceSharesProcessed records if private shares from all participants are
+// processed, it should be called with dkgLock held.
+func (cc *configurationChain) traceSharesProcessed() {
+	if !cc.dkgTrace.enabled || cc.dkg == nil || !cc.mpkReady {
+		return
+	}
+	if len(cc.dkg.prvSharesReceived) == len(cc.dkg.mpkMap) {
+		cc.traceDKG(dkgTraceAllSharesProcessed)
+	}
+}
@@ -20,6 +20,7 @@ package core
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -40,6 +41,8 @@ var (
 		"private share not found for specific ID")
 	ErrIncorrectPrivateShareSignature = fmt.Errorf(
 		"incorrect private share signature")
+	ErrIncorrectPrivateShare = fmt.Errorf(
+		"incorrect private share")
 	ErrMismatchPartialSignatureHash = fmt.Errorf(
 		"mismatch partialSignature hash")
 	ErrIncorrectPartialSignatureSignature = fmt.Errorf(
@@ -218,6 +221,9 @@ type tsigProtocol struct {
 	hash           common.Hash
 	sigs           map[dkg.ID]dkg.PartialSignature
 	threshold      int
+	aborted        bool
+	// The time the first partial signature of hash is buffered or processed.
+	firstPsigTime time.Time
 }
 
 func newDKGProtocol(
@@ -226,9 +232,19 @@ func newDKGProtocol(
 	round uint64,
 	reset uint64,
 	threshold int) *dkgProtocol {
-
 	prvShare, pubShare := dkg.NewPrivateKeyShares(threshold)
+	return newDKGProtocolWithShares(
+		ID, recv, round, reset, threshold, prvShare, pubShare)
+}
 
+func newDKGProtocolWithShares(
+	ID types.NodeID,
+	recv dkgReceiver,
+	round uint64,
+	reset uint64,
+	threshold int,
+	prvShare *dkg.PrivateKeyShares,
+	pubShare *dkg.PublicKeyShares) *dkgProtocol {
 	recv.ProposeDKGMasterPublicKey(&typesDKG.MasterPublicKey{
 		Round:           round,
 		Reset:           reset,
@@ -457,6 +473,24 @@ func (d *dkgProtocol) sanityCheck(prvShare *typesDKG.PrivateShare) error {
 	return nil
 }
 
+// verifyPrivateShare checks the private share is signed by its proposer and
+// matches the master public key of the proposer.
+func (d *dkgProtocol) verifyPrivateShare(
+	receiverID dkg.ID, prvShare *typesDKG.PrivateShare) error {
+	if err := d.sanityCheck(prvShare); err != nil {
+		return err
+	}
+	mpk := d.mpkMap[prvShare.ProposerID]
+	ok, err := mpk.VerifyPrvShare(receiverID, &prvShare.PrivateShare)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIncorrectPrivateShare
+	}
+	return nil
+}
+
 func (d *dkgProtocol) processPrivateShare(
 	prvShare *typesDKG.PrivateShare) error {
 	receiverID, exist := d.idMap[prvShare.ReceiverID]
@@ -476,14 +510,11 @@ func (d *dkgProtocol) processPrivateShare(
 			}
 		}
 	}
-	if err := d.sanityCheck(prvShare); err != nil {
-		return err
-	}
-	mpk := d.mpkMap[prvShare.ProposerID]
-	ok, err := mpk.VerifyPrvShare(receiverID, &prvShare.PrivateShare)
-	if err != nil {
+	err := d.verifyPrivateShare(receiverID, prvShare)
+	if err != nil && err != ErrIncorrectPrivateShare {
 		return err
 	}
+	ok := err == nil
 	if prvShare.ReceiverID == d.ID {
 		d.prvSharesReceived[prvShare.ProposerID] = struct{}{}
 	}
@@ -691,13 +722,28 @@ func (tsig *tsigProtocol) processPartialSignature(
 		tsig.hash, crypto.Signature(psig.PartialSignature)) {
 		return ErrIncorrectPartialSignature
 	}
+	if tsig.firstPsigTime.IsZero() {
+		tsig.firstPsigTime = time.Now()
+	}
 	tsig.sigs[id] = psig.PartialSignature
 	return nil
 }
 
 func (tsig *tsigProtocol) signature() (crypto.Signature, error) {
+	psigs, ids, err := tsig.partialSignatures()
+	if err != nil {
+		return crypto.Signature{}, err
+	}
+	return dkg.RecoverSignature(psigs, ids)
+}
+
+// partialSignatures returns a copy of collected partial signatures, and
+// ErrNotEnoughtPartialSignatures when there are not enough to recover the
+// signature.
+func (tsig *tsigProtocol) partialSignatures() (
+	[]dkg.PartialSignature, dkg.IDs, error) {
 	if len(tsig.sigs) < tsig.nodePublicKeys.Threshold {
-		return crypto.Signature{}, ErrNotEnoughtPartialSignatures
+		return nil, nil, ErrNotEnoughtPartialSignatures
 	}
 	ids := make(dkg.IDs, 0, len(tsig.sigs))
 	psigs := make([]dkg.PartialSignature, 0, len(tsig.sigs))
@@ -705,5 +751,5 @@ func (tsig *tsigProtocol) signature() (crypto.Signature, error) {
 		ids = append(ids, id)
 		psigs = append(psigs, psig)
 	}
-	return dkg.RecoverSignature(psigs, ids)
+	return psigs, ids, nil
 }
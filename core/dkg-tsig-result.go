@@ -0,0 +1,155 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"time"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+// TSigVerifyMode decides when a threshold signature recovered by runTSig is
+// verified against the group public key.
+type TSigVerifyMode int
+
+// TSigVerifyMode enums.
+const (
+	// TSigVerifyEager verifies the signature before returning it, which is
+	// the default.
+	TSigVerifyEager TSigVerifyMode = iota
+	// TSigVerifyLazy returns the signature once recovered, the caller should
+	// verify it via TSigResult.Verify.
+	TSigVerifyLazy
+)
+
+// TSigResult is a threshold signature recovered by runTSig.
+type TSigResult struct {
+	Round     uint64
+	Hash      common.Hash
+	Signature crypto.Signature
+
+	groupPubKey func() (*dkg.PublicKey, error)
+	verified    bool
+}
+
+// Verify checks the signature against the group public key of the round,
+// ErrIncorrectThresholdSignature is returned when it's incorrect. It's not
+// thread-safe.
+func (r *TSigResult) Verify() error {
+	if r.verified {
+		return nil
+	}
+	groupPubKey, err := r.groupPubKey()
+	if err != nil {
+		return err
+	}
+	if !groupPubKey.VerifySignature(r.Hash, r.Signature) {
+		return ErrIncorrectThresholdSignature
+	}
+	r.verified = true
+	return nil
+}
+
+// recoverGroupPublicKey recovers the group public key from the public keys of
+// qualified participants.
+func recoverGroupPublicKey(
+	npks *typesDKG.NodePublicKeys) (*dkg.PublicKey, error) {
+	pubKeys := make([]*dkg.PublicKey, 0, len(npks.IDMap))
+	ids := make(dkg.IDs, 0, len(npks.IDMap))
+	for nID, id := range npks.IDMap {
+		pubKeys = append(pubKeys, npks.PublicKeys[nID])
+		ids = append(ids, id)
+	}
+	return dkg.RecoverPublicKey(pubKeys, ids)
+}
+
+// cachedGroupPublicKey is the group public key recovered from npks.
+type cachedGroupPublicKey struct {
+	npks *typesDKG.NodePublicKeys
+	key  *dkg.PublicKey
+}
+
+// groupPublicKey returns the group public key recovered from npks of round.
+// It's recovered once and cached next to npks, as long as npks is the one
+// cached for round.
+func (cc *configurationChain) groupPublicKey(round uint64,
+	npks *typesDKG.NodePublicKeys) (*dkg.PublicKey, error) {
+	if cached := func() *cachedGroupPublicKey {
+		cc.dkgResult.RLock()
+		defer cc.dkgResult.RUnlock()
+		return cc.groupPubKeys[round]
+	}(); cached != nil && cached.npks == npks {
+		return cached.key, nil
+	}
+	key, err := recoverGroupPublicKey(npks)
+	if err != nil {
+		return nil, err
+	}
+	cc.dkgResult.Lock()
+	defer cc.dkgResult.Unlock()
+	if cc.npks[round] == npks {
+		cc.groupPubKeys[round] = &cachedGroupPublicKey{npks: npks, key: key}
+	}
+	return key, nil
+}
+
+// SetTSigVerifyMode sets when threshold signatures recovered by runTSig are
+// verified, the default is TSigVerifyEager. In TSigVerifyLazy mode, runTSig
+// returns signatures without verifying them, and runTSigResult returns them
+// to be verified later. It's not thread-safe and should be called before
+// runTSig.
+func (cc *configurationChain) SetTSigVerifyMode(mode TSigVerifyMode) {
+	cc.tsigVerifyMode = mode
+}
+
+// runTSigResult is runTSig returning the signature as a TSigResult, which is
+// verified already unless the verify mode is TSigVerifyLazy.
+func (cc *configurationChain) runTSigResult(
+	round uint64, hash common.Hash, wait time.Duration) (*TSigResult, error) {
+	return cc.runTSigResultWithProgress(round, hash, wait, nil)
+}
+
+func (cc *configurationChain) runTSigResultWithProgress(
+	round uint64, hash common.Hash, wait time.Duration,
+	progress func(have, needed int)) (*TSigResult, error) {
+	signature, npks, err := cc.collectTSig(round, hash, wait, progress)
+	if err != nil {
+		return nil, err
+	}
+	result := &TSigResult{
+		Round:     round,
+		Hash:      hash,
+		Signature: signature,
+		groupPubKey: func() (*dkg.PublicKey, error) {
+			return cc.groupPublicKey(round, npks)
+		},
+	}
+	if cc.tsigVerifyMode == TSigVerifyEager {
+		if err = result.Verify(); err != nil {
+			cc.logger.Error("Failed to verify threshold signature",
+				"round", round,
+				"hash", hash,
+				"error", err)
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/utils"
+)
+
+// Verifier verifies threshold signatures with group public keys derived from
+// DKG results in governance. It holds no private material, and is for clients
+// verifying threshold signatures without participating in DKG.
+type Verifier struct {
+	gov   Governance
+	cache *utils.NodeSetCache
+	lock  sync.RWMutex
+	gpks  map[uint64]*typesDKG.GroupPublicKey
+	// The DKG reset count of each cached group public key.
+	resets map[uint64]uint64
+}
+
+// NewVerifier constructs a Verifier instance.
+func NewVerifier(gov Governance, cache *utils.NodeSetCache) *Verifier {
+	return &Verifier{
+		gov:    gov,
+		cache:  cache,
+		gpks:   make(map[uint64]*typesDKG.GroupPublicKey),
+		resets: make(map[uint64]uint64),
+	}
+}
+
+// GroupPublicKey returns the group public key of a round, ErrDKGNotReady is
+// returned when the DKG of that round is not final yet. ErrNotDKGParticipant
+// is returned when any qualified node is not in the notary set of that round.
+func (v *Verifier) GroupPublicKey(
+	round uint64) (*typesDKG.GroupPublicKey, error) {
+	reset := v.gov.DKGResetCount(round)
+	v.lock.RLock()
+	gpk, exist := v.gpks[round]
+	if exist && v.resets[round] != reset {
+		exist = false
+	}
+	v.lock.RUnlock()
+	if exist {
+		return gpk, nil
+	}
+	if !v.gov.IsDKGFinal(round) {
+		return nil, ErrDKGNotReady
+	}
+	cfg := v.gov.Configuration(round)
+	if cfg == nil {
+		return nil, utils.ErrConfigurationNotReady
+	}
+	gpk, err := typesDKG.NewGroupPublicKey(round,
+		v.gov.DKGMasterPublicKeys(round),
+		v.gov.DKGComplaints(round),
+		utils.GetDKGThreshold(cfg))
+	if err != nil {
+		return nil, err
+	}
+	notarySet, err := v.cache.GetNotarySet(round)
+	if err != nil {
+		return nil, err
+	}
+	for nID := range gpk.QualifyNodeIDs {
+		if _, exist := notarySet[nID]; !exist {
+			return nil, ErrNotDKGParticipant
+		}
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.gpks[round] = gpk
+	v.resets[round] = reset
+	return gpk, nil
+}
+
+// VerifyThresholdSignature checks if sig is the threshold signature of hash
+// signed by qualified nodes of a round.
+func (v *Verifier) VerifyThresholdSignature(
+	round uint64, hash common.Hash, sig crypto.Signature) (bool, error) {
+	gpk, err := v.GroupPublicKey(round)
+	if err != nil {
+		return false, err
+	}
+	return gpk.VerifySignature(hash, sig), nil
+}
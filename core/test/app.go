@@ -19,11 +19,16 @@ package test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/db"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
 	"github.com/dexon-foundation/dexon-consensus/core/utils"
 )
@@ -71,8 +76,42 @@ var (
 	ErrEmptyRandomness = fmt.Errorf("empty randomness")
 	// ErrInvalidHeight refers to invalid value for block height.
 	ErrInvalidHeight = fmt.Errorf("invalid height")
+	// ErrDeliveredBeforeConfirmed raised when a block is delivered before it's
+	// confirmed.
+	ErrDeliveredBeforeConfirmed = fmt.Errorf("delivered before confirmed")
+	// ErrTooManyUndeliveredBlocks raised when too many confirmed blocks are
+	// waiting to be delivered.
+	ErrTooManyUndeliveredBlocks = fmt.Errorf("too many undelivered blocks")
+	// ErrRoundOutOfOrder raised when a block is delivered after any block of
+	// later rounds.
+	ErrRoundOutOfOrder = fmt.Errorf("round out of order")
 )
 
+// ErrHeightGap raised when heights of delivered blocks skip some heights,
+// Missing is the first height skipped before the block of Hash.
+type ErrHeightGap struct {
+	Hash    common.Hash
+	Missing uint64
+}
+
+func (e ErrHeightGap) Error() string {
+	return fmt.Sprintf("height gap before %s: missing %d",
+		e.Hash.String()[:6], e.Missing)
+}
+
+// ErrWitnessAheadOfDelivery raised when a block is confirmed with a witness of
+// a block not delivered before that, Witnessed is the hash in the witness of
+// the block of Hash.
+type ErrWitnessAheadOfDelivery struct {
+	Hash      common.Hash
+	Witnessed common.Hash
+}
+
+func (e ErrWitnessAheadOfDelivery) Error() string {
+	return fmt.Sprintf("witness ahead of delivery: %s witnesses %s",
+		e.Hash.String()[:6], e.Witnessed.String()[:6])
+}
+
 // AppDeliveredRecord caches information when this application received
 // a block delivered notification.
 type AppDeliveredRecord struct {
@@ -94,6 +133,10 @@ type App struct {
 	rEvt                *utils.RoundEvent
 	hEvt                *common.Event
 	roundToNotify       uint64
+	duplicates          common.Hashes
+	confirmedTime       map[common.Hash]time.Time
+	db                  db.Database
+	unknownDeliveries   common.Hashes
 }
 
 // NewApp constructs a TestApp instance.
@@ -103,6 +146,7 @@ func NewApp(initRound uint64, gov *Governance, rEvt *utils.RoundEvent) (
 		Confirmed:       make(map[common.Hash]*types.Block),
 		Delivered:       make(map[common.Hash]*AppDeliveredRecord),
 		DeliverSequence: common.Hashes{},
+		confirmedTime:   make(map[common.Hash]time.Time),
 		gov:             gov,
 		rEvt:            rEvt,
 		hEvt:            common.NewEvent(),
@@ -190,6 +234,7 @@ func (app *App) BlockConfirmed(b types.Block) {
 	app.confirmedLock.Lock()
 	defer app.confirmedLock.Unlock()
 	app.Confirmed[b.Hash] = &b
+	app.confirmedTime[b.Hash] = time.Now().UTC()
 	if app.LastConfirmedHeight+1 != b.Position.Height {
 		panic(ErrConfirmedHeightNotIncreasing)
 	}
@@ -208,9 +253,14 @@ func (app *App) ClearUndeliveredBlocks() {
 // BlockDelivered implements Application interface.
 func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 	rand []byte) {
-	func() {
+	duplicated := func() bool {
 		app.deliveredLock.Lock()
 		defer app.deliveredLock.Unlock()
+		if _, exists := app.Delivered[blockHash]; exists {
+			// Keep the first delivery and record this one.
+			app.duplicates = append(app.duplicates, blockHash)
+			return true
+		}
 		app.Delivered[blockHash] = &AppDeliveredRecord{
 			Rand: common.CopyBytes(rand),
 			When: time.Now().UTC(),
@@ -228,7 +278,14 @@ func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 			}
 		}
 		app.DeliverSequence = append(app.DeliverSequence, blockHash)
+		if app.db != nil && !app.db.HasBlock(blockHash) {
+			app.unknownDeliveries = append(app.unknownDeliveries, blockHash)
+		}
+		return false
 	}()
+	if duplicated {
+		return
+	}
 	// Apply packed state change requests in payload.
 	func() {
 		if app.state == nil {
@@ -271,6 +328,248 @@ func (app *App) GetLatestDeliveredPosition() types.Position {
 	return app.Confirmed[app.DeliverSequence[len(app.DeliverSequence)-1]].Position
 }
 
+// RandomnessOf returns the randomness recorded when the block identified by
+// the hash is delivered.
+func (app *App) RandomnessOf(hash common.Hash) ([]byte, bool) {
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	rec, exists := app.Delivered[hash]
+	if !exists {
+		return nil, false
+	}
+	return common.CopyBytes(rec.Rand), true
+}
+
+// DuplicateDeliveries returns hashes of blocks delivered more than once, one
+// entry for each extra delivery.
+func (app *App) DuplicateDeliveries() common.Hashes {
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	return append(common.Hashes(nil), app.duplicates...)
+}
+
+// AttachDB makes BlockDelivered check if each delivered block exists in the
+// DB, the ones not found are reported by DeliveredUnknownBlocks. It's not
+// thread-safe and should be called before any block is delivered.
+func (app *App) AttachDB(d db.Database) {
+	app.db = d
+}
+
+// DeliveredUnknownBlocks returns hashes of blocks delivered but not found in
+// the DB attached by AttachDB, in the order they are delivered.
+func (app *App) DeliveredUnknownBlocks() common.Hashes {
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	return append(common.Hashes(nil), app.unknownDeliveries...)
+}
+
+// DeliveryIntervals returns the minimum, maximum and mean of the intervals
+// between consecutive deliveries, in the order they are delivered. Zeros are
+// returned when fewer than two blocks are delivered.
+func (app *App) DeliveryIntervals() (min, max, mean time.Duration) {
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	if len(app.DeliverSequence) < 2 {
+		return
+	}
+	var total time.Duration
+	prev := app.Delivered[app.DeliverSequence[0]].When
+	for idx, h := range app.DeliverSequence[1:] {
+		when := app.Delivered[h].When
+		interval := when.Sub(prev)
+		if idx == 0 || interval < min {
+			min = interval
+		}
+		if idx == 0 || interval > max {
+			max = interval
+		}
+		total += interval
+		prev = when
+	}
+	mean = total / time.Duration(len(app.DeliverSequence)-1)
+	return
+}
+
+// VerifyConfirmedDeliverConsistency checks that each delivered block is
+// confirmed no later than it's delivered, and there are at most tolerance
+// confirmed blocks not delivered yet, or the delivery is stalled.
+func (app *App) VerifyConfirmedDeliverConsistency(tolerance int) error {
+	app.confirmedLock.RLock()
+	defer app.confirmedLock.RUnlock()
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	for _, h := range app.DeliverSequence {
+		confirmedTime, exist := app.confirmedTime[h]
+		if !exist {
+			return ErrDeliveredBlockNotConfirmed
+		}
+		if confirmedTime.After(app.Delivered[h].When) {
+			return ErrDeliveredBeforeConfirmed
+		}
+	}
+	var deliveredHeight uint64
+	if _, rec := app.LastDeliveredRecordNoLock(); rec != nil {
+		deliveredHeight = rec.Pos.Height
+	}
+	if app.LastConfirmedHeight > deliveredHeight+uint64(tolerance) {
+		return ErrTooManyUndeliveredBlocks
+	}
+	return nil
+}
+
+// VerifyRoundBoundaries checks that blocks of one round are all delivered
+// before any block of later rounds, the round of each delivered block is
+// decided by roundOf.
+func (app *App) VerifyRoundBoundaries(roundOf func(common.Hash) uint64) error {
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	var prevRound uint64
+	for idx, h := range app.DeliverSequence {
+		round := roundOf(h)
+		if idx > 0 && round < prevRound {
+			return ErrRoundOutOfOrder
+		}
+		prevRound = round
+	}
+	return nil
+}
+
+// VerifyNoHeightGaps checks that heights of delivered blocks, decided by
+// heightOf, increase by exactly one in the order they are delivered. The first
+// gap is reported as ErrHeightGap, ErrHeightOutOfOrder is returned when a
+// height doesn't increase.
+func (app *App) VerifyNoHeightGaps(heightOf func(common.Hash) uint64) error {
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	var prevHeight uint64
+	for idx, h := range app.DeliverSequence {
+		height := heightOf(h)
+		if idx > 0 {
+			if height <= prevHeight {
+				return ErrHeightOutOfOrder
+			}
+			if height != prevHeight+1 {
+				return ErrHeightGap{Hash: h, Missing: prevHeight + 1}
+			}
+		}
+		prevHeight = height
+	}
+	return nil
+}
+
+// VerifyWitnessOrder checks that the block in the witness of each confirmed
+// block is delivered before the witnessing block is confirmed. Confirmed
+// blocks are checked by their heights, and the first violation is reported as
+// ErrWitnessAheadOfDelivery.
+func (app *App) VerifyWitnessOrder() error {
+	app.confirmedLock.RLock()
+	defer app.confirmedLock.RUnlock()
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	blocks := make([]*types.Block, 0, len(app.Confirmed))
+	for _, b := range app.Confirmed {
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Position.Height < blocks[j].Position.Height
+	})
+	for _, b := range blocks {
+		if b.Witness.Height < types.GenesisHeight {
+			continue
+		}
+		var witnessed common.Hash
+		copy(witnessed[:], b.Witness.Data)
+		rec, exist := app.Delivered[witnessed]
+		if !exist || rec.When.After(app.confirmedTime[b.Hash]) {
+			return ErrWitnessAheadOfDelivery{
+				Hash:      b.Hash,
+				Witnessed: witnessed,
+			}
+		}
+	}
+	return nil
+}
+
+// DeliverDigest folds the hash and consensus timestamp of delivered blocks, in
+// the order they are delivered, into one digest. Two App instances with the
+// same digest delivered the same sequence.
+func (app *App) DeliverDigest() common.Hash {
+	app.confirmedLock.RLock()
+	defer app.confirmedLock.RUnlock()
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	var digest common.Hash
+	for _, h := range app.DeliverSequence {
+		var timestamp time.Time
+		if b, exist := app.Confirmed[h]; exist {
+			timestamp = b.Timestamp
+		}
+		binaryTimestamp, err := timestamp.UTC().MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		digest = crypto.Keccak256Hash(digest[:], h[:], binaryTimestamp)
+	}
+	return digest
+}
+
+// appState is the serialized form of App, used by SaveState and
+// LoadAppState.
+type appState struct {
+	Confirmed           map[common.Hash]*types.Block        `json:"confirmed"`
+	ConfirmedTime       map[common.Hash]time.Time           `json:"confirmed_time"`
+	LastConfirmedHeight uint64                              `json:"last_confirmed_height"`
+	Delivered           map[common.Hash]*AppDeliveredRecord `json:"delivered"`
+	DeliverSequence     common.Hashes                       `json:"deliver_sequence"`
+	Duplicates          common.Hashes                       `json:"duplicates"`
+	RoundToNotify       uint64                              `json:"round_to_notify"`
+}
+
+// SaveState writes confirmed and delivered blocks received by this App
+// instance to w, which could be loaded by LoadAppState later.
+func (app *App) SaveState(w io.Writer) error {
+	app.confirmedLock.RLock()
+	defer app.confirmedLock.RUnlock()
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	return json.NewEncoder(w).Encode(&appState{
+		Confirmed:           app.Confirmed,
+		ConfirmedTime:       app.confirmedTime,
+		LastConfirmedHeight: app.LastConfirmedHeight,
+		Delivered:           app.Delivered,
+		DeliverSequence:     app.DeliverSequence,
+		Duplicates:          app.duplicates,
+		RoundToNotify:       app.roundToNotify,
+	})
+}
+
+// LoadAppState constructs an App instance from the state written by
+// SaveState, all checks done by Verify hold as they were before saving.
+// Governance and round event are not saved, so the loaded instance doesn't
+// apply state changes in payloads of blocks delivered later.
+func LoadAppState(r io.Reader) (*App, error) {
+	state := appState{}
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+	app := NewApp(state.RoundToNotify, nil, nil)
+	if state.Confirmed != nil {
+		app.Confirmed = state.Confirmed
+	}
+	if state.ConfirmedTime != nil {
+		app.confirmedTime = state.ConfirmedTime
+	}
+	if state.Delivered != nil {
+		app.Delivered = state.Delivered
+	}
+	if state.DeliverSequence != nil {
+		app.DeliverSequence = state.DeliverSequence
+	}
+	app.LastConfirmedHeight = state.LastConfirmedHeight
+	app.duplicates = state.Duplicates
+	return app, nil
+}
+
 // Compare performs these checks against another App instance
 // and return erros if not passed:
 // - deliver sequence by comparing block hashes.
@@ -28,6 +28,7 @@ import (
 	"github.com/dexon-foundation/dexon-consensus/core"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	"github.com/dexon-foundation/dexon-consensus/core/db"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
 	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/utils"
@@ -197,6 +198,366 @@ func (s *AppTestSuite) TestVerify() {
 	s.Require().NoError(app.Verify())
 }
 
+func (s *AppTestSuite) TestVerifyConfirmedDeliverConsistency() {
+	blocks := make([]types.Block, 4)
+	for i := range blocks {
+		blocks[i] = types.Block{
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: types.GenesisHeight + uint64(i)},
+			Randomness: []byte{byte(i)},
+		}
+	}
+	app := NewApp(0, nil, nil)
+	s.Require().NoError(app.VerifyConfirmedDeliverConsistency(0))
+	for _, b := range blocks {
+		app.BlockConfirmed(b)
+	}
+	app.BlockDelivered(blocks[0].Hash, blocks[0].Position, blocks[0].Randomness)
+	// Within tolerance.
+	s.Require().NoError(app.VerifyConfirmedDeliverConsistency(3))
+	// Beyond tolerance.
+	s.Require().Equal(ErrTooManyUndeliveredBlocks,
+		app.VerifyConfirmedDeliverConsistency(2))
+	for _, b := range blocks[1:] {
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	}
+	s.Require().NoError(app.VerifyConfirmedDeliverConsistency(0))
+	// Delivered before confirmed.
+	app = NewApp(0, nil, nil)
+	app.BlockDelivered(blocks[0].Hash, blocks[0].Position, blocks[0].Randomness)
+	s.Require().Equal(ErrDeliveredBlockNotConfirmed,
+		app.VerifyConfirmedDeliverConsistency(0))
+	time.Sleep(time.Millisecond)
+	app.BlockConfirmed(blocks[0])
+	s.Require().Equal(ErrDeliveredBeforeConfirmed,
+		app.VerifyConfirmedDeliverConsistency(0))
+}
+
+func (s *AppTestSuite) TestVerifyRoundBoundaries() {
+	rounds := []uint64{0, 0, 1, 1, 0, 2}
+	roundOf := make(map[common.Hash]uint64)
+	blocks := make([]types.Block, len(rounds))
+	for i := range blocks {
+		blocks[i] = types.Block{
+			Hash: common.NewRandomHash(),
+			Position: types.Position{
+				Round:  rounds[i],
+				Height: types.GenesisHeight + uint64(i),
+			},
+			Randomness: []byte{byte(i)},
+		}
+		roundOf[blocks[i].Hash] = rounds[i]
+	}
+	app := NewApp(0, nil, nil)
+	verify := func() error {
+		return app.VerifyRoundBoundaries(func(h common.Hash) uint64 {
+			return roundOf[h]
+		})
+	}
+	s.Require().NoError(verify())
+	for _, b := range blocks[:4] {
+		app.BlockConfirmed(b)
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	}
+	s.Require().NoError(verify())
+	// A block of round 0 is delivered after blocks of round 1.
+	for _, b := range blocks[4:] {
+		app.BlockConfirmed(b)
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	}
+	s.Require().Equal(ErrRoundOutOfOrder, verify())
+}
+
+func (s *AppTestSuite) TestVerifyNoHeightGaps() {
+	// The block at height 4 is lost.
+	heights := []uint64{1, 2, 3, 5, 6}
+	heightOf := make(map[common.Hash]uint64)
+	blocks := make([]types.Block, len(heights))
+	for i := range blocks {
+		blocks[i] = types.Block{
+			Hash: common.NewRandomHash(),
+			Position: types.Position{
+				Height: types.GenesisHeight + uint64(i),
+			},
+			Randomness: []byte{byte(i)},
+		}
+		heightOf[blocks[i].Hash] = heights[i]
+	}
+	app := NewApp(0, nil, nil)
+	verify := func() error {
+		return app.VerifyNoHeightGaps(func(h common.Hash) uint64 {
+			return heightOf[h]
+		})
+	}
+	s.Require().NoError(verify())
+	for _, b := range blocks[:3] {
+		app.BlockConfirmed(b)
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	}
+	s.Require().NoError(verify())
+	for _, b := range blocks[3:] {
+		app.BlockConfirmed(b)
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	}
+	s.Require().Equal(ErrHeightGap{Hash: blocks[3].Hash, Missing: 4}, verify())
+	// A height not increasing is not a gap.
+	heightOf[blocks[3].Hash] = 3
+	s.Require().Equal(ErrHeightOutOfOrder, verify())
+}
+
+func (s *AppTestSuite) TestRandomnessOf() {
+	b := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight},
+		Randomness: []byte("b"),
+	}
+	app := NewApp(0, nil, nil)
+	_, exists := app.RandomnessOf(b.Hash)
+	s.Require().False(exists)
+	app.BlockConfirmed(b)
+	app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	rand, exists := app.RandomnessOf(b.Hash)
+	s.Require().True(exists)
+	s.Require().Equal(b.Randomness, rand)
+	// The returned randomness is a copy.
+	rand[0] = 'c'
+	rand, _ = app.RandomnessOf(b.Hash)
+	s.Require().Equal(b.Randomness, rand)
+}
+
+func (s *AppTestSuite) TestDuplicateDeliveries() {
+	b0 := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight},
+		Randomness: []byte("b0"),
+	}
+	b1 := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight + 1},
+		Randomness: []byte("b1"),
+	}
+	app := NewApp(0, nil, nil)
+	s.Require().Empty(app.DuplicateDeliveries())
+	app.BlockConfirmed(b0)
+	app.BlockDelivered(b0.Hash, b0.Position, b0.Randomness)
+	app.BlockConfirmed(b1)
+	app.BlockDelivered(b1.Hash, b1.Position, b1.Randomness)
+	// Deliver b0 again.
+	app.BlockDelivered(b0.Hash, b0.Position, []byte("b0-dup"))
+	s.Require().Equal(common.Hashes{b0.Hash}, app.DuplicateDeliveries())
+	s.Require().Equal(common.Hashes{b0.Hash, b1.Hash}, app.DeliverSequence)
+	rand, exists := app.RandomnessOf(b0.Hash)
+	s.Require().True(exists)
+	s.Require().Equal(b0.Randomness, rand)
+	s.Require().Equal(b1.Position, app.GetLatestDeliveredPosition())
+}
+
+func (s *AppTestSuite) TestDeliveredUnknownBlocks() {
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	b0 := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: types.GenesisHeight},
+	}
+	b1 := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: types.GenesisHeight + 1},
+	}
+	// Only b0 is stored in DB.
+	s.Require().NoError(dbInst.PutBlock(b0))
+	app := NewApp(0, nil, nil)
+	app.AttachDB(dbInst)
+	s.Require().Empty(app.DeliveredUnknownBlocks())
+	app.BlockConfirmed(b0)
+	app.BlockDelivered(b0.Hash, b0.Position, nil)
+	s.Require().Empty(app.DeliveredUnknownBlocks())
+	app.BlockConfirmed(b1)
+	app.BlockDelivered(b1.Hash, b1.Position, nil)
+	s.Require().Equal(common.Hashes{b1.Hash}, app.DeliveredUnknownBlocks())
+	// Nothing is checked without an attached DB.
+	b2 := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: types.GenesisHeight},
+	}
+	app = NewApp(0, nil, nil)
+	app.BlockConfirmed(b2)
+	app.BlockDelivered(b2.Hash, b2.Position, nil)
+	s.Require().Empty(app.DeliveredUnknownBlocks())
+}
+
+func (s *AppTestSuite) TestDeliveryIntervals() {
+	app := NewApp(0, nil, nil)
+	check := func(min, max, mean time.Duration) {
+		actualMin, actualMax, actualMean := app.DeliveryIntervals()
+		s.Require().Equal(min, actualMin)
+		s.Require().Equal(max, actualMax)
+		s.Require().Equal(mean, actualMean)
+	}
+	check(0, 0, 0)
+	// Deliver blocks at known time.
+	begin := time.Now().UTC()
+	offsets := []time.Duration{
+		0, time.Second, 3 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i, offset := range offsets {
+		b := types.Block{
+			Hash: common.NewRandomHash(),
+			Position: types.Position{
+				Height: types.GenesisHeight + uint64(i),
+			},
+			Randomness: []byte{byte(i)},
+		}
+		app.BlockConfirmed(b)
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+		app.Delivered[b.Hash].When = begin.Add(offset)
+		if i == 0 {
+			// Only one block is delivered.
+			check(0, 0, 0)
+		}
+	}
+	check(time.Second, 4*time.Second, 2*time.Second)
+}
+
+func (s *AppTestSuite) TestVerifyWitnessOrder() {
+	app := NewApp(0, nil, nil)
+	b0 := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight},
+		Randomness: []byte("b0"),
+	}
+	b1 := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight + 1},
+		Randomness: []byte("b1"),
+		Witness: types.Witness{
+			Height: b0.Position.Height,
+			Data:   b0.Hash.Bytes(),
+		},
+	}
+	b2 := types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Height: types.GenesisHeight + 2},
+		Witness: types.Witness{
+			Height: b1.Position.Height,
+			Data:   b1.Hash.Bytes(),
+		},
+	}
+	app.BlockConfirmed(b0)
+	app.BlockDelivered(b0.Hash, b0.Position, b0.Randomness)
+	app.BlockConfirmed(b1)
+	app.BlockDelivered(b1.Hash, b1.Position, b1.Randomness)
+	app.BlockConfirmed(b2)
+	s.Require().NoError(app.VerifyWitnessOrder())
+	// b1 is delivered after b2 confirmed with its witness.
+	app.Delivered[b1.Hash].When = app.confirmedTime[b2.Hash].Add(time.Second)
+	s.Require().Equal(ErrWitnessAheadOfDelivery{
+		Hash:      b2.Hash,
+		Witnessed: b1.Hash,
+	}, app.VerifyWitnessOrder())
+	// The first violation is reported.
+	app.Delivered[b0.Hash].When = app.confirmedTime[b1.Hash].Add(time.Second)
+	s.Require().Equal(ErrWitnessAheadOfDelivery{
+		Hash:      b1.Hash,
+		Witnessed: b0.Hash,
+	}, app.VerifyWitnessOrder())
+	// The witnessed block is never delivered.
+	app = NewApp(0, nil, nil)
+	app.BlockConfirmed(b0)
+	app.BlockDelivered(b0.Hash, b0.Position, b0.Randomness)
+	app.BlockConfirmed(b1)
+	b2.Witness.Data = common.NewRandomHash().Bytes()
+	app.BlockConfirmed(b2)
+	s.Require().IsType(ErrWitnessAheadOfDelivery{}, app.VerifyWitnessOrder())
+}
+
+func (s *AppTestSuite) TestDeliverDigest() {
+	now := time.Now().UTC()
+	b0 := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight},
+		Timestamp:  now,
+		Randomness: []byte("b0"),
+	}
+	b1 := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight + 1},
+		Timestamp:  now.Add(time.Second),
+		Randomness: []byte("b1"),
+	}
+	deliver := func(blocks ...types.Block) *App {
+		app := NewApp(0, nil, nil)
+		for _, b := range blocks {
+			app.BlockConfirmed(b)
+			app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+		}
+		return app
+	}
+	app1 := deliver(b0, b1)
+	app2 := deliver(b0, b1)
+	s.Require().Equal(app1.DeliverDigest(), app2.DeliverDigest())
+	s.Require().NotEqual(common.Hash{}, app1.DeliverDigest())
+	// A prefix of the sequence.
+	s.Require().NotEqual(app1.DeliverDigest(), deliver(b0).DeliverDigest())
+	// A different block at the same height.
+	b1Other := b1
+	b1Other.Hash = common.NewRandomHash()
+	s.Require().NotEqual(
+		app1.DeliverDigest(), deliver(b0, b1Other).DeliverDigest())
+	// The same block with a different consensus timestamp.
+	b1Other = b1
+	b1Other.Timestamp = now.Add(2 * time.Second)
+	s.Require().NotEqual(
+		app1.DeliverDigest(), deliver(b0, b1Other).DeliverDigest())
+	s.Require().Equal(common.Hash{}, NewApp(0, nil, nil).DeliverDigest())
+}
+
+func (s *AppTestSuite) TestSaveState() {
+	app := NewApp(0, nil, nil)
+	now := time.Now().UTC()
+	blocks := []types.Block{}
+	for i := 0; i < 3; i++ {
+		b := types.Block{
+			Hash: common.NewRandomHash(),
+			Position: types.Position{
+				Height: types.GenesisHeight + uint64(i),
+			},
+			Timestamp:  now.Add(time.Duration(i) * time.Second),
+			Payload:    []byte{byte(i)},
+			Randomness: []byte{byte(i)},
+		}
+		blocks = append(blocks, b)
+		app.BlockConfirmed(b)
+		app.BlockDelivered(b.Hash, b.Position, b.Randomness)
+	}
+	app.BlockDelivered(blocks[1].Hash, blocks[1].Position, []byte("dup"))
+	s.Require().NoError(app.Verify())
+	buf := &bytes.Buffer{}
+	s.Require().NoError(app.SaveState(buf))
+	loaded, err := LoadAppState(buf)
+	s.Require().NoError(err)
+	s.Require().NoError(loaded.Verify())
+	s.Require().NoError(loaded.VerifyConfirmedDeliverConsistency(0))
+	s.Require().NoError(app.Compare(loaded))
+	s.Require().Equal(app.DeliverDigest(), loaded.DeliverDigest())
+	s.Require().Equal(app.DuplicateDeliveries(), loaded.DuplicateDeliveries())
+	s.Require().Equal(app.LastConfirmedHeight, loaded.LastConfirmedHeight)
+	s.Require().Equal(
+		app.GetLatestDeliveredPosition(), loaded.GetLatestDeliveredPosition())
+	// The loaded instance keeps verifying blocks delivered later.
+	next := types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Height: types.GenesisHeight + 3},
+		Timestamp:  now.Add(-time.Second),
+		Randomness: []byte("next"),
+	}
+	s.Require().Equal(types.VerifyOK, loaded.VerifyBlock(&next))
+	loaded.BlockConfirmed(next)
+	loaded.BlockDelivered(next.Hash, next.Position, next.Randomness)
+	s.Require().Equal(ErrTimestampOutOfOrder, loaded.Verify())
+	// Malformed state.
+	_, err = LoadAppState(bytes.NewBufferString("{"))
+	s.Require().Error(err)
+}
+
 func (s *AppTestSuite) TestWitness() {
 	// Deliver several blocks, there is only one chain only.
 	app := NewApp(0, nil, nil)
@@ -0,0 +1,197 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+)
+
+// Errors returns from DKG driver.
+var (
+	ErrDKGPhaseOutOfRange = errors.New("dkg phase out of range")
+	ErrDKGPhasePassed     = errors.New("dkg phase already passed")
+	ErrDKGPhaseTimeout    = errors.New("dkg phase timeout")
+)
+
+// DKGPhaseReporter reports the count of DKG phases done by a node, or the
+// error failing its DKG. The node is ready to be stepped once reported.
+type DKGPhaseReporter func(done int, err error)
+
+type dkgDriverNode struct {
+	event *common.Event
+	// The count of phases done, -1 before the node is ready.
+	done int
+	err  error
+}
+
+// DKGDriver drives the DKG phases of nodes registered on their common.Event
+// by notifying the heights one phase at a time, instead of notifying the
+// heights of blocks. A phase is triggered only after the previous one is done
+// by all nodes, and phases not yet triggered are held until stepped.
+type DKGDriver struct {
+	lock        sync.Mutex
+	beginHeight uint64
+	offsets     []uint64
+	timeout     time.Duration
+	nodes       []*dkgDriverNode
+	// The count of phases triggered.
+	triggered int
+	// Guard the progress reported by nodes, changed is signaled on each
+	// report.
+	nodesLock sync.Mutex
+	changed   chan struct{}
+}
+
+// NewDKGDriver constructs a DKGDriver. The phases are expected to begin at
+// beginHeight+offsets[i], which should be the same dkgBeginHeight and phase
+// offsets used to run DKG. Stepping fails with ErrDKGPhaseTimeout when a node
+// doesn't report its phase done within timeout.
+func NewDKGDriver(
+	beginHeight uint64, offsets []uint64, timeout time.Duration) *DKGDriver {
+	return &DKGDriver{
+		beginHeight: beginHeight,
+		offsets:     append([]uint64(nil), offsets...),
+		timeout:     timeout,
+		changed:     make(chan struct{}, 1),
+	}
+}
+
+// NewEvent returns the common.Event for a node to run DKG, and the reporter
+// for that node to report its progress. It should be called before stepping
+// any phase.
+func (d *DKGDriver) NewEvent() (*common.Event, DKGPhaseReporter) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	node := &dkgDriverNode{
+		event: common.NewEvent(),
+		done:  -1,
+	}
+	d.nodes = append(d.nodes, node)
+	return node.event, func(done int, err error) {
+		d.nodesLock.Lock()
+		defer d.nodesLock.Unlock()
+		if done > node.done {
+			node.done = done
+		}
+		if err != nil && node.err == nil {
+			node.err = err
+		}
+		select {
+		case d.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Triggered returns the count of phases triggered.
+func (d *DKGDriver) Triggered() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.triggered
+}
+
+// StepTo triggers phases until the given one (inclusive), and holds the
+// phases after it. It returns after that phase is done by all nodes, except
+// the last phase, or once any node fails.
+func (d *DKGDriver) StepTo(phase int) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.stepToNoLock(phase)
+}
+
+// Step triggers the next phase.
+func (d *DKGDriver) Step() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.stepToNoLock(d.triggered)
+}
+
+// Release triggers all remaining phases.
+func (d *DKGDriver) Release() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.triggered == len(d.offsets) {
+		return
+	}
+	d.stepToNoLock(len(d.offsets) - 1)
+}
+
+func (d *DKGDriver) stepToNoLock(phase int) error {
+	if phase < 0 || phase >= len(d.offsets) {
+		return ErrDKGPhaseOutOfRange
+	}
+	if phase < d.triggered {
+		return ErrDKGPhasePassed
+	}
+	// Phases registered after their heights are notified would be held.
+	if d.triggered == 0 {
+		if err := d.waitNodesNoLock(0); err != nil {
+			return err
+		}
+	}
+	for d.triggered <= phase {
+		current := d.triggered
+		for _, node := range d.nodes {
+			node.event.NotifyHeight(d.beginHeight + d.offsets[current])
+		}
+		d.triggered++
+		// Nothing would be done after the last phase to be observed.
+		if d.triggered == len(d.offsets) {
+			break
+		}
+		if err := d.waitNodesNoLock(d.triggered); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitNodesNoLock waits until all nodes report done phases, the error of any
+// node is returned.
+func (d *DKGDriver) waitNodesNoLock(done int) error {
+	timer := time.NewTimer(d.timeout)
+	defer timer.Stop()
+	for {
+		finished, err := func() (bool, error) {
+			d.nodesLock.Lock()
+			defer d.nodesLock.Unlock()
+			finished := true
+			for _, node := range d.nodes {
+				if node.err != nil {
+					return false, node.err
+				}
+				if node.done < done {
+					finished = false
+				}
+			}
+			return finished, nil
+		}()
+		if err != nil || finished {
+			return err
+		}
+		select {
+		case <-d.changed:
+		case <-timer.C:
+			return ErrDKGPhaseTimeout
+		}
+	}
+}
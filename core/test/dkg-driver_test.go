@@ -0,0 +1,92 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DKGDriverTestSuite struct {
+	suite.Suite
+}
+
+func (s *DKGDriverTestSuite) TestStep() {
+	beginHeight := uint64(10)
+	offsets := []uint64{0, 5, 10, 20}
+	nodes := 3
+	driver := NewDKGDriver(beginHeight, offsets, 5*time.Second)
+	lock := sync.Mutex{}
+	phases := make([][]int, nodes)
+	for i := 0; i < nodes; i++ {
+		i := i
+		event, report := driver.NewEvent()
+		for phase, offset := range offsets {
+			phase := phase
+			event.RegisterHeight(beginHeight+offset, func(uint64) {
+				// Phases are done asynchronously.
+				go func() {
+					lock.Lock()
+					defer lock.Unlock()
+					phases[i] = append(phases[i], phase)
+					report(len(phases[i]), nil)
+				}()
+			})
+		}
+		report(0, nil)
+	}
+	s.Require().Equal(0, driver.Triggered())
+	s.Require().NoError(driver.Step())
+	for i := 0; i < nodes; i++ {
+		s.Require().Equal([]int{0}, phases[i])
+	}
+	s.Require().NoError(driver.StepTo(2))
+	for i := 0; i < nodes; i++ {
+		s.Require().Equal([]int{0, 1, 2}, phases[i])
+	}
+	s.Require().Equal(3, driver.Triggered())
+	s.Require().Equal(ErrDKGPhasePassed, driver.StepTo(1))
+	s.Require().Equal(ErrDKGPhaseOutOfRange, driver.StepTo(len(offsets)))
+	driver.Release()
+	s.Require().Equal(len(offsets), driver.Triggered())
+	s.Require().Equal(ErrDKGPhaseOutOfRange, driver.Step())
+}
+
+func (s *DKGDriverTestSuite) TestStepFailed() {
+	offsets := []uint64{0, 5, 10}
+	errFailed := errors.New("failed")
+	driver := NewDKGDriver(0, offsets, 5*time.Second)
+	event, report := driver.NewEvent()
+	event.RegisterHeight(0, func(uint64) {
+		go report(0, errFailed)
+	})
+	report(0, nil)
+	s.Require().Equal(errFailed, driver.Step())
+	// Nodes never reporting their phases fail by timeout.
+	driver = NewDKGDriver(0, offsets, 100*time.Millisecond)
+	driver.NewEvent()
+	s.Require().Equal(ErrDKGPhaseTimeout, driver.Step())
+}
+
+func TestDKGDriver(t *testing.T) {
+	suite.Run(t, new(DKGDriverTestSuite))
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+)
+
+// Errors returns from RunFullDKGTSig.
+var (
+	// ErrInvalidDKGThreshold raised when the threshold is not in [1, n].
+	ErrInvalidDKGThreshold = errors.New("invalid dkg threshold")
+	// ErrIncorrectDKGPrivateShare raised when a private share doesn't match
+	// the master public key of its proposer.
+	ErrIncorrectDKGPrivateShare = errors.New("incorrect dkg private share")
+	// ErrMismatchDKGPublicKey raised when the public key recovered from
+	// received private shares doesn't match the one recovered from master
+	// public keys.
+	ErrMismatchDKGPublicKey = errors.New("mismatch dkg public key")
+	// ErrIncorrectDKGPartialSignature raised when a partial signature can't
+	// be verified by the public key of its signer.
+	ErrIncorrectDKGPartialSignature = errors.New(
+		"incorrect dkg partial signature")
+	// ErrIncorrectTSig raised when a threshold signature can't be verified
+	// by the group public key.
+	ErrIncorrectTSig = errors.New("incorrect threshold signature")
+	// ErrMismatchTSig raised when threshold signatures recovered from
+	// different signers are not identical.
+	ErrMismatchTSig = errors.New("mismatch threshold signature")
+	// ErrTSigBelowThreshold raised when a threshold signature recovered from
+	// less than threshold partial signatures could be verified.
+	ErrTSigBelowThreshold = errors.New("threshold signature below threshold")
+)
+
+// RunFullDKGTSig runs DKG among n nodes with threshold k, in which every node
+// is honest, then recovers a threshold signature of a random hash from
+// partial signatures and verifies it by the group public key. Any
+// inconsistency found during the process is returned as an error.
+func RunFullDKGTSig(k, n int, round uint64) error {
+	if k <= 0 || k > n {
+		return ErrInvalidDKGThreshold
+	}
+	nIDs := GenerateRandomNodeIDs(n)
+	ids := make(dkg.IDs, 0, n)
+	for _, nID := range nIDs {
+		ids = append(ids, typesDKG.NewID(nID))
+	}
+	// Propose master public keys.
+	mpks := make([]*typesDKG.MasterPublicKey, 0, n)
+	prvShares := make([]*dkg.PrivateKeyShares, 0, n)
+	for i, nID := range nIDs {
+		prvShare, pubShare := dkg.NewPrivateKeyShares(k)
+		prvShare.SetParticipants(ids)
+		prvShares = append(prvShares, prvShare)
+		mpks = append(mpks, &typesDKG.MasterPublicKey{
+			ProposerID:      nID,
+			Round:           round,
+			DKGID:           ids[i],
+			PublicKeyShares: *pubShare.Move(),
+		})
+	}
+	// Exchange private shares.
+	received := make([]*dkg.PrivateKeyShares, 0, n)
+	for _, receiverID := range ids {
+		shares := dkg.NewEmptyPrivateKeyShares()
+		for i, prvShare := range prvShares {
+			share, exist := prvShare.Share(receiverID)
+			if !exist {
+				return ErrIncorrectDKGPrivateShare
+			}
+			ok, err := mpks[i].PublicKeyShares.VerifyPrvShare(receiverID, share)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrIncorrectDKGPrivateShare
+			}
+			if err = shares.AddShare(ids[i], share); err != nil {
+				return err
+			}
+		}
+		received = append(received, shares)
+	}
+	npks, err := typesDKG.NewNodePublicKeys(round, mpks, nil, k)
+	if err != nil {
+		return err
+	}
+	gpk, err := typesDKG.NewGroupPublicKey(round, mpks, nil, k)
+	if err != nil {
+		return err
+	}
+	// Sign a random hash with private keys recovered by each node.
+	hash := common.NewRandomHash()
+	psigs := make([]dkg.PartialSignature, 0, n)
+	for i, nID := range nIDs {
+		prvKey, err := received[i].RecoverPrivateKey(npks.QualifyIDs)
+		if err != nil {
+			return err
+		}
+		recoveredPubKey, err := received[i].RecoverPublicKey(npks.QualifyIDs)
+		if err != nil {
+			return err
+		}
+		pubKey, exist := npks.PublicKeys[nID]
+		if !exist || !bytes.Equal(pubKey.Bytes(), recoveredPubKey.Bytes()) {
+			return ErrMismatchDKGPublicKey
+		}
+		sig, err := prvKey.Sign(hash)
+		if err != nil {
+			return err
+		}
+		if !pubKey.VerifySignature(hash, sig) {
+			return ErrIncorrectDKGPartialSignature
+		}
+		psigs = append(psigs, dkg.PartialSignature(sig))
+	}
+	// Any k partial signatures should recover the same threshold signature.
+	recoverTSig := func(signers []int) (crypto.Signature, error) {
+		sigs := make([]dkg.PartialSignature, 0, len(signers))
+		signerIDs := make(dkg.IDs, 0, len(signers))
+		for _, i := range signers {
+			sigs = append(sigs, psigs[i])
+			signerIDs = append(signerIDs, ids[i])
+		}
+		return dkg.RecoverSignature(sigs, signerIDs)
+	}
+	var expected crypto.Signature
+	for trial := 0; trial < 2; trial++ {
+		signers := rand.Perm(n)[:k]
+		tsig, err := recoverTSig(signers)
+		if err != nil {
+			return err
+		}
+		if !gpk.VerifySignature(hash, tsig) {
+			return ErrIncorrectTSig
+		}
+		if trial == 0 {
+			expected = tsig
+		} else if !bytes.Equal(expected.Signature, tsig.Signature) {
+			return ErrMismatchTSig
+		}
+	}
+	// Partial signatures less than threshold should not be enough.
+	if k > 1 {
+		tsig, err := recoverTSig(rand.Perm(n)[:k-1])
+		if err == nil && gpk.VerifySignature(hash, tsig) {
+			return ErrTSigBelowThreshold
+		}
+	}
+	return nil
+}
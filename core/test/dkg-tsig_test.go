@@ -0,0 +1,45 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DKGTSigTestSuite struct {
+	suite.Suite
+}
+
+func (s *DKGTSigTestSuite) TestRunFullDKGTSig() {
+	for _, c := range []struct{ k, n int }{
+		{1, 1}, {1, 4}, {2, 3}, {2, 4}, {3, 4}, {4, 4},
+		{3, 7}, {5, 7}, {7, 7}, {5, 13}, {9, 13}, {11, 31},
+	} {
+		s.Require().NoError(RunFullDKGTSig(c.k, c.n, 1),
+			fmt.Sprintf("k=%d n=%d", c.k, c.n))
+	}
+	s.Require().Equal(ErrInvalidDKGThreshold, RunFullDKGTSig(0, 4, 1))
+	s.Require().Equal(ErrInvalidDKGThreshold, RunFullDKGTSig(5, 4, 1))
+}
+
+func TestDKGTSig(t *testing.T) {
+	suite.Run(t, new(DKGTSigTestSuite))
+}
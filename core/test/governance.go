@@ -32,6 +32,27 @@ import (
 	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 )
 
+var (
+	// ErrMismatchDKGRound means the round of a DKG message is not the round
+	// to be pre-seeded.
+	ErrMismatchDKGRound = errors.New("mismatch DKG round")
+	// ErrMismatchDKGReset means the DKG reset count of a snapshot is not the
+	// one of the state to replay it.
+	ErrMismatchDKGReset = errors.New("mismatch DKG reset")
+)
+
+// DKGGovSnapshot is the DKG state of one round recorded from a Governance
+// instance, private shares are not included since they are not sent to
+// governance.
+type DKGGovSnapshot struct {
+	Round            uint64
+	Reset            uint64
+	MasterPublicKeys []*typesDKG.MasterPublicKey
+	Complaints       []*typesDKG.Complaint
+	MPKReadys        []*typesDKG.MPKReady
+	Finalizes        []*typesDKG.Finalize
+}
+
 // TODO(mission): add a method to compare config/crs between governance
 //                instances.
 
@@ -61,6 +82,81 @@ func NewGovernance(state *State, roundShift uint64) (g *Governance, err error) {
 	return
 }
 
+// NewGovernanceWithDKG constructs a Governance instance with DKG master public
+// keys and complaints of one round already added, so tests could jump to a
+// specific phase without driving the whole DKG protocol.
+func NewGovernanceWithDKG(
+	state *State,
+	roundShift, round uint64,
+	mpks []*typesDKG.MasterPublicKey,
+	complaints []*typesDKG.Complaint) (g *Governance, err error) {
+	for _, mpk := range mpks {
+		if mpk.Round != round {
+			return nil, ErrMismatchDKGRound
+		}
+	}
+	for _, comp := range complaints {
+		if comp.Round != round {
+			return nil, ErrMismatchDKGRound
+		}
+	}
+	if g, err = NewGovernance(state, roundShift); err != nil {
+		return
+	}
+	for _, mpk := range mpks {
+		if err = state.RequestChange(
+			StateAddDKGMasterPublicKey, CloneDKGMasterPublicKey(mpk)); err != nil {
+			return nil, err
+		}
+	}
+	for _, comp := range complaints {
+		if err = state.RequestChange(
+			StateAddDKGComplaint, CloneDKGComplaint(comp)); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// NewGovernanceFromDKGSnapshot constructs a Governance instance with the DKG
+// state recorded in a snapshot, the DKG reset count of the state should be
+// identical to the snapshot.
+func NewGovernanceFromDKGSnapshot(
+	state *State,
+	roundShift uint64,
+	snapshot *DKGGovSnapshot) (g *Governance, err error) {
+	if state.DKGResetCount(snapshot.Round) != snapshot.Reset {
+		return nil, ErrMismatchDKGReset
+	}
+	for _, ready := range snapshot.MPKReadys {
+		if ready.Round != snapshot.Round {
+			return nil, ErrMismatchDKGRound
+		}
+	}
+	for _, final := range snapshot.Finalizes {
+		if final.Round != snapshot.Round {
+			return nil, ErrMismatchDKGRound
+		}
+	}
+	if g, err = NewGovernanceWithDKG(state, roundShift, snapshot.Round,
+		snapshot.MasterPublicKeys, snapshot.Complaints); err != nil {
+		return
+	}
+	for _, ready := range snapshot.MPKReadys {
+		if err = state.RequestChange(
+			StateAddDKGMPKReady, CloneDKGMPKReady(ready)); err != nil {
+			return nil, err
+		}
+	}
+	for _, final := range snapshot.Finalizes {
+		if err = state.RequestChange(
+			StateAddDKGFinal, CloneDKGFinalize(final)); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
 // NodeSet implements Governance interface to return current
 // notary set.
 func (g *Governance) NodeSet(round uint64) []crypto.PublicKey {
@@ -324,6 +420,19 @@ func (g *Governance) DKGResetCount(round uint64) uint64 {
 	return g.stateModule.DKGResetCount(round)
 }
 
+// SnapshotDKG records current DKG state of one round, which could be replayed
+// by NewGovernanceFromDKGSnapshot.
+func (g *Governance) SnapshotDKG(round uint64) *DKGGovSnapshot {
+	return &DKGGovSnapshot{
+		Round:            round,
+		Reset:            g.stateModule.DKGResetCount(round),
+		MasterPublicKeys: g.stateModule.DKGMasterPublicKeys(round),
+		Complaints:       g.stateModule.DKGComplaints(round),
+		MPKReadys:        g.stateModule.DKGMPKReadys(round),
+		Finalizes:        g.stateModule.DKGFinalizes(round),
+	}
+}
+
 //
 // Test Utilities
 //
@@ -482,7 +591,7 @@ func (g *Governance) Equal(other *Governance, checkState bool) bool {
 // NOTE: this function should be called before running.
 func (g *Governance) RegisterConfigChange(
 	round uint64, t StateChangeType, v interface{}) (err error) {
-	if t < StateAddCRS || t > StateChangeNotarySetSize {
+	if t < StateAddCRS || t > StateChangeDKGFinalizePhase {
 		return fmt.Errorf("state changes to register is not supported: %v", t)
 	}
 	if round < 2 {
@@ -183,6 +183,94 @@ func (s *GovernanceTestSuite) TestProhibit() {
 	s.Require().True(gov.IsDKGFinal(round))
 }
 
+func (s *GovernanceTestSuite) TestNewGovernanceWithDKG() {
+	round := uint64(1)
+	prvKeys, genesisNodes, err := NewKeys(4)
+	s.Require().NoError(err)
+	newState := func() *State {
+		return NewState(
+			1, genesisNodes, 100*time.Millisecond, &common.NullLogger{}, true)
+	}
+	mpks := make([]*typesDKG.MasterPublicKey, 0, len(prvKeys))
+	for _, k := range prvKeys {
+		_, pubShare := dkg.NewPrivateKeyShares(2)
+		mpk := &typesDKG.MasterPublicKey{
+			Round:           round,
+			DKGID:           typesDKG.NewID(types.NewNodeID(k.PublicKey())),
+			PublicKeyShares: *pubShare.Move(),
+		}
+		s.Require().NoError(utils.NewSigner(k).SignDKGMasterPublicKey(mpk))
+		mpks = append(mpks, mpk)
+	}
+	comp := &typesDKG.Complaint{
+		Round: round,
+		PrivateShare: typesDKG.PrivateShare{
+			ProposerID: types.NewNodeID(prvKeys[1].PublicKey()),
+			Round:      round,
+		},
+	}
+	s.Require().NoError(utils.NewSigner(prvKeys[0]).SignDKGComplaint(comp))
+	gov, err := NewGovernanceWithDKG(
+		newState(), 2, round, mpks, []*typesDKG.Complaint{comp})
+	s.Require().NoError(err)
+	s.Require().Len(gov.DKGMasterPublicKeys(round), len(mpks))
+	comps := gov.DKGComplaints(round)
+	s.Require().Len(comps, 1)
+	s.Require().True(comps[0].Equal(comp))
+	s.Require().Empty(gov.DKGMasterPublicKeys(round + 1))
+	// DKG messages of other rounds are not accepted.
+	_, err = NewGovernanceWithDKG(newState(), 2, round+1, mpks, nil)
+	s.Require().Equal(ErrMismatchDKGRound, err)
+}
+
+func (s *GovernanceTestSuite) TestDKGSnapshot() {
+	round := uint64(1)
+	prvKeys, genesisNodes, err := NewKeys(4)
+	s.Require().NoError(err)
+	newState := func() *State {
+		return NewState(
+			1, genesisNodes, 100*time.Millisecond, &common.NullLogger{}, true)
+	}
+	gov, err := NewGovernance(newState(), 2)
+	s.Require().NoError(err)
+	for _, k := range prvKeys {
+		_, pubShare := dkg.NewPrivateKeyShares(2)
+		mpk := &typesDKG.MasterPublicKey{
+			Round:           round,
+			DKGID:           typesDKG.NewID(types.NewNodeID(k.PublicKey())),
+			PublicKeyShares: *pubShare.Move(),
+		}
+		s.Require().NoError(utils.NewSigner(k).SignDKGMasterPublicKey(mpk))
+		gov.AddDKGMasterPublicKey(mpk)
+	}
+	for _, k := range prvKeys {
+		ready := &typesDKG.MPKReady{Round: round}
+		s.Require().NoError(utils.NewSigner(k).SignDKGMPKReady(ready))
+		gov.AddDKGMPKReady(ready)
+	}
+	final := &typesDKG.Finalize{Round: round}
+	s.Require().NoError(utils.NewSigner(prvKeys[0]).SignDKGFinalize(final))
+	gov.AddDKGFinalize(final)
+	snapshot := gov.SnapshotDKG(round)
+	s.Require().Equal(round, snapshot.Round)
+	s.Require().Len(snapshot.MasterPublicKeys, len(prvKeys))
+	s.Require().Len(snapshot.MPKReadys, len(prvKeys))
+	s.Require().Len(snapshot.Finalizes, 1)
+	replayed, err := NewGovernanceFromDKGSnapshot(newState(), 2, snapshot)
+	s.Require().NoError(err)
+	replayedSnapshot := replayed.SnapshotDKG(round)
+	s.Require().ElementsMatch(
+		snapshot.MasterPublicKeys, replayedSnapshot.MasterPublicKeys)
+	s.Require().ElementsMatch(snapshot.MPKReadys, replayedSnapshot.MPKReadys)
+	s.Require().ElementsMatch(snapshot.Finalizes, replayedSnapshot.Finalizes)
+	s.Require().True(replayed.IsDKGMPKReady(round))
+	s.Require().False(replayed.IsDKGFinal(round))
+	// Snapshot of another DKG reset is not accepted.
+	snapshot.Reset++
+	_, err = NewGovernanceFromDKGSnapshot(newState(), 2, snapshot)
+	s.Require().Equal(ErrMismatchDKGReset, err)
+}
+
 func TestGovernance(t *testing.T) {
 	suite.Run(t, new(GovernanceTestSuite))
 }
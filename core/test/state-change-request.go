@@ -48,6 +48,11 @@ const (
 	StateChangeRoundLength
 	StateChangeMinBlockInterval
 	StateChangeNotarySetSize
+	StateChangeDKGComplaintWindow
+	StateChangeMinDKGParticipants
+	StateChangeDKGMPKPhase
+	StateChangeDKGSharePhase
+	StateChangeDKGFinalizePhase
 	// Node set related.
 	StateAddNode
 )
@@ -76,6 +81,16 @@ func (t StateChangeType) String() string {
 		return "ChangeMinBlockInterval"
 	case StateChangeNotarySetSize:
 		return "ChangeNotarySetSize"
+	case StateChangeDKGComplaintWindow:
+		return "ChangeDKGComplaintWindow"
+	case StateChangeMinDKGParticipants:
+		return "ChangeMinDKGParticipants"
+	case StateChangeDKGMPKPhase:
+		return "ChangeDKGMPKPhase"
+	case StateChangeDKGSharePhase:
+		return "ChangeDKGSharePhase"
+	case StateChangeDKGFinalizePhase:
+		return "ChangeDKGFinalizePhase"
 	case StateAddNode:
 		return "AddNode"
 	}
@@ -193,6 +208,13 @@ func (req *StateChangeRequest) String() (ret string) {
 		ret += fmt.Sprintf("%v", time.Duration(req.Payload.(uint64)))
 	case StateChangeNotarySetSize:
 		ret += fmt.Sprintf("%v", req.Payload.(uint32))
+	case StateChangeDKGComplaintWindow:
+		ret += fmt.Sprintf("%v", time.Duration(req.Payload.(uint64)))
+	case StateChangeMinDKGParticipants:
+		ret += fmt.Sprintf("%v", req.Payload.(uint32))
+	case StateChangeDKGMPKPhase, StateChangeDKGSharePhase,
+		StateChangeDKGFinalizePhase:
+		ret += fmt.Sprintf("%v", time.Duration(req.Payload.(uint64)))
 	case StateAddNode:
 		ret += fmt.Sprintf(
 			"%s", types.NewNodeID(req.Payload.(crypto.PublicKey)).String()[:6])
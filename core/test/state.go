@@ -94,11 +94,16 @@ type crsAdditionRequest struct {
 // State emulates what the global state in governace contract on a fullnode.
 type State struct {
 	// Configuration related.
-	lambdaBA         time.Duration
-	lambdaDKG        time.Duration
-	notarySetSize    uint32
-	roundInterval    uint64
-	minBlockInterval time.Duration
+	lambdaBA           time.Duration
+	lambdaDKG          time.Duration
+	dkgComplaintWindow time.Duration
+	notarySetSize      uint32
+	minDKGParticipants uint32
+	dkgMPKPhase        time.Duration
+	dkgSharePhase      time.Duration
+	dkgFinalizePhase   time.Duration
+	roundInterval      uint64
+	minBlockInterval   time.Duration
 	// Nodes
 	nodes map[types.NodeID]crypto.PublicKey
 	// DKG & CRS
@@ -184,11 +189,16 @@ func (s *State) Snapshot() (*types.Config, []crypto.PublicKey) {
 		nodes = append(nodes, key)
 	}
 	cfg := &types.Config{
-		LambdaBA:         s.lambdaBA,
-		LambdaDKG:        s.lambdaDKG,
-		NotarySetSize:    s.notarySetSize,
-		RoundLength:      s.roundInterval,
-		MinBlockInterval: s.minBlockInterval,
+		LambdaBA:           s.lambdaBA,
+		LambdaDKG:          s.lambdaDKG,
+		DKGComplaintWindow: s.dkgComplaintWindow,
+		NotarySetSize:      s.notarySetSize,
+		MinDKGParticipants: s.minDKGParticipants,
+		DKGMPKPhase:        s.dkgMPKPhase,
+		DKGSharePhase:      s.dkgSharePhase,
+		DKGFinalizePhase:   s.dkgFinalizePhase,
+		RoundLength:        s.roundInterval,
+		MinBlockInterval:   s.minBlockInterval,
 	}
 	s.logger.Info("Snapshot config", "config", cfg)
 	return cfg, nodes
@@ -240,10 +250,15 @@ func (s *State) unpackPayload(
 		var tmp uint64
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
 		v = tmp
-	case StateChangeNotarySetSize:
+	case StateChangeNotarySetSize, StateChangeMinDKGParticipants:
 		var tmp uint32
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
 		v = tmp
+	case StateChangeDKGComplaintWindow, StateChangeDKGMPKPhase,
+		StateChangeDKGSharePhase, StateChangeDKGFinalizePhase:
+		var tmp uint64
+		err = rlp.DecodeBytes(raw.Payload, &tmp)
+		v = tmp
 	case StateAddNode:
 		var tmp []byte
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
@@ -284,7 +299,12 @@ func (s *State) Equal(other *State) error {
 	// Check configuration part.
 	configEqual := s.lambdaBA == other.lambdaBA &&
 		s.lambdaDKG == other.lambdaDKG &&
+		s.dkgComplaintWindow == other.dkgComplaintWindow &&
 		s.notarySetSize == other.notarySetSize &&
+		s.minDKGParticipants == other.minDKGParticipants &&
+		s.dkgMPKPhase == other.dkgMPKPhase &&
+		s.dkgSharePhase == other.dkgSharePhase &&
+		s.dkgFinalizePhase == other.dkgFinalizePhase &&
 		s.roundInterval == other.roundInterval &&
 		s.minBlockInterval == other.minBlockInterval
 	if !configEqual {
@@ -472,14 +492,19 @@ func (s *State) Equal(other *State) error {
 func (s *State) Clone() (copied *State) {
 	// Clone configuration parts.
 	copied = &State{
-		lambdaBA:         s.lambdaBA,
-		lambdaDKG:        s.lambdaDKG,
-		notarySetSize:    s.notarySetSize,
-		roundInterval:    s.roundInterval,
-		minBlockInterval: s.minBlockInterval,
-		local:            s.local,
-		logger:           s.logger,
-		nodes:            make(map[types.NodeID]crypto.PublicKey),
+		lambdaBA:           s.lambdaBA,
+		lambdaDKG:          s.lambdaDKG,
+		dkgComplaintWindow: s.dkgComplaintWindow,
+		notarySetSize:      s.notarySetSize,
+		minDKGParticipants: s.minDKGParticipants,
+		dkgMPKPhase:        s.dkgMPKPhase,
+		dkgSharePhase:      s.dkgSharePhase,
+		dkgFinalizePhase:   s.dkgFinalizePhase,
+		roundInterval:      s.roundInterval,
+		minBlockInterval:   s.minBlockInterval,
+		local:              s.local,
+		logger:             s.logger,
+		nodes:              make(map[types.NodeID]crypto.PublicKey),
 		dkgComplaints: make(
 			map[uint64]map[types.NodeID][]*typesDKG.Complaint),
 		dkgMasterPublicKeys: make(
@@ -816,6 +841,16 @@ func (s *State) applyRequest(req *StateChangeRequest) error {
 		s.minBlockInterval = time.Duration(req.Payload.(uint64))
 	case StateChangeNotarySetSize:
 		s.notarySetSize = req.Payload.(uint32)
+	case StateChangeDKGComplaintWindow:
+		s.dkgComplaintWindow = time.Duration(req.Payload.(uint64))
+	case StateChangeMinDKGParticipants:
+		s.minDKGParticipants = req.Payload.(uint32)
+	case StateChangeDKGMPKPhase:
+		s.dkgMPKPhase = time.Duration(req.Payload.(uint64))
+	case StateChangeDKGSharePhase:
+		s.dkgSharePhase = time.Duration(req.Payload.(uint64))
+	case StateChangeDKGFinalizePhase:
+		s.dkgFinalizePhase = time.Duration(req.Payload.(uint64))
 	default:
 		return errors.New("you are definitely kidding me")
 	}
@@ -841,7 +876,11 @@ func (s *State) RequestChange(
 		payload = payload.(crypto.PublicKey).Bytes()
 	case StateChangeLambdaBA,
 		StateChangeLambdaDKG,
-		StateChangeMinBlockInterval:
+		StateChangeMinBlockInterval,
+		StateChangeDKGComplaintWindow,
+		StateChangeDKGMPKPhase,
+		StateChangeDKGSharePhase,
+		StateChangeDKGFinalizePhase:
 		payload = uint64(payload.(time.Duration))
 	// These cases for for type assertion, make sure callers pass expected types.
 	case StateAddCRS:
@@ -921,6 +960,28 @@ func (s *State) DKGMasterPublicKeys(round uint64) []*typesDKG.MasterPublicKey {
 	return mpks
 }
 
+// DKGMPKReadys access current received dkg MPK readys for that round.
+func (s *State) DKGMPKReadys(round uint64) []*typesDKG.MPKReady {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	readys := make([]*typesDKG.MPKReady, 0, len(s.dkgReadys[round]))
+	for _, ready := range s.dkgReadys[round] {
+		readys = append(readys, CloneDKGMPKReady(ready))
+	}
+	return readys
+}
+
+// DKGFinalizes access current received dkg finalizes for that round.
+func (s *State) DKGFinalizes(round uint64) []*typesDKG.Finalize {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	finals := make([]*typesDKG.Finalize, 0, len(s.dkgFinals[round]))
+	for _, final := range s.dkgFinals[round] {
+		finals = append(finals, CloneDKGFinalize(final))
+	}
+	return finals
+}
+
 // IsDKGMPKReady checks if current received dkg readys exceeds threshold.
 // This information won't be snapshot, thus can't be cached in test.Governance.
 func (s *State) IsDKGMPKReady(round uint64, threshold int) bool {
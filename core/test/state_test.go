@@ -147,6 +147,7 @@ func (s *StateTestSuite) makeConfigChanges(st *State) {
 	st.RequestChange(StateChangeRoundLength, uint64(1001))
 	st.RequestChange(StateChangeMinBlockInterval, time.Second)
 	st.RequestChange(StateChangeNotarySetSize, uint32(5))
+	st.RequestChange(StateChangeMinDKGParticipants, uint32(4))
 }
 
 func (s *StateTestSuite) checkConfigChanges(config *types.Config) {
@@ -156,6 +157,7 @@ func (s *StateTestSuite) checkConfigChanges(config *types.Config) {
 	req.Equal(config.RoundLength, uint64(1001))
 	req.Equal(config.MinBlockInterval, time.Second)
 	req.Equal(config.NotarySetSize, uint32(5))
+	req.Equal(config.MinDKGParticipants, uint32(4))
 }
 
 func (s *StateTestSuite) TestEqual() {
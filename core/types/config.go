@@ -27,9 +27,21 @@ type Config struct {
 	// Lambda related.
 	LambdaBA  time.Duration
 	LambdaDKG time.Duration
+	// DKGComplaintWindow is the duration to propose complaints in DKG, zero
+	// means 2*LambdaDKG.
+	DKGComplaintWindow time.Duration
+	// DKGMPKPhase, DKGSharePhase and DKGFinalizePhase are the durations to
+	// collect master public keys, exchange private shares and finalize in
+	// DKG, zero means LambdaDKG.
+	DKGMPKPhase      time.Duration
+	DKGSharePhase    time.Duration
+	DKGFinalizePhase time.Duration
 
 	// Set related.
 	NotarySetSize uint32
+	// MinDKGParticipants is the minimum count of notary set members proposing
+	// master public keys for DKG to proceed, zero means the DKG threshold.
+	MinDKGParticipants uint32
 
 	// Time related.
 	RoundLength      uint64
@@ -39,15 +51,22 @@ type Config struct {
 // Clone return a copied configuration.
 func (c *Config) Clone() *Config {
 	return &Config{
-		LambdaBA:         c.LambdaBA,
-		LambdaDKG:        c.LambdaDKG,
-		NotarySetSize:    c.NotarySetSize,
-		RoundLength:      c.RoundLength,
-		MinBlockInterval: c.MinBlockInterval,
+		LambdaBA:           c.LambdaBA,
+		LambdaDKG:          c.LambdaDKG,
+		DKGComplaintWindow: c.DKGComplaintWindow,
+		DKGMPKPhase:        c.DKGMPKPhase,
+		DKGSharePhase:      c.DKGSharePhase,
+		DKGFinalizePhase:   c.DKGFinalizePhase,
+		NotarySetSize:      c.NotarySetSize,
+		MinDKGParticipants: c.MinDKGParticipants,
+		RoundLength:        c.RoundLength,
+		MinBlockInterval:   c.MinBlockInterval,
 	}
 }
 
-// Bytes returns []byte representation of Config.
+// Bytes returns []byte representation of Config. Fields of DKG phases and
+// MinDKGParticipants are appended after MinBlockInterval only when any of
+// them is set, so configs without them keep their encoding.
 func (c *Config) Bytes() []byte {
 	binaryLambdaBA := make([]byte, 8)
 	binary.LittleEndian.PutUint64(
@@ -65,11 +84,70 @@ func (c *Config) Bytes() []byte {
 	binary.LittleEndian.PutUint64(binaryMinBlockInterval,
 		uint64(c.MinBlockInterval.Nanoseconds()))
 
-	enc := make([]byte, 0, 40)
+	enc := make([]byte, 0, 72)
 	enc = append(enc, binaryLambdaBA...)
 	enc = append(enc, binaryLambdaDKG...)
 	enc = append(enc, binaryNotarySetSize...)
 	enc = append(enc, binaryRoundLength...)
 	enc = append(enc, binaryMinBlockInterval...)
+	if c.DKGComplaintWindow == 0 && c.DKGMPKPhase == 0 &&
+		c.DKGSharePhase == 0 && c.DKGFinalizePhase == 0 &&
+		c.MinDKGParticipants == 0 {
+		return enc
+	}
+
+	binaryDKGComplaintWindow := make([]byte, 8)
+	binary.LittleEndian.PutUint64(
+		binaryDKGComplaintWindow, uint64(c.DKGComplaintWindow.Nanoseconds()))
+	binaryDKGMPKPhase := make([]byte, 8)
+	binary.LittleEndian.PutUint64(
+		binaryDKGMPKPhase, uint64(c.DKGMPKPhase.Nanoseconds()))
+	binaryDKGSharePhase := make([]byte, 8)
+	binary.LittleEndian.PutUint64(
+		binaryDKGSharePhase, uint64(c.DKGSharePhase.Nanoseconds()))
+	binaryDKGFinalizePhase := make([]byte, 8)
+	binary.LittleEndian.PutUint64(
+		binaryDKGFinalizePhase, uint64(c.DKGFinalizePhase.Nanoseconds()))
+	binaryMinDKGParticipants := make([]byte, 4)
+	binary.LittleEndian.PutUint32(
+		binaryMinDKGParticipants, c.MinDKGParticipants)
+
+	enc = append(enc, binaryDKGComplaintWindow...)
+	enc = append(enc, binaryDKGMPKPhase...)
+	enc = append(enc, binaryDKGSharePhase...)
+	enc = append(enc, binaryDKGFinalizePhase...)
+	enc = append(enc, binaryMinDKGParticipants...)
 	return enc
 }
+
+// ComplaintWindow returns the duration to propose complaints in DKG.
+func (c *Config) ComplaintWindow() time.Duration {
+	if c.DKGComplaintWindow == 0 {
+		return 2 * c.LambdaDKG
+	}
+	return c.DKGComplaintWindow
+}
+
+// MPKPhase returns the duration to collect master public keys in DKG.
+func (c *Config) MPKPhase() time.Duration {
+	if c.DKGMPKPhase == 0 {
+		return c.LambdaDKG
+	}
+	return c.DKGMPKPhase
+}
+
+// SharePhase returns the duration to exchange private shares in DKG.
+func (c *Config) SharePhase() time.Duration {
+	if c.DKGSharePhase == 0 {
+		return c.LambdaDKG
+	}
+	return c.DKGSharePhase
+}
+
+// FinalizePhase returns the duration to finalize DKG.
+func (c *Config) FinalizePhase() time.Duration {
+	if c.DKGFinalizePhase == 0 {
+		return c.LambdaDKG
+	}
+	return c.DKGFinalizePhase
+}
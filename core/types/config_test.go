@@ -29,6 +29,22 @@ type ConfigTestSuite struct {
 }
 
 func (s *ConfigTestSuite) TestClone() {
+	c := &Config{
+		LambdaBA:           1 * time.Millisecond,
+		LambdaDKG:          2 * time.Hour,
+		DKGComplaintWindow: 5 * time.Hour,
+		DKGMPKPhase:        6 * time.Hour,
+		DKGSharePhase:      7 * time.Hour,
+		DKGFinalizePhase:   8 * time.Hour,
+		NotarySetSize:      5,
+		MinDKGParticipants: 3,
+		RoundLength:        1000,
+		MinBlockInterval:   7 * time.Nanosecond,
+	}
+	s.Require().Equal(c, c.Clone())
+}
+
+func (s *ConfigTestSuite) TestBytes() {
 	c := &Config{
 		LambdaBA:         1 * time.Millisecond,
 		LambdaDKG:        2 * time.Hour,
@@ -36,7 +52,42 @@ func (s *ConfigTestSuite) TestClone() {
 		RoundLength:      1000,
 		MinBlockInterval: 7 * time.Nanosecond,
 	}
-	s.Require().Equal(c, c.Clone())
+	// Configs without DKG phases and MinDKGParticipants keep their encoding.
+	legacy := c.Bytes()
+	s.Require().Len(legacy, 36)
+	s.Require().Equal([]byte{0x40, 0x42, 0x0f, 0, 0, 0, 0, 0}, legacy[:8])
+	s.Require().Equal([]byte{5, 0, 0, 0}, legacy[16:20])
+	s.Require().Equal([]byte{7, 0, 0, 0, 0, 0, 0, 0}, legacy[28:])
+	// Those fields are appended after MinBlockInterval when set.
+	c.MinDKGParticipants = 3
+	enc := c.Bytes()
+	s.Require().Len(enc, 72)
+	s.Require().Equal(legacy, enc[:36])
+	s.Require().Equal([]byte{3, 0, 0, 0}, enc[68:])
+	c.MinDKGParticipants = 0
+	c.DKGComplaintWindow = 5 * time.Hour
+	s.Require().NotEqual(enc, c.Bytes())
+	s.Require().Equal(legacy, c.Bytes()[:36])
+}
+
+func (s *ConfigTestSuite) TestComplaintWindow() {
+	c := &Config{LambdaDKG: 2 * time.Hour}
+	s.Require().Equal(4*time.Hour, c.ComplaintWindow())
+	c.DKGComplaintWindow = 5 * time.Hour
+	s.Require().Equal(5*time.Hour, c.ComplaintWindow())
+}
+
+func (s *ConfigTestSuite) TestDKGPhases() {
+	c := &Config{LambdaDKG: 2 * time.Hour}
+	s.Require().Equal(2*time.Hour, c.MPKPhase())
+	s.Require().Equal(2*time.Hour, c.SharePhase())
+	s.Require().Equal(2*time.Hour, c.FinalizePhase())
+	c.DKGMPKPhase = 3 * time.Hour
+	c.DKGSharePhase = 4 * time.Hour
+	c.DKGFinalizePhase = 5 * time.Hour
+	s.Require().Equal(3*time.Hour, c.MPKPhase())
+	s.Require().Equal(4*time.Hour, c.SharePhase())
+	s.Require().Equal(5*time.Hour, c.FinalizePhase())
 }
 
 func TestConfig(t *testing.T) {
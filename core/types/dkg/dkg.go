@@ -35,6 +35,7 @@ import (
 var (
 	ErrNotReachThreshold = fmt.Errorf("threshold not reach")
 	ErrInvalidThreshold  = fmt.Errorf("invalid threshold")
+	ErrUnknownMessage    = fmt.Errorf("unknown message")
 )
 
 // NewID creates a DKGID from NodeID.
@@ -348,12 +349,15 @@ func (gpk *GroupPublicKey) VerifySignature(
 func CalcQualifyNodes(
 	mpks []*MasterPublicKey, complaints []*Complaint, threshold int) (
 	qualifyIDs cryptoDKG.IDs, qualifyNodeIDs map[types.NodeID]struct{}, err error) {
-	if len(mpks) < threshold {
-		err = ErrInvalidThreshold
-		return
-	}
+	return CalcQualifyNodesWithDisqualified(
+		mpks, CalcDisqualifyNodes(complaints, threshold), threshold)
+}
 
-	// Calculate qualify members.
+// CalcDisqualifyNodes returns the nodes disqualified by complaints, either
+// by a complaint with the private share revealed or by nack complaints from
+// at least threshold nodes.
+func CalcDisqualifyNodes(
+	complaints []*Complaint, threshold int) map[types.NodeID]struct{} {
 	disqualifyIDs := map[types.NodeID]struct{}{}
 	complaintsByID := map[types.NodeID]map[types.NodeID]struct{}{}
 	for _, complaint := range complaints {
@@ -373,14 +377,42 @@ func CalcQualifyNodes(
 			disqualifyIDs[nID] = struct{}{}
 		}
 	}
-	qualifyIDs = make(cryptoDKG.IDs, 0, len(mpks)-len(disqualifyIDs))
+	return disqualifyIDs
+}
+
+// CalcQualifyNodesWithDisqualified returns the nodes proposing master public
+// keys except the disqualified ones, and the ones reported by
+// CalcThresholdMismatched.
+func CalcQualifyNodesWithDisqualified(
+	mpks []*MasterPublicKey, disqualifyIDs map[types.NodeID]struct{},
+	threshold int) (
+	qualifyIDs cryptoDKG.IDs, qualifyNodeIDs map[types.NodeID]struct{}, err error) {
+	if len(mpks) < threshold {
+		err = ErrInvalidThreshold
+		return
+	}
+	mismatched := CalcThresholdMismatched(mpks, threshold)
+	excluded := func(mpk *MasterPublicKey) bool {
+		if _, exist := disqualifyIDs[mpk.ProposerID]; exist {
+			return true
+		}
+		_, exist := mismatched[mpk.ProposerID]
+		return exist
+	}
+	qualified := len(mpks)
+	for _, mpk := range mpks {
+		if excluded(mpk) {
+			qualified--
+		}
+	}
+	qualifyIDs = make(cryptoDKG.IDs, 0, qualified)
 	if cap(qualifyIDs) < threshold {
 		err = ErrNotReachThreshold
 		return
 	}
 	qualifyNodeIDs = make(map[types.NodeID]struct{})
 	for _, mpk := range mpks {
-		if _, exist := disqualifyIDs[mpk.ProposerID]; exist {
+		if excluded(mpk) {
 			continue
 		}
 		qualifyIDs = append(qualifyIDs, mpk.DKGID)
@@ -389,14 +421,47 @@ func CalcQualifyNodes(
 	return
 }
 
+// CalcThresholdMismatched returns the proposers of master public keys
+// generated for a threshold other than the given one, which can't be combined
+// with the others. Nothing is returned when none of mpks is generated for the
+// given threshold, so rounds whose master public keys all share another
+// threshold are qualified as before.
+func CalcThresholdMismatched(
+	mpks []*MasterPublicKey, threshold int) map[types.NodeID]struct{} {
+	mismatched := make(map[types.NodeID]struct{})
+	matched := false
+	for _, mpk := range mpks {
+		if mpk.PublicKeyShares.Threshold() == threshold {
+			matched = true
+		} else {
+			mismatched[mpk.ProposerID] = struct{}{}
+		}
+	}
+	if !matched {
+		return map[types.NodeID]struct{}{}
+	}
+	return mismatched
+}
+
 // NewGroupPublicKey creats a GroupPublicKey instance.
 func NewGroupPublicKey(
 	round uint64,
 	mpks []*MasterPublicKey, complaints []*Complaint,
 	threshold int) (
 	*GroupPublicKey, error) {
+	return NewGroupPublicKeyWithDisqualified(round, mpks,
+		CalcDisqualifyNodes(complaints, threshold), threshold)
+}
+
+// NewGroupPublicKeyWithDisqualified creats a GroupPublicKey instance from
+// master public keys of nodes not disqualified.
+func NewGroupPublicKeyWithDisqualified(
+	round uint64,
+	mpks []*MasterPublicKey, disqualifyIDs map[types.NodeID]struct{},
+	threshold int) (
+	*GroupPublicKey, error) {
 	qualifyIDs, qualifyNodeIDs, err :=
-		CalcQualifyNodes(mpks, complaints, threshold)
+		CalcQualifyNodesWithDisqualified(mpks, disqualifyIDs, threshold)
 	if err != nil {
 		return nil, err
 	}
@@ -441,8 +506,19 @@ func NewNodePublicKeys(
 	mpks []*MasterPublicKey, complaints []*Complaint,
 	threshold int) (
 	*NodePublicKeys, error) {
+	return NewNodePublicKeysWithDisqualified(round, mpks,
+		CalcDisqualifyNodes(complaints, threshold), threshold)
+}
+
+// NewNodePublicKeysWithDisqualified creats a NodePublicKeys instance from
+// master public keys of nodes not disqualified.
+func NewNodePublicKeysWithDisqualified(
+	round uint64,
+	mpks []*MasterPublicKey, disqualifyIDs map[types.NodeID]struct{},
+	threshold int) (
+	*NodePublicKeys, error) {
 	qualifyIDs, qualifyNodeIDs, err :=
-		CalcQualifyNodes(mpks, complaints, threshold)
+		CalcQualifyNodesWithDisqualified(mpks, disqualifyIDs, threshold)
 	if err != nil {
 		return nil, err
 	}
@@ -483,3 +559,69 @@ func NewNodePublicKeys(
 		Threshold:      threshold,
 	}, nil
 }
+
+// Type tags of DKG messages encoded by Encode.
+const (
+	tagMasterPublicKey byte = iota + 1
+	tagPrivateShare
+	tagComplaint
+	tagMPKReady
+	tagFinalize
+	tagSuccess
+)
+
+// Encode encodes a DKG message into bytes tagged with its type, which could
+// be decoded by Decode.
+func Encode(msg interface{}) ([]byte, error) {
+	var tag byte
+	switch msg.(type) {
+	case *MasterPublicKey:
+		tag = tagMasterPublicKey
+	case *PrivateShare:
+		tag = tagPrivateShare
+	case *Complaint:
+		tag = tagComplaint
+	case *MPKReady:
+		tag = tagMPKReady
+	case *Finalize:
+		tag = tagFinalize
+	case *Success:
+		tag = tagSuccess
+	default:
+		return nil, ErrUnknownMessage
+	}
+	b, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{tag}, b...), nil
+}
+
+// Decode decodes a DKG message encoded by Encode, a pointer to the message is
+// returned.
+func Decode(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, ErrUnknownMessage
+	}
+	var msg interface{}
+	switch data[0] {
+	case tagMasterPublicKey:
+		msg = NewMasterPublicKey()
+	case tagPrivateShare:
+		msg = &PrivateShare{}
+	case tagComplaint:
+		msg = &Complaint{}
+	case tagMPKReady:
+		msg = &MPKReady{}
+	case tagFinalize:
+		msg = &Finalize{}
+	case tagSuccess:
+		msg = &Success{}
+	default:
+		return nil, ErrUnknownMessage
+	}
+	if err := rlp.DecodeBytes(data[1:], msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -173,6 +173,88 @@ func (s *DKGTestSuite) TestRLPEncodeDecode() {
 	s.Require().True(reflect.DeepEqual(c.Signature, cc.Signature))
 }
 
+func (s *DKGTestSuite) TestEncodeDecode() {
+	signature := crypto.Signature{Type: "123", Signature: []byte{1, 2, 3}}
+	_, pubShare := cryptoDKG.NewPrivateKeyShares(3)
+	prvShare := &PrivateShare{
+		ProposerID:   types.NodeID{Hash: common.Hash{1}},
+		ReceiverID:   types.NodeID{Hash: common.Hash{2}},
+		Round:        10,
+		Reset:        11,
+		PrivateShare: *cryptoDKG.NewPrivateKey(),
+		Signature:    signature,
+	}
+	msgs := []interface{}{
+		&MasterPublicKey{
+			ProposerID:      types.NodeID{Hash: common.Hash{1}},
+			Round:           10,
+			Reset:           11,
+			DKGID:           s.genID(),
+			PublicKeyShares: *pubShare.Move(),
+			Signature:       signature,
+		},
+		prvShare,
+		&Complaint{
+			ProposerID:   types.NodeID{Hash: common.Hash{2}},
+			Round:        10,
+			Reset:        11,
+			PrivateShare: *prvShare,
+			Signature:    signature,
+		},
+		&MPKReady{
+			ProposerID: types.NodeID{Hash: common.Hash{3}},
+			Round:      10,
+			Reset:      11,
+			Signature:  signature,
+		},
+		&Finalize{
+			ProposerID: types.NodeID{Hash: common.Hash{4}},
+			Round:      10,
+			Reset:      11,
+			Signature:  signature,
+		},
+		&Success{
+			ProposerID: types.NodeID{Hash: common.Hash{5}},
+			Round:      10,
+			Reset:      11,
+			Signature:  signature,
+		},
+	}
+	for _, msg := range msgs {
+		b, err := Encode(msg)
+		s.Require().NoError(err)
+		decoded, err := Decode(b)
+		s.Require().NoError(err)
+		s.Require().IsType(msg, decoded)
+		switch m := msg.(type) {
+		case *MasterPublicKey:
+			s.Require().True(m.Equal(decoded.(*MasterPublicKey)))
+		case *PrivateShare:
+			s.Require().True(m.Equal(decoded.(*PrivateShare)))
+		case *Complaint:
+			s.Require().True(m.Equal(decoded.(*Complaint)))
+		case *MPKReady:
+			s.Require().True(m.Equal(decoded.(*MPKReady)))
+		case *Finalize:
+			s.Require().True(m.Equal(decoded.(*Finalize)))
+		case *Success:
+			s.Require().True(m.Equal(decoded.(*Success)))
+		}
+	}
+	// Unknown messages.
+	_, err := Encode(MPKReady{})
+	s.Require().Equal(ErrUnknownMessage, err)
+	_, err = Decode(nil)
+	s.Require().Equal(ErrUnknownMessage, err)
+	_, err = Decode([]byte{0})
+	s.Require().Equal(ErrUnknownMessage, err)
+	// Truncated data.
+	b, err := Encode(msgs[0])
+	s.Require().NoError(err)
+	_, err = Decode(b[:len(b)/2])
+	s.Require().Error(err)
+}
+
 func (s *DKGTestSuite) TestMasterPublicKeyEquality() {
 	var req = s.Require()
 	// Prepare source master public key.
@@ -430,6 +512,38 @@ func (s *DKGTestSuite) TestSuccessEquality() {
 	req.True(success1.Equal(success2))
 }
 
+func (s *DKGTestSuite) TestCalcQualifyNodesThresholdMismatch() {
+	req := s.Require()
+	threshold := 2
+	mpks := make([]*MasterPublicKey, 0, 4)
+	for i := 0; i < 4; i++ {
+		k := threshold
+		if i == 0 {
+			k = threshold + 1
+		}
+		_, pubShares := cryptoDKG.NewPrivateKeyShares(k)
+		mpks = append(mpks, &MasterPublicKey{
+			ProposerID:      types.NodeID{Hash: common.NewRandomHash()},
+			DKGID:           s.genID(),
+			PublicKeyShares: *pubShares.Move(),
+		})
+	}
+	// The master public key with another threshold is excluded.
+	qualifyIDs, qualifyNodeIDs, err := CalcQualifyNodes(mpks, nil, threshold)
+	req.NoError(err)
+	req.Len(qualifyIDs, 3)
+	req.NotContains(qualifyNodeIDs, mpks[0].ProposerID)
+	// The one with the given threshold is the only one qualified, even if
+	// it's the minority.
+	_, _, err = CalcQualifyNodes(mpks, nil, threshold+1)
+	req.Equal(ErrNotReachThreshold, err)
+	// No master public key is excluded when none of them is generated for
+	// the given threshold.
+	_, qualifyNodeIDs, err = CalcQualifyNodes(mpks[1:], nil, threshold+1)
+	req.NoError(err)
+	req.Len(qualifyNodeIDs, 3)
+}
+
 func TestDKG(t *testing.T) {
 	suite.Run(t, new(DKGTestSuite))
 }
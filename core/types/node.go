@@ -20,6 +20,7 @@ package types
 import (
 	"bytes"
 	"encoding/hex"
+	"sort"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -59,3 +60,18 @@ func (v NodeIDs) Less(i int, j int) bool {
 func (v NodeIDs) Swap(i int, j int) {
 	v[i], v[j] = v[j], v[i]
 }
+
+// Sort sorts NodeIDs in ascending order of their hash bytes.
+func (v NodeIDs) Sort() {
+	sort.Sort(v)
+}
+
+// Contains checks if a NodeID is in NodeIDs.
+func (v NodeIDs) Contains(id NodeID) bool {
+	for _, nID := range v {
+		if nID.Equal(id) {
+			return true
+		}
+	}
+	return false
+}
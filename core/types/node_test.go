@@ -0,0 +1,95 @@
+// Copyright 2019 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/dexon-foundation/dexon-consensus/common"
+	"github.com/stretchr/testify/suite"
+)
+
+type NodeTestSuite struct {
+	suite.Suite
+}
+
+func (s *NodeTestSuite) TestNodeIDsLess() {
+	nIDs := NodeIDs{}
+	for i := 0; i < 10; i++ {
+		nIDs = append(nIDs, NodeID{common.NewRandomHash()})
+	}
+	for i := range nIDs {
+		s.False(nIDs.Less(i, i))
+		for j := range nIDs {
+			if i == j {
+				continue
+			}
+			// Exactly one of them should be less than the other one.
+			s.NotEqual(nIDs.Less(i, j), nIDs.Less(j, i))
+			s.Equal(bytes.Compare(nIDs[i].Hash[:], nIDs[j].Hash[:]) < 0,
+				nIDs.Less(i, j))
+		}
+	}
+}
+
+func (s *NodeTestSuite) TestNodeIDsSort() {
+	nIDs := NodeIDs{}
+	for i := 0; i < 10; i++ {
+		nIDs = append(nIDs, NodeID{common.NewRandomHash()})
+	}
+	// Duplicated IDs should be kept.
+	nIDs = append(nIDs, nIDs[0], nIDs[1])
+	sorted := append(NodeIDs(nil), nIDs...)
+	sorted.Sort()
+	s.Len(sorted, len(nIDs))
+	for i := 1; i < len(sorted); i++ {
+		s.False(sorted.Less(i, i-1))
+	}
+	// Sorting any permutation of the same IDs should lead to the same
+	// result.
+	for i := 0; i < 10; i++ {
+		shuffled := append(NodeIDs(nil), nIDs...)
+		rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		shuffled.Sort()
+		s.Equal(sorted, shuffled)
+	}
+	// Sorting a sorted NodeIDs should change nothing.
+	resorted := append(NodeIDs(nil), sorted...)
+	resorted.Sort()
+	s.Equal(sorted, resorted)
+}
+
+func (s *NodeTestSuite) TestNodeIDsContains() {
+	nIDs := NodeIDs{}
+	for i := 0; i < 4; i++ {
+		nIDs = append(nIDs, NodeID{common.NewRandomHash()})
+	}
+	for _, nID := range nIDs {
+		s.True(nIDs.Contains(nID))
+	}
+	s.False(nIDs.Contains(NodeID{common.NewRandomHash()}))
+	s.False(NodeIDs{}.Contains(nIDs[0]))
+}
+
+func TestNode(t *testing.T) {
+	suite.Run(t, new(NodeTestSuite))
+}
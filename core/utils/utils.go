@@ -139,6 +139,15 @@ func GetDKGThreshold(config *types.Config) int {
 	return int(config.NotarySetSize*2/3) + 1
 }
 
+// GetDKGMinParticipants return the minimum count of master public keys for
+// DKG to proceed.
+func GetDKGMinParticipants(config *types.Config) int {
+	if config.MinDKGParticipants == 0 {
+		return GetDKGThreshold(config)
+	}
+	return int(config.MinDKGParticipants)
+}
+
 // GetDKGValidThreshold return threshold for DKG set to considered valid.
 func GetDKGValidThreshold(config *types.Config) int {
 	return int(config.NotarySetSize * 5 / 6)
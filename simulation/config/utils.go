@@ -37,6 +37,16 @@ func StateChangeTypeFromString(s string) test.StateChangeType {
 		return test.StateChangeMinBlockInterval
 	case "notary_set_size":
 		return test.StateChangeNotarySetSize
+	case "dkg_complaint_window":
+		return test.StateChangeDKGComplaintWindow
+	case "min_dkg_participants":
+		return test.StateChangeMinDKGParticipants
+	case "dkg_mpk_phase":
+		return test.StateChangeDKGMPKPhase
+	case "dkg_share_phase":
+		return test.StateChangeDKGSharePhase
+	case "dkg_finalize_phase":
+		return test.StateChangeDKGFinalizePhase
 	}
 	panic(fmt.Errorf("unsupported state change type %s", s))
 }
@@ -46,14 +56,16 @@ func StateChangeTypeFromString(s string) test.StateChangeType {
 func StateChangeValueFromString(
 	t test.StateChangeType, v string) interface{} {
 	switch t {
-	case test.StateChangeNotarySetSize:
+	case test.StateChangeNotarySetSize, test.StateChangeMinDKGParticipants:
 		ret, err := strconv.ParseUint(v, 10, 32)
 		if err != nil {
 			panic(err)
 		}
 		return uint32(ret)
 	case test.StateChangeLambdaBA, test.StateChangeLambdaDKG,
-		test.StateChangeRoundLength, test.StateChangeMinBlockInterval:
+		test.StateChangeRoundLength, test.StateChangeMinBlockInterval,
+		test.StateChangeDKGComplaintWindow, test.StateChangeDKGMPKPhase,
+		test.StateChangeDKGSharePhase, test.StateChangeDKGFinalizePhase:
 		ret, err := strconv.ParseInt(v, 10, 32)
 		if err != nil {
 			panic(err)